@@ -7,6 +7,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap/data"
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	log "github.com/sirupsen/logrus"
@@ -17,13 +18,20 @@ func Init() {
 	bootstrap.Log()
 	bootstrap.InitDB()
 	data.InitData()
+	bootstrap.InitCA()
+	bootstrap.InitIdP()
 	bootstrap.InitStreamLimit()
 	bootstrap.InitIndex()
 	bootstrap.InitUpgradePatch()
 }
 
 func Release() {
+	bootstrap.StopLdapSync()
+	bootstrap.StopCertificateLifecycle()
+	bootstrap.StopAudit()
+	cluster.Stop()
 	db.Close()
+	bootstrap.CloseCacheStore()
 }
 
 var pid = -1
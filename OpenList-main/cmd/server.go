@@ -42,7 +42,13 @@ the address is defined in config file`,
 			time.Sleep(time.Duration(conf.Conf.DelayedStart) * time.Second)
 		}
 		bootstrap.InitOfflineDownloadTools()
+		bootstrap.InitCluster()
+		bootstrap.InitCacheStore()
 		bootstrap.LoadStorages()
+		bootstrap.InitStorageHealth()
+		bootstrap.InitCertificateLifecycle()
+		bootstrap.InitLdapSync()
+		bootstrap.InitAudit()
 		bootstrap.InitTaskManager()
 		if !flags.Debug && !flags.Dev {
 			gin.SetMode(gin.ReleaseMode)
@@ -79,7 +85,7 @@ the address is defined in config file`,
 			httpsBase := fmt.Sprintf("%s:%d", conf.Conf.Scheme.Address, conf.Conf.Scheme.HttpsPort)
 			fmt.Printf("start HTTPS server @ %s\n", httpsBase)
 			utils.Log.Infof("start HTTPS server @ %s", httpsBase)
-			httpsSrv = &http.Server{Addr: httpsBase, Handler: r}
+			httpsSrv = &http.Server{Addr: httpsBase, Handler: r, TLSConfig: server.TLSConfig()}
 			go func() {
 				err := httpsSrv.ListenAndServeTLS(conf.Conf.Scheme.CertFile, conf.Conf.Scheme.KeyFile)
 				if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -122,7 +128,7 @@ the address is defined in config file`,
 			go func() {
 				var err error
 				if conf.Conf.S3.SSL {
-					httpsSrv = &http.Server{Addr: s3Base, Handler: s3r}
+					httpsSrv = &http.Server{Addr: s3Base, Handler: s3r, TLSConfig: server.TLSConfig()}
 					err = httpsSrv.ListenAndServeTLS(conf.Conf.Scheme.CertFile, conf.Conf.Scheme.KeyFile)
 				}
 				if !conf.Conf.S3.SSL {
@@ -125,7 +125,7 @@ var listStorageCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		Init()
 		defer Release()
-		storages, _, err := db.GetStorages(1, -1)
+		storages, _, err := db.GetStorages(1, -1, nil)
 		if err != nil {
 			return fmt.Errorf("failed to query storages: %+v", err)
 		} else {
@@ -25,4 +25,5 @@ func init() {
 	op.RegisterDriver(func() driver.Driver {
 		return &Pan115{}
 	})
+	op.RegisterStorableObj[*FileObj]("115.FileObj")
 }
@@ -30,4 +30,5 @@ func init() {
 			},
 		}
 	})
+	op.RegisterStorableObj[File]("123.File")
 }
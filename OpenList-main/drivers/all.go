@@ -42,6 +42,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/mediatrack"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/mega"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/mirror"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/misskey"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/mopan"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/netease_music"
@@ -66,6 +67,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunder"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunder_browser"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunderx"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/union"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/url_tree"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/uss"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/virtual"
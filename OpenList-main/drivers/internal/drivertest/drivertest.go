@@ -0,0 +1,26 @@
+// Package drivertest has the fixtures shared by the tests of the drivers built on other mounts
+package drivertest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+// Mount creates a storage, which is deleted when the test ends
+func Mount(t *testing.T, driver, mountPath string, addition map[string]any) {
+	t.Helper()
+	b, _ := json.Marshal(addition)
+	id, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    driver,
+		MountPath: mountPath,
+		Addition:  string(b),
+	})
+	if err != nil {
+		t.Fatalf("failed to mount %s: %+v", mountPath, err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(context.Background(), id) })
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/times"
 	cp "github.com/otiai10/copy"
+	"github.com/shirou/gopsutil/v4/disk"
 	log "github.com/sirupsen/logrus"
 	_ "golang.org/x/image/webp"
 )
@@ -427,4 +428,16 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 	return nil
 }
 
+func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	usage, err := disk.UsageWithContext(ctx, d.GetRootPath())
+	if err != nil {
+		return nil, err
+	}
+	return &model.StorageDetails{
+		TotalSpace: int64(usage.Total),
+		FreeSpace:  int64(usage.Free),
+	}, nil
+}
+
 var _ driver.Driver = (*Local)(nil)
+var _ driver.WithDetails = (*Local)(nil)
@@ -0,0 +1,246 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+)
+
+// Mirror keeps a primary mount and its mirrors in sync:
+// writes go to all of them and reads fail over to the mirrors
+// when the primary errors or is not working
+type Mirror struct {
+	model.Storage
+	Addition
+	primary string
+	mirrors []string
+}
+
+func (d *Mirror) Config() driver.Config {
+	return config
+}
+
+func (d *Mirror) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Mirror) Init(ctx context.Context) error {
+	if strings.TrimSpace(d.Primary) == "" {
+		return errors.New("primary is required")
+	}
+	d.primary = utils.FixAndCleanPath(d.Primary)
+	d.mirrors = nil
+	for _, path := range strings.Split(d.Mirrors, "\n") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		d.mirrors = append(d.mirrors, utils.FixAndCleanPath(path))
+	}
+	if len(d.mirrors) == 0 {
+		return errors.New("mirrors is required")
+	}
+	for _, path := range d.backends() {
+		if utils.IsSubPath(d.MountPath, path) || utils.IsSubPath(path, d.MountPath) {
+			return fmt.Errorf("path %s overlaps the mount path of the mirror", path)
+		}
+	}
+	for _, path := range d.mirrors {
+		if utils.IsSubPath(d.primary, path) || utils.IsSubPath(path, d.primary) {
+			return fmt.Errorf("mirror %s overlaps the primary", path)
+		}
+	}
+	if d.WriteMode != WriteSync && d.WriteMode != WriteAsync {
+		return fmt.Errorf("invalid write mode: %s", d.WriteMode)
+	}
+	return nil
+}
+
+func (d *Mirror) Drop(ctx context.Context) error {
+	d.mirrors = nil
+	return nil
+}
+
+func (d *Mirror) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     "/",
+		}, nil
+	}
+	var err error
+	for _, backend := range d.readOrder() {
+		obj, e := fs.Get(ctx, stdpath.Join(backend, path), &fs.GetArgs{NoLog: true})
+		if e != nil {
+			err = e
+			continue
+		}
+		return &model.Object{
+			Path:     path,
+			Name:     obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			IsFolder: obj.IsDir(),
+			HashInfo: obj.GetHash(),
+		}, nil
+	}
+	return nil, err
+}
+
+func (d *Mirror) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	var err error
+	fsArgs := &fs.ListArgs{NoLog: true, Refresh: args.Refresh}
+	for _, backend := range d.readOrder() {
+		objs, e := fs.List(ctx, stdpath.Join(backend, dir.GetPath()), fsArgs)
+		if e != nil {
+			err = e
+			continue
+		}
+		return utils.MustSliceConvert(objs, convertObj), nil
+	}
+	return nil, err
+}
+
+func (d *Mirror) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	// proxy || ftp,s3
+	if common.GetApiUrl(ctx) == "" {
+		args.Redirect = false
+	}
+	var err error
+	for _, backend := range d.readOrder() {
+		reqPath := stdpath.Join(backend, file.GetPath())
+		link, fi, e := d.link(ctx, reqPath, args)
+		if e != nil {
+			err = e
+			continue
+		}
+		if link == nil {
+			return &model.Link{
+				URL: fmt.Sprintf("%s/p%s?sign=%s",
+					common.GetApiUrl(ctx),
+					utils.EncodePath(reqPath, true),
+					sign.Sign(reqPath)),
+			}, nil
+		}
+
+		resultLink := *link
+		resultLink.SyncClosers = utils.NewSyncClosers(link)
+		if args.Redirect {
+			return &resultLink, nil
+		}
+
+		if resultLink.ContentLength == 0 {
+			resultLink.ContentLength = fi.GetSize()
+		}
+		if resultLink.MFile != nil {
+			return &resultLink, nil
+		}
+		if d.DownloadConcurrency > 0 {
+			resultLink.Concurrency = d.DownloadConcurrency
+		}
+		if d.DownloadPartSize > 0 {
+			resultLink.PartSize = d.DownloadPartSize * utils.KB
+		}
+		return &resultLink, nil
+	}
+	return nil, err
+}
+
+func (d *Mirror) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	return d.write(ctx, "make dir", func(ctx context.Context, backend string) error {
+		return fs.MakeDir(ctx, stdpath.Join(backend, parentDir.GetPath(), dirName))
+	})
+}
+
+func (d *Mirror) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.write(ctx, "move", func(ctx context.Context, backend string) error {
+		_, err := fs.Move(ctx, stdpath.Join(backend, srcObj.GetPath()), stdpath.Join(backend, dstDir.GetPath()))
+		return err
+	})
+}
+
+func (d *Mirror) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.write(ctx, "rename", func(ctx context.Context, backend string) error {
+		return fs.Rename(ctx, stdpath.Join(backend, srcObj.GetPath()), newName)
+	})
+}
+
+func (d *Mirror) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.write(ctx, "copy", func(ctx context.Context, backend string) error {
+		_, err := fs.Copy(ctx, stdpath.Join(backend, srcObj.GetPath()), stdpath.Join(backend, dstDir.GetPath()))
+		return err
+	})
+}
+
+func (d *Mirror) Remove(ctx context.Context, obj model.Obj) error {
+	return d.write(ctx, "remove", func(ctx context.Context, backend string) error {
+		return fs.Remove(ctx, stdpath.Join(backend, obj.GetPath()))
+	})
+}
+
+func (d *Mirror) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	ctx = fs.BackendCtx(ctx)
+	storage, reqActualPath, err := op.GetStorageAndActualPath(stdpath.Join(d.primary, dstDir.GetPath()))
+	if err != nil {
+		return err
+	}
+	err = op.Put(ctx, storage, reqActualPath, &stream.FileStream{
+		Obj:      s,
+		Mimetype: s.GetMimetype(),
+		Reader:   s,
+	}, up)
+	if err != nil {
+		return err
+	}
+	// the mirrors are copied from the primary, so that the stream is read only once
+	srcPath := stdpath.Join(d.primary, dstDir.GetPath(), s.GetName())
+	return d.replicate(ctx, "put", func(ctx context.Context, backend string) error {
+		dstPath := stdpath.Join(backend, dstDir.GetPath())
+		if err := fs.MakeDir(ctx, dstPath); err != nil {
+			return err
+		}
+		_, err := fs.Copy(ctx, srcPath, dstPath)
+		return err
+	})
+}
+
+func (d *Mirror) PutURL(ctx context.Context, dstDir model.Obj, name, url string) error {
+	return d.write(ctx, "put url", func(ctx context.Context, backend string) error {
+		return fs.PutURL(ctx, stdpath.Join(backend, dstDir.GetPath()), name, url)
+	})
+}
+
+func (d *Mirror) Other(ctx context.Context, args model.OtherArgs) (interface{}, error) {
+	switch args.Method {
+	case "check":
+		path := "/"
+		if args.Obj != nil {
+			path = args.Obj.GetPath()
+		}
+		mirrors := utils.MustSliceConvert(d.mirrors, func(mirror string) string {
+			return stdpath.Join(mirror, path)
+		})
+		t, err := fs.CheckMirrors(ctx, stdpath.Join(d.primary, path), mirrors)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"task_id": t.GetID()}, nil
+	default:
+		return nil, errs.NotSupport
+	}
+}
+
+var _ driver.Driver = (*Mirror)(nil)
@@ -0,0 +1,120 @@
+package mirror_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/internal/drivertest"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/mirror"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func readFile(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func TestMirror(t *testing.T) {
+	primary, replica := t.TempDir(), t.TempDir()
+	drivertest.Mount(t, "Local", "/primary", map[string]any{"root_folder_path": primary})
+	drivertest.Mount(t, "Local", "/replica", map[string]any{"root_folder_path": replica})
+	drivertest.Mount(t, "Mirror", "/mirror", map[string]any{"primary": "/primary", "mirrors": "/replica", "write_mode": "sync"})
+
+	ctx := context.Background()
+	if err := fs.MakeDir(ctx, "/mirror/docs"); err != nil {
+		t.Fatalf("failed to make dir: %+v", err)
+	}
+	content := "hello mirror"
+	err := fs.PutDirectly(ctx, "/mirror/docs", &stream.FileStream{
+		Obj:    &model.Object{Name: "a.txt", Size: int64(len(content))},
+		Reader: strings.NewReader(content),
+	})
+	if err != nil {
+		t.Fatalf("failed to put: %+v", err)
+	}
+	for _, root := range []string{primary, replica} {
+		if got := readFile(filepath.Join(root, "docs", "a.txt")); got != content {
+			t.Errorf("expect the file to be written to %s, got %q", root, got)
+		}
+	}
+	if err = fs.Rename(ctx, "/mirror/docs/a.txt", "b.txt"); err != nil {
+		t.Fatalf("failed to rename: %+v", err)
+	}
+	if readFile(filepath.Join(replica, "docs", "b.txt")) != content {
+		t.Errorf("expect the rename to be applied to the mirror")
+	}
+
+	// the reads fail over to the mirror when the primary is broken
+	storage, err := op.GetStorageByMountPath("/primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.RemoveAll(primary); err != nil {
+		t.Fatal(err)
+	}
+	storage.GetStorage().SetStatus("token expired")
+	objs, err := fs.List(ctx, "/mirror/docs", &fs.ListArgs{Refresh: true})
+	if err != nil || len(objs) != 1 || objs[0].GetName() != "b.txt" {
+		t.Errorf("expect the listing of the mirror, got %v, %v", objs, err)
+	}
+	if _, _, err = fs.Link(ctx, "/mirror/docs/b.txt", model.LinkArgs{}); err != nil {
+		t.Errorf("expect the file to be linked from the mirror, got %+v", err)
+	}
+	storage.GetStorage().SetStatus(op.WORK)
+}
+
+func TestMirrorCheck(t *testing.T) {
+	primary, replica := t.TempDir(), t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(primary, "same.txt"):       "same",
+		filepath.Join(replica, "same.txt"):       "same",
+		filepath.Join(primary, "dir", "a.txt"):   "primary",
+		filepath.Join(replica, "dir", "a.txt"):   "mirror changed",
+		filepath.Join(primary, "missing.txt"):    "lost",
+		filepath.Join(replica, "dir", "new.txt"): "extra",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drivertest.Mount(t, "Local", "/check_primary", map[string]any{"root_folder_path": primary})
+	drivertest.Mount(t, "Local", "/check_replica", map[string]any{"root_folder_path": replica})
+
+	task := &fs.MirrorCheckTask{Primary: "/check_primary", Mirrors: []string{"/check_replica"}}
+	task.SetCtx(context.Background())
+	err := task.Run()
+	if err == nil {
+		t.Fatalf("expect the divergences to be reported")
+	}
+	for _, want := range []string{"3 divergences", "[missing.txt] missing", "[dir/a.txt] is 7 bytes", "[dir/new.txt] only exists"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expect %q in the report, got %s", want, err)
+		}
+	}
+}
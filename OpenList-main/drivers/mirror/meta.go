@@ -0,0 +1,37 @@
+package mirror
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	Primary             string `json:"primary" required:"true" help:"Mount path of the primary"`
+	Mirrors             string `json:"mirrors" required:"true" type:"text" help:"Mount paths of the mirrors, one per line, tried in order when the primary fails"`
+	WriteMode           string `json:"write_mode" type:"select" options:"sync,async" default:"sync" help:"async writes the primary only and updates the mirrors in the background"`
+	DownloadConcurrency int    `json:"download_concurrency" default:"0" required:"false" type:"number" help:"Need to enable proxy"`
+	DownloadPartSize    int    `json:"download_part_size" default:"0" type:"number" required:"false" help:"Need to enable proxy. Unit: KB"`
+}
+
+const (
+	WriteSync  = "sync"
+	WriteAsync = "async"
+)
+
+var config = driver.Config{
+	Name:             "Mirror",
+	LocalSort:        true,
+	NoCache:          true,
+	DefaultRoot:      "/",
+	ProxyRangeOption: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Mirror{
+			Addition: Addition{
+				WriteMode: WriteSync,
+			},
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	log "github.com/sirupsen/logrus"
+)
+
+func convertObj(obj model.Obj) model.Obj {
+	objRes := model.Object{
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}
+	thumb, ok := model.GetThumb(obj)
+	if !ok {
+		return &objRes
+	}
+	return &model.ObjThumb{
+		Object: objRes,
+		Thumbnail: model.Thumbnail{
+			Thumbnail: thumb,
+		},
+	}
+}
+
+func (d *Mirror) backends() []string {
+	return append([]string{d.primary}, d.mirrors...)
+}
+
+// readOrder puts the backends whose storage is not working last,
+// so that they are only tried when all the others fail
+func (d *Mirror) readOrder() []string {
+	var working, broken []string
+	for _, backend := range d.backends() {
+		storage, _, err := op.GetStorageAndActualPath(backend)
+		if err == nil && storage.GetStorage().Status == op.WORK {
+			working = append(working, backend)
+		} else {
+			broken = append(broken, backend)
+		}
+	}
+	return append(working, broken...)
+}
+
+// write applies the change to the primary, then to the mirrors if it succeeded
+func (d *Mirror) write(ctx context.Context, action string, f func(ctx context.Context, backend string) error) error {
+	ctx = fs.BackendCtx(ctx)
+	if err := f(ctx, d.primary); err != nil {
+		return err
+	}
+	return d.replicate(ctx, action, f)
+}
+
+// replicate applies the change to the mirrors, in the background in the async write mode
+func (d *Mirror) replicate(ctx context.Context, action string, f func(ctx context.Context, backend string) error) error {
+	ctx = fs.BackendCtx(ctx)
+	if d.WriteMode == WriteAsync {
+		// copies between storages are added as tasks, the rest is done in a goroutine
+		ctx = context.WithoutCancel(ctx)
+		go func() {
+			for _, mirror := range d.mirrors {
+				if err := f(ctx, mirror); err != nil {
+					log.Errorf("failed %s in mirror [%s] of [%s]: %+v", action, mirror, d.MountPath, err)
+				}
+			}
+		}()
+		return nil
+	}
+	// wait for the copies between storages too
+	ctx = context.WithValue(ctx, conf.NoTaskKey, struct{}{})
+	var err error
+	for _, mirror := range d.mirrors {
+		err = errors.Join(err, f(ctx, mirror))
+	}
+	return err
+}
+
+func (d *Mirror) link(ctx context.Context, reqPath string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+	storage, reqActualPath, err := op.GetStorageAndActualPath(reqPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !args.Redirect {
+		return op.Link(ctx, storage, reqActualPath, args)
+	}
+	obj, err := fs.Get(ctx, reqPath, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	if common.ShouldProxy(storage, stdpath.Base(reqPath)) {
+		return nil, obj, nil
+	}
+	return op.Link(ctx, storage, reqActualPath, args)
+}
@@ -30,4 +30,5 @@ func init() {
 	op.RegisterDriver(func() driver.Driver {
 		return &Onedrive{}
 	})
+	op.RegisterStorableObj[*Object]("onedrive.Object")
 }
@@ -0,0 +1,270 @@
+package union
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+)
+
+// Union merges the dirs of several mount paths into one tree, like mergerfs:
+// the listings are merged, a file is read from any backend holding it,
+// new files go to the backend chosen by the create policy,
+// and removes and renames are applied to every backend holding the object
+type Union struct {
+	model.Storage
+	Addition
+	backends []string
+}
+
+func (d *Union) Config() driver.Config {
+	return config
+}
+
+func (d *Union) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Union) Init(ctx context.Context) error {
+	d.backends = nil
+	for _, path := range strings.Split(d.Paths, "\n") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		path = utils.FixAndCleanPath(path)
+		if utils.IsSubPath(d.MountPath, path) || utils.IsSubPath(path, d.MountPath) {
+			return fmt.Errorf("path %s overlaps the mount path of the union", path)
+		}
+		d.backends = append(d.backends, path)
+	}
+	if len(d.backends) == 0 {
+		return errors.New("paths is required")
+	}
+	switch d.CreatePolicy {
+	case PolicyExistingPath, PolicyFirstFound, PolicyMostFreeSpace:
+	default:
+		return fmt.Errorf("invalid create policy: %s", d.CreatePolicy)
+	}
+	return nil
+}
+
+func (d *Union) Drop(ctx context.Context) error {
+	d.backends = nil
+	return nil
+}
+
+func (d *Union) Get(ctx context.Context, path string) (model.Obj, error) {
+	if utils.PathEqual(path, "/") {
+		return &model.Object{
+			Name:     "Root",
+			IsFolder: true,
+			Path:     "/",
+		}, nil
+	}
+	for _, backend := range d.backends {
+		obj, err := fs.Get(ctx, stdpath.Join(backend, path), &fs.GetArgs{NoLog: true})
+		if err != nil {
+			continue
+		}
+		return &model.Object{
+			Path:     path,
+			Name:     obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			IsFolder: obj.IsDir(),
+			HashInfo: obj.GetHash(),
+		}, nil
+	}
+	return nil, errs.ObjectNotFound
+}
+
+func (d *Union) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	var (
+		objs  []model.Obj
+		found bool
+		err   error
+	)
+	seen := make(map[string]struct{})
+	fsArgs := &fs.ListArgs{NoLog: true, Refresh: args.Refresh}
+	for _, backend := range d.backends {
+		tmp, e := fs.List(ctx, stdpath.Join(backend, dir.GetPath()), fsArgs)
+		if e != nil {
+			err = e
+			continue
+		}
+		found = true
+		for _, obj := range tmp {
+			// the same name in an earlier backend shadows the later ones,
+			// dirs of the same name are listed once and merged when entered
+			if _, ok := seen[obj.GetName()]; ok {
+				continue
+			}
+			seen[obj.GetName()] = struct{}{}
+			objs = append(objs, convertObj(obj))
+		}
+	}
+	if !found {
+		if err == nil {
+			err = errs.ObjectNotFound
+		}
+		return nil, err
+	}
+	return objs, nil
+}
+
+func (d *Union) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	// proxy || ftp,s3
+	if common.GetApiUrl(ctx) == "" {
+		args.Redirect = false
+	}
+	for _, backend := range d.readOrder() {
+		reqPath := stdpath.Join(backend, file.GetPath())
+		link, fi, err := d.link(ctx, reqPath, args)
+		if err != nil {
+			continue
+		}
+		if link == nil {
+			return &model.Link{
+				URL: fmt.Sprintf("%s/p%s?sign=%s",
+					common.GetApiUrl(ctx),
+					utils.EncodePath(reqPath, true),
+					sign.Sign(reqPath)),
+			}, nil
+		}
+
+		resultLink := *link
+		resultLink.SyncClosers = utils.NewSyncClosers(link)
+		if args.Redirect {
+			return &resultLink, nil
+		}
+
+		if resultLink.ContentLength == 0 {
+			resultLink.ContentLength = fi.GetSize()
+		}
+		if resultLink.MFile != nil {
+			return &resultLink, nil
+		}
+		if d.DownloadConcurrency > 0 {
+			resultLink.Concurrency = d.DownloadConcurrency
+		}
+		if d.DownloadPartSize > 0 {
+			resultLink.PartSize = d.DownloadPartSize * utils.KB
+		}
+		return &resultLink, nil
+	}
+	return nil, errs.ObjectNotFound
+}
+
+func (d *Union) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	ctx = fs.BackendCtx(ctx)
+	backend, err := d.createBackend(ctx, parentDir.GetPath())
+	if err != nil {
+		return err
+	}
+	return fs.MakeDir(ctx, stdpath.Join(backend, parentDir.GetPath(), dirName))
+}
+
+func (d *Union) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx = fs.BackendCtx(ctx)
+	backends := d.locate(ctx, srcObj.GetPath())
+	if len(backends) == 0 {
+		return errs.ObjectNotFound
+	}
+	// every part of the object stays in its own backend
+	var err error
+	for _, backend := range backends {
+		dstPath := stdpath.Join(backend, dstDir.GetPath())
+		if e := fs.MakeDir(ctx, dstPath); e != nil {
+			err = errors.Join(err, e)
+			continue
+		}
+		_, e := fs.Move(ctx, stdpath.Join(backend, srcObj.GetPath()), dstPath)
+		err = errors.Join(err, e)
+	}
+	return err
+}
+
+func (d *Union) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	ctx = fs.BackendCtx(ctx)
+	backends := d.locate(ctx, srcObj.GetPath())
+	if len(backends) == 0 {
+		return errs.ObjectNotFound
+	}
+	var err error
+	for _, backend := range backends {
+		err = errors.Join(err, fs.Rename(ctx, stdpath.Join(backend, srcObj.GetPath()), newName))
+	}
+	return err
+}
+
+func (d *Union) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx = fs.BackendCtx(ctx)
+	backends := d.locate(ctx, srcObj.GetPath())
+	if len(backends) == 0 {
+		return errs.ObjectNotFound
+	}
+	var err error
+	for _, backend := range backends {
+		dstPath := stdpath.Join(backend, dstDir.GetPath())
+		if e := fs.MakeDir(ctx, dstPath); e != nil {
+			err = errors.Join(err, e)
+			continue
+		}
+		_, e := fs.Copy(ctx, stdpath.Join(backend, srcObj.GetPath()), dstPath)
+		err = errors.Join(err, e)
+	}
+	return err
+}
+
+func (d *Union) Remove(ctx context.Context, obj model.Obj) error {
+	ctx = fs.BackendCtx(ctx)
+	backends := d.locate(ctx, obj.GetPath())
+	if len(backends) == 0 {
+		return errs.ObjectNotFound
+	}
+	var err error
+	for _, backend := range backends {
+		err = errors.Join(err, fs.Remove(ctx, stdpath.Join(backend, obj.GetPath())))
+	}
+	return err
+}
+
+func (d *Union) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	ctx = fs.BackendCtx(ctx)
+	dstPath, err := d.putTarget(ctx, dstDir.GetPath(), s.GetName())
+	if err != nil {
+		return err
+	}
+	storage, reqActualPath, err := op.GetStorageAndActualPath(dstPath)
+	if err != nil {
+		return err
+	}
+	return op.Put(ctx, storage, reqActualPath, &stream.FileStream{
+		Obj:      s,
+		Mimetype: s.GetMimetype(),
+		Reader:   s,
+	}, up)
+}
+
+func (d *Union) PutURL(ctx context.Context, dstDir model.Obj, name, url string) error {
+	ctx = fs.BackendCtx(ctx)
+	dstPath, err := d.putTarget(ctx, dstDir.GetPath(), name)
+	if err != nil {
+		return err
+	}
+	return fs.PutURL(ctx, dstPath, name, url)
+}
+
+var _ driver.Driver = (*Union)(nil)
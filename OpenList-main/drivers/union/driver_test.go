@@ -0,0 +1,105 @@
+package union_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/internal/drivertest"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/union"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listNames(t *testing.T, path string) string {
+	t.Helper()
+	objs, err := fs.List(context.Background(), path, &fs.ListArgs{Refresh: true})
+	if err != nil {
+		t.Fatalf("failed to list %s: %+v", path, err)
+	}
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetName())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestUnion(t *testing.T) {
+	disk1, disk2 := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(disk1, "movies", "a.mkv"), "disk1")
+	writeFile(t, filepath.Join(disk1, "shared.txt"), "disk1")
+	writeFile(t, filepath.Join(disk2, "movies", "b.mkv"), "disk2")
+	writeFile(t, filepath.Join(disk2, "music", "c.flac"), "disk2")
+	writeFile(t, filepath.Join(disk2, "shared.txt"), "disk2")
+
+	drivertest.Mount(t, "Local", "/disk1", map[string]any{"root_folder_path": disk1})
+	drivertest.Mount(t, "Local", "/disk2", map[string]any{"root_folder_path": disk2})
+	drivertest.Mount(t, "Union", "/union", map[string]any{"paths": "/disk1\n/disk2", "create_policy": "existing_path"})
+
+	ctx := context.Background()
+	if got := listNames(t, "/union"); got != "movies,music,shared.txt" {
+		t.Errorf("expect the root listings to be merged, got %s", got)
+	}
+	if got := listNames(t, "/union/movies"); got != "a.mkv,b.mkv" {
+		t.Errorf("expect the dirs of the same name to be merged, got %s", got)
+	}
+
+	// a new dir goes to the backend which has the parent dir
+	if err := fs.MakeDir(ctx, "/union/music/live"); err != nil {
+		t.Fatalf("failed to make dir: %+v", err)
+	}
+	if !exists(filepath.Join(disk2, "music", "live")) || exists(filepath.Join(disk1, "music")) {
+		t.Errorf("expect the dir to be made in the backend having the parent dir only")
+	}
+
+	// a rename is applied to every replica
+	if err := fs.Rename(ctx, "/union/movies", "films"); err != nil {
+		t.Fatalf("failed to rename: %+v", err)
+	}
+	if !exists(filepath.Join(disk1, "films", "a.mkv")) || !exists(filepath.Join(disk2, "films", "b.mkv")) {
+		t.Errorf("expect the dir to be renamed in both backends")
+	}
+
+	// so is a remove
+	if err := fs.Remove(ctx, "/union/shared.txt"); err != nil {
+		t.Fatalf("failed to remove: %+v", err)
+	}
+	if exists(filepath.Join(disk1, "shared.txt")) || exists(filepath.Join(disk2, "shared.txt")) {
+		t.Errorf("expect the file to be removed from both backends")
+	}
+	if got := listNames(t, "/union"); got != "films,music" {
+		t.Errorf("unexpected listing after the mutations: %s", got)
+	}
+}
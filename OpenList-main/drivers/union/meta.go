@@ -0,0 +1,45 @@
+package union
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	Paths               string `json:"paths" required:"true" type:"text" help:"One mount path per line, earlier paths take precedence"`
+	CreatePolicy        string `json:"create_policy" type:"select" options:"existing_path,first_found,most_free_space" default:"existing_path" help:"Where new files and dirs are created"`
+	ReadPolicy          string `json:"read_policy" type:"select" options:"first_found,random" default:"first_found" help:"Which replica a file is read from, the others are tried on failure"`
+	DownloadConcurrency int    `json:"download_concurrency" default:"0" required:"false" type:"number" help:"Need to enable proxy"`
+	DownloadPartSize    int    `json:"download_part_size" default:"0" type:"number" required:"false" help:"Need to enable proxy. Unit: KB"`
+}
+
+const (
+	// create in the first backend where the parent dir exists
+	PolicyExistingPath = "existing_path"
+	// create in the first backend, making the parent dirs when missing
+	PolicyFirstFound = "first_found"
+	// create in the backend with the most free space, making the parent dirs when missing
+	PolicyMostFreeSpace = "most_free_space"
+
+	ReadFirstFound = "first_found"
+	ReadRandom     = "random"
+)
+
+var config = driver.Config{
+	Name:             "Union",
+	LocalSort:        true,
+	NoCache:          true,
+	DefaultRoot:      "/",
+	ProxyRangeOption: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Union{
+			Addition: Addition{
+				CreatePolicy: PolicyExistingPath,
+				ReadPolicy:   ReadFirstFound,
+			},
+		}
+	})
+}
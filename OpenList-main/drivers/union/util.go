@@ -0,0 +1,144 @@
+package union
+
+import (
+	"context"
+	"math/rand"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+)
+
+func convertObj(obj model.Obj) model.Obj {
+	objRes := model.Object{
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}
+	thumb, ok := model.GetThumb(obj)
+	if !ok {
+		return &objRes
+	}
+	return &model.ObjThumb{
+		Object: objRes,
+		Thumbnail: model.Thumbnail{
+			Thumbnail: thumb,
+		},
+	}
+}
+
+// readOrder is the order the replicas of a file are tried in
+func (d *Union) readOrder() []string {
+	if d.ReadPolicy != ReadRandom || len(d.backends) < 2 {
+		return d.backends
+	}
+	start := rand.Intn(len(d.backends))
+	return append(append([]string{}, d.backends[start:]...), d.backends[:start]...)
+}
+
+// locate returns the backends holding the path
+func (d *Union) locate(ctx context.Context, path string) []string {
+	var backends []string
+	for _, backend := range d.backends {
+		if _, err := fs.Get(ctx, stdpath.Join(backend, path), &fs.GetArgs{NoLog: true}); err == nil {
+			backends = append(backends, backend)
+		}
+	}
+	return backends
+}
+
+// createBackend chooses the backend a new object in the dir is created in by the create policy
+func (d *Union) createBackend(ctx context.Context, dirPath string) (string, error) {
+	switch d.CreatePolicy {
+	case PolicyFirstFound:
+		for _, backend := range d.backends {
+			if _, _, err := op.GetStorageAndActualPath(backend); err == nil {
+				return backend, nil
+			}
+		}
+		return "", errs.StorageNotFound
+	case PolicyMostFreeSpace:
+		return d.mostFreeBackend(ctx)
+	default:
+		for _, backend := range d.backends {
+			obj, err := fs.Get(ctx, stdpath.Join(backend, dirPath), &fs.GetArgs{NoLog: true})
+			if err == nil && obj.IsDir() {
+				return backend, nil
+			}
+		}
+		return "", errs.ObjectNotFound
+	}
+}
+
+// mostFreeBackend returns the backend with the most free space,
+// backends which can't tell their space are only used when none can
+func (d *Union) mostFreeBackend(ctx context.Context) (string, error) {
+	var (
+		best     string
+		bestFree int64 = -1
+		fallback string
+	)
+	for _, backend := range d.backends {
+		storage, _, err := op.GetStorageAndActualPath(backend)
+		if err != nil {
+			continue
+		}
+		if fallback == "" {
+			fallback = backend
+		}
+		details, err := op.GetStorageDetails(ctx, storage)
+		if err != nil {
+			continue
+		}
+		if details.FreeSpace > bestFree {
+			best, bestFree = backend, details.FreeSpace
+		}
+	}
+	if best != "" {
+		return best, nil
+	}
+	if fallback != "" {
+		return fallback, nil
+	}
+	return "", errs.StorageNotFound
+}
+
+// putTarget returns the real dir a file uploaded to the dir is put in,
+// a file that exists already is overwritten in place, otherwise the create policy decides
+func (d *Union) putTarget(ctx context.Context, dirPath, name string) (string, error) {
+	if backends := d.locate(ctx, stdpath.Join(dirPath, name)); len(backends) > 0 {
+		return stdpath.Join(backends[0], dirPath), nil
+	}
+	backend, err := d.createBackend(ctx, dirPath)
+	if err != nil {
+		return "", err
+	}
+	dstPath := stdpath.Join(backend, dirPath)
+	if err = fs.MakeDir(ctx, dstPath); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+func (d *Union) link(ctx context.Context, reqPath string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+	storage, reqActualPath, err := op.GetStorageAndActualPath(reqPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !args.Redirect {
+		return op.Link(ctx, storage, reqActualPath, args)
+	}
+	obj, err := fs.Get(ctx, reqPath, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	if common.ShouldProxy(storage, stdpath.Base(reqPath)) {
+		return nil, obj, nil
+	}
+	return op.Link(ctx, storage, reqActualPath, args)
+}
@@ -0,0 +1,35 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	log "github.com/sirupsen/logrus"
+)
+
+var auditRetentionCron *cron.Cron
+
+// InitAudit removes the audit events past the retention every hour, in a cluster only the leader does
+func InitAudit() {
+	auditRetentionCron = cron.NewCron(time.Hour)
+	auditRetentionCron.Do(func() {
+		if !cluster.IsLeader() {
+			return
+		}
+		n, err := op.DeleteExpiredAuditEvents()
+		if err != nil {
+			log.Errorf("failed delete expired audit events: %+v", err)
+		} else if n > 0 {
+			log.Infof("deleted %d expired audit events", n)
+		}
+	})
+}
+
+// StopAudit stops the retention cron, which only the server command starts
+func StopAudit() {
+	if auditRetentionCron != nil {
+		auditRetentionCron.Stop()
+	}
+}
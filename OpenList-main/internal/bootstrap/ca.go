@@ -0,0 +1,49 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+var certificateLifecycleCron *cron.Cron
+
+func InitCA() {
+	if err := ca.Init(); err != nil {
+		utils.Log.Fatalf("failed to init certificate authority: %+v", err)
+	}
+}
+
+// InitCertificateLifecycle marks the certificates expiring or expired now and then every hour
+func InitCertificateLifecycle() {
+	updateCertificateLifecycle()
+	certificateLifecycleCron = cron.NewCron(time.Hour)
+	certificateLifecycleCron.Do(updateCertificateLifecycle)
+}
+
+func StopCertificateLifecycle() {
+	if certificateLifecycleCron != nil {
+		certificateLifecycleCron.Stop()
+	}
+}
+
+// updateCertificateLifecycle marks the certificates expiring or expired, in a cluster only the leader does
+func updateCertificateLifecycle() {
+	if !cluster.IsLeader() {
+		return
+	}
+	expiring, expired, err := op.UpdateCertificateLifecycle(setting.GetInt(conf.CertificateExpiringDays, 30))
+	if err != nil {
+		utils.Log.Errorf("failed to update certificate lifecycle: %+v", err)
+		return
+	}
+	if expiring > 0 || expired > 0 {
+		utils.Log.Infof("certificate lifecycle: %d marked expiring, %d marked expired", expiring, expired)
+	}
+}
@@ -0,0 +1,33 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/cachestore"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	log "github.com/sirupsen/logrus"
+)
+
+var cacheStore cachestore.Store
+
+// InitCacheStore opens the configured store of the listing and link caches,
+// only the server does it, as a bolt file can't be opened by two processes
+func InitCacheStore() {
+	var err error
+	cacheStore, err = cachestore.New(conf.Conf.Cache)
+	if err != nil {
+		log.Fatalf("failed init cache store: %+v", err)
+	}
+	if cacheStore != nil {
+		log.Infof("using %s cache store", conf.Conf.Cache.Type)
+		op.SetCacheStore(cacheStore)
+	}
+}
+
+func CloseCacheStore() {
+	if cacheStore == nil {
+		return
+	}
+	if err := cacheStore.Close(); err != nil {
+		log.Errorf("failed close cache store: %+v", err)
+	}
+}
@@ -0,0 +1,13 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitCluster joins the cluster if the cluster mode is on
+func InitCluster() {
+	if err := cluster.Start(); err != nil {
+		log.Fatalf("failed init cluster: %+v", err)
+	}
+}
@@ -167,6 +167,18 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.SharePreview, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PUBLIC},
 		{Key: conf.ShareArchivePreview, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PUBLIC},
 		{Key: conf.ShareForceProxy, Value: "true", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.CertificateExpiringDays, Value: "30", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `days before expiration when a certificate is marked as expiring`},
+		{Key: conf.PasswordMinLength, Value: "8", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `minimum length of new passwords`},
+		{Key: conf.PasswordMinClasses, Value: "0", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `minimum number of character classes (lowercase, uppercase, digits, symbols) in new passwords`},
+		{Key: conf.PasswordHistory, Value: "0", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `number of previous passwords which can not be reused, 0 to allow reuse`},
+		{Key: conf.PasswordMaxAge, Value: "0", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `days before a password must be changed, 0 to never expire`},
+		{Key: conf.AccountLockThreshold, Value: "10", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `failed sign-in attempts before the account is locked, 0 to disable`},
+		{Key: conf.AccountLockDuration, Value: "15", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `minutes an account stays locked, 0 to keep it locked until an admin unlocks it`},
+		{Key: conf.Force2FA, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `require admins and tenants to enroll TOTP or WebAuthn before they can use the API`},
+		{Key: conf.AuditLogEnabled, Value: "true", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.AuditLogRetention, Value: "90", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `days the audit events are kept, 0 to keep them forever`},
+		{Key: conf.StorageHealthCheckInterval, Value: "300", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `seconds between the health checks of a storage, 0 to disable them`},
+		{Key: conf.StorageHealthMaxBackoff, Value: "3600", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `max seconds between the re-inits of a broken storage, the delay doubles after each failure`},
 		{Key: conf.ShareSummaryContent, Value: "@{{creator}} shared {{#each files}}{{#if @first}}\"{{filename this}}\"{{/if}}{{#if @last}}{{#unless (eq @index 0)}} and {{@index}} more files{{/unless}}{{/if}}{{/each}} from {{site_title}}: {{base_url}}/@s/{{id}}{{#if pwd}} , the share code is {{pwd}}{{/if}}{{#if expires}}, please access before {{dateLocaleString expires}}.{{/if}}", Type: conf.TypeText, Group: model.GLOBAL, Flag: model.PUBLIC},
 
 		// single settings
@@ -192,6 +204,9 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.SSODefaultDir, Value: "/", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: conf.SSODefaultPermission, Value: "0", Type: conf.TypeNumber, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: conf.SSOCompatibilityMode, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PUBLIC},
+		{Key: conf.SSOGroupsClaim, Value: "groups", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE},
+		{Key: conf.OIDCProviderEnabled, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PRIVATE},
+		{Key: conf.SCIMToken, Value: "", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE},
 
 		// ldap settings
 		{Key: conf.LdapLoginEnabled, Value: "false", Type: conf.TypeBool, Group: model.LDAP, Flag: model.PUBLIC},
@@ -203,11 +218,14 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.LdapDefaultDir, Value: "/", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: conf.LdapDefaultPermission, Value: "0", Type: conf.TypeNumber, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: conf.LdapLoginTips, Value: "login with ldap", Type: conf.TypeString, Group: model.LDAP, Flag: model.PUBLIC},
+		{Key: conf.LdapGroupAttribute, Value: "memberOf", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
+		{Key: conf.LdapSyncInterval, Value: "0", Type: conf.TypeNumber, Group: model.LDAP, Flag: model.PRIVATE, Help: `minutes between reconciliations of LDAP users, 0 to disable`},
 
 		// s3 settings
 		{Key: conf.S3AccessKeyId, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
 		{Key: conf.S3SecretAccessKey, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
 		{Key: conf.S3Buckets, Value: "[]", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
+		{Key: conf.S3User, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE, Help: `the user the access key acts as, the admin if empty`},
 
 		// ftp settings
 		{Key: conf.FTPPublicHost, Value: "127.0.0.1", Type: conf.TypeString, Group: model.FTP, Flag: model.PRIVATE},
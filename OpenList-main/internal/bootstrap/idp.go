@@ -0,0 +1,12 @@
+package bootstrap
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/idp"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func InitIdP() {
+	if err := idp.Init(); err != nil {
+		utils.Log.Fatalf("failed to init oidc provider: %+v", err)
+	}
+}
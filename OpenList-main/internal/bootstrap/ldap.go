@@ -0,0 +1,36 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/ldap"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+)
+
+var ldapSyncCron *cron.Cron
+
+// InitLdapSync checks every minute whether the LDAP users are due for a reconciliation,
+// so that a change of the interval takes effect without a restart. In a cluster only the leader syncs.
+func InitLdapSync() {
+	var last time.Time
+	ldapSyncCron = cron.NewCron(time.Minute)
+	ldapSyncCron.Do(func() {
+		interval := setting.GetInt(conf.LdapSyncInterval, 0)
+		if interval <= 0 || !cluster.IsLeader() || !setting.GetBool(conf.LdapLoginEnabled) ||
+			time.Since(last) < time.Duration(interval)*time.Minute {
+			return
+		}
+		last = time.Now()
+		report, _ := ldap.Sync()
+		report.Log()
+	})
+}
+
+func StopLdapSync() {
+	if ldapSyncCron != nil {
+		ldapSyncCron.Stop()
+	}
+}
@@ -9,7 +9,7 @@ import (
 // HashPwdForOldVersion encode passwords using SHA256
 // First published: 75acbcc perf: sha256 for user's password (close #3552) by Andy Hsu
 func HashPwdForOldVersion() {
-	users, _, err := op.GetUsers(1, -1)
+	users, _, err := op.GetUsers(1, -1, nil)
 	if err != nil {
 		utils.Log.Fatalf("[hash pwd for old version] failed get users: %v", err)
 	}
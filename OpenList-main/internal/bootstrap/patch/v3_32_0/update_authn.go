@@ -9,7 +9,7 @@ import (
 // UpdateAuthnForOldVersion updates users' authn
 // First published: bdfc159 fix: webauthn logspam (#6181) by itsHenry
 func UpdateAuthnForOldVersion() {
-	users, _, err := op.GetUsers(1, -1)
+	users, _, err := op.GetUsers(1, -1, nil)
 	if err != nil {
 		utils.Log.Fatalf("[update authn for old version] failed get users: %v", err)
 	}
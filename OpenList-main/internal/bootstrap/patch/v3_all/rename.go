@@ -7,7 +7,7 @@ import (
 
 // Rename Alist V3 driver to OpenList
 func RenameAlistV3Driver() {
-	storages, _, err := db.GetStorages(1, -1)
+	storages, _, err := db.GetStorages(1, -1, nil)
 	if err != nil {
 		utils.Log.Errorf("[RenameAlistV3Driver] failed to get storages: %s", err.Error())
 		return
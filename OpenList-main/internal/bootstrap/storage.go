@@ -2,14 +2,19 @@ package bootstrap
 
 import (
 	"context"
+	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 )
 
+var storageHealthCron *cron.Cron
+
 func LoadStorages() {
 	storages, err := db.GetEnabledStorages()
 	if err != nil {
@@ -28,3 +33,15 @@ func LoadStorages() {
 		conf.StoragesLoaded = true
 	}(storages)
 }
+
+// InitStorageHealth looks for the storages due for a health check every 10 seconds,
+// the interval of each storage is set by the storage_health_check_interval setting.
+// In a cluster only the leader checks the storages.
+func InitStorageHealth() {
+	storageHealthCron = cron.NewCron(10 * time.Second)
+	storageHealthCron.Do(func() {
+		if conf.StoragesLoaded && cluster.IsLeader() {
+			op.CheckStoragesHealth(context.Background())
+		}
+	})
+}
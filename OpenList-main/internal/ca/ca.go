@@ -0,0 +1,254 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	caCertName = "ca.crt"
+	caKeyName  = "ca.key"
+
+	caValidity = 10 * 365 * 24 * time.Hour
+)
+
+// Authority is the certificate authority used to sign tenant certificates.
+type Authority struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	Signer  crypto.Signer
+}
+
+var (
+	authority *Authority
+	mu        sync.RWMutex
+)
+
+// Init loads the CA configured in conf.Conf.CA, or loads/generates one
+// under the data directory if no external CA is configured.
+func Init() error {
+	var (
+		a   *Authority
+		err error
+	)
+	if conf.Conf.CA.CertFile != "" || conf.Conf.CA.KeyFile != "" {
+		if conf.Conf.CA.CertFile == "" || conf.Conf.CA.KeyFile == "" {
+			return errors.New("both ca cert_file and key_file must be set to import a CA")
+		}
+		a, err = LoadFromFile(conf.Conf.CA.CertFile, conf.Conf.CA.KeyFile)
+		if err != nil {
+			return errors.WithMessage(err, "failed to import CA")
+		}
+	} else {
+		a, err = LoadOrGenerate(filepath.Join(flags.DataDir, "ca"))
+		if err != nil {
+			return err
+		}
+	}
+	Set(a)
+	return nil
+}
+
+// Set replaces the active authority.
+func Set(a *Authority) {
+	mu.Lock()
+	defer mu.Unlock()
+	authority = a
+}
+
+// Get returns the active authority, or nil if Init has not been called.
+func Get() *Authority {
+	mu.RLock()
+	defer mu.RUnlock()
+	return authority
+}
+
+// LoadOrGenerate loads ca.crt/ca.key from dir, generating a new
+// self-signed CA there if they do not exist.
+func LoadOrGenerate(dir string) (*Authority, error) {
+	certPath := filepath.Join(dir, caCertName)
+	keyPath := filepath.Join(dir, caKeyName)
+	if utils.Exists(certPath) && utils.Exists(keyPath) {
+		return LoadFromFile(certPath, keyPath)
+	}
+	if !utils.Exists(dir) {
+		if err := utils.CreateNestedDirectory(dir); err != nil {
+			return nil, errors.WithMessage(err, "failed to create ca directory")
+		}
+	}
+	a, keyPEM, err := Generate("OpenList Tenant CA")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, errors.WithMessage(err, "failed to write CA private key")
+	}
+	if err = os.WriteFile(certPath, a.CertPEM, 0644); err != nil {
+		_ = os.Remove(keyPath)
+		return nil, errors.WithMessage(err, "failed to write CA certificate")
+	}
+	utils.Log.Infof("generated new CA certificate at %s", certPath)
+	return a, nil
+}
+
+// LoadFromFile loads a PEM encoded CA certificate and private key.
+func LoadFromFile(certPath, keyPath string) (*Authority, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return Parse(certPEM, keyPEM)
+}
+
+// Parse builds an Authority from a PEM encoded certificate and private key.
+func Parse(certPEM, keyPEM []byte) (*Authority, error) {
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, errors.New("certificate is not a CA")
+	}
+	signer, err := ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKeyEqual(cert.PublicKey, signer.Public()) {
+		return nil, errors.New("CA private key does not match certificate")
+	}
+	return &Authority{
+		Cert:    cert,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		Signer:  signer,
+	}, nil
+}
+
+// Generate creates a new self-signed CA and returns it with its PEM encoded key.
+func Generate(commonName string) (*Authority, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to generate CA key")
+	}
+	serial, err := NewSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{"OpenList"},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to create CA certificate")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	keyPEM, err := EncodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Authority{
+		Cert:    cert,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Signer:  key,
+	}, keyPEM, nil
+}
+
+// NewSerialNumber returns a random 128-bit certificate serial number.
+func NewSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to generate serial number")
+	}
+	return serial, nil
+}
+
+// SerialToHex formats a serial number the way it is stored in model.Certificate.
+func SerialToHex(serial *big.Int) string {
+	return strings.ToUpper(serial.Text(16))
+}
+
+func ParseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("failed to parse PEM block containing the certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block containing the key")
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func EncodePrivateKeyPEM(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// CertPool returns a pool containing the active CA certificate, used to verify tenant client certificates.
+func CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	if a := Get(); a != nil {
+		pool.AddCert(a.Cert)
+	}
+	return pool
+}
@@ -0,0 +1,154 @@
+package ca_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func setupAuthority(t *testing.T) *ca.Authority {
+	a, keyPEM, err := ca.Generate("test CA")
+	if err != nil {
+		t.Fatalf("failed to generate CA: %+v", err)
+	}
+	reloaded, err := ca.Parse(a.CertPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to parse generated CA: %+v", err)
+	}
+	ca.Set(reloaded)
+	return reloaded
+}
+
+func TestIssue(t *testing.T) {
+	a := setupAuthority(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(a.Cert)
+	now := time.Now()
+	var tests = []struct {
+		typ   model.CertificateType
+		usage x509.ExtKeyUsage
+		dns   bool
+	}{
+		{typ: model.CertificateTypeUser, usage: x509.ExtKeyUsageClientAuth},
+		{typ: model.CertificateTypeNode, usage: x509.ExtKeyUsageServerAuth, dns: true},
+	}
+	for _, tt := range tests {
+		issued, err := ca.Issue(&ca.IssueReq{
+			Type:      tt.typ,
+			Username:  "tenant1",
+			UserID:    3,
+			NotBefore: now,
+			NotAfter:  now.AddDate(1, 0, 0),
+		})
+		if err != nil {
+			t.Fatalf("failed to issue %s certificate: %+v", tt.typ, err)
+		}
+		if _, err = issued.Cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{tt.usage}}); err != nil {
+			t.Errorf("%s certificate does not verify: %+v", tt.typ, err)
+		}
+		if issued.Cert.Subject.CommonName != "tenant1" {
+			t.Errorf("expect common name tenant1, got %s", issued.Cert.Subject.CommonName)
+		}
+		if (len(issued.Cert.DNSNames) > 0) != tt.dns {
+			t.Errorf("unexpected DNS names for %s certificate: %v", tt.typ, issued.Cert.DNSNames)
+		}
+		key, err := ca.ParsePrivateKeyPEM(issued.KeyPEM)
+		if err != nil {
+			t.Fatalf("failed to parse issued key: %+v", err)
+		}
+		if !key.Public().(*ecdsa.PublicKey).Equal(issued.Cert.PublicKey) {
+			t.Errorf("issued key does not match %s certificate", tt.typ)
+		}
+	}
+}
+
+func TestIssueInvalidType(t *testing.T) {
+	setupAuthority(t)
+	now := time.Now()
+	_, err := ca.Issue(&ca.IssueReq{
+		Type:      "unknown",
+		Username:  "tenant1",
+		NotBefore: now,
+		NotAfter:  now.Add(time.Hour),
+	})
+	if err == nil {
+		t.Errorf("expect error for unknown certificate type")
+	}
+}
+
+func newCSR(t *testing.T, key crypto.Signer) string {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "ignored"},
+	}, key)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %+v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func TestParseCSR(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	weakKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	tampered := []byte(newCSR(t, ecKey))
+	block, _ := pem.Decode(tampered)
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	var tests = []struct {
+		name  string
+		csr   string
+		isErr bool
+	}{
+		{name: "ecdsa", csr: newCSR(t, ecKey), isErr: false},
+		{name: "weak rsa", csr: newCSR(t, weakKey), isErr: true},
+		{name: "bad signature", csr: string(pem.EncodeToMemory(block)), isErr: true},
+		{name: "not pem", csr: "hello", isErr: true},
+	}
+	for _, tt := range tests {
+		_, err := ca.ParseCSR(tt.csr)
+		if (err != nil) != tt.isErr {
+			t.Errorf("%s: unexpected result %v", tt.name, err)
+		}
+		if err != nil && !errors.Is(err, errs.InvalidCSR) {
+			t.Errorf("%s: expect InvalidCSR, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestSignCSR(t *testing.T) {
+	setupAuthority(t)
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	csr, err := ca.ParseCSR(newCSR(t, key))
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %+v", err)
+	}
+	now := time.Now()
+	issued, err := ca.Sign(&ca.IssueReq{
+		Type:      model.CertificateTypeNode,
+		Username:  "node1",
+		NotBefore: now,
+		NotAfter:  now.Add(time.Hour),
+	}, csr.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to sign CSR: %+v", err)
+	}
+	if len(issued.KeyPEM) != 0 {
+		t.Errorf("expect no private key when signing a CSR")
+	}
+	if !key.PublicKey.Equal(issued.Cert.PublicKey) {
+		t.Errorf("signed certificate does not carry the CSR public key")
+	}
+	if issued.Cert.Subject.CommonName != "node1" {
+		t.Errorf("expect subject derived from tenant, got %s", issued.Cert.Subject.CommonName)
+	}
+}
@@ -0,0 +1,184 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// IssueReq describes the certificate to be signed for a tenant.
+type IssueReq struct {
+	Type      model.CertificateType
+	Username  string
+	UserID    uint
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// Issued is a freshly signed certificate together with its private key.
+type Issued struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// SerialHex returns the serial number in the upper-case hex form stored in the database.
+func (i *Issued) SerialHex() string {
+	return SerialToHex(i.Cert.SerialNumber)
+}
+
+const (
+	// CRLPath and OCSPPath are embedded into issued certificates, relative to the site URL
+	CRLPath  = "/api/public/certificate/crl"
+	OCSPPath = "/api/public/certificate/ocsp"
+
+	minRSAKeySize = 2048
+	maxRSAKeySize = 8192
+)
+
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Issue generates a new key pair and signs a certificate for it.
+func Issue(req *IssueReq) (*Issued, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to generate key")
+	}
+	issued, err := Sign(req, key.Public())
+	if err != nil {
+		return nil, err
+	}
+	issued.KeyPEM, err = EncodePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Sign signs a certificate for the given public key with the active authority.
+func Sign(req *IssueReq, pub crypto.PublicKey) (*Issued, error) {
+	a := Get()
+	if a == nil {
+		return nil, errors.New("certificate authority is not initialized")
+	}
+	tmpl, err := template(req)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.Cert, pub, a.Signer)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to sign certificate")
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Issued{
+		Cert:    cert,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+func template(req *IssueReq) (*x509.Certificate, error) {
+	if req.Username == "" {
+		return nil, errors.New("certificate subject is empty")
+	}
+	if !req.NotAfter.After(req.NotBefore) {
+		return nil, errors.New("certificate expires before it becomes valid")
+	}
+	serial, err := NewSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:         req.Username,
+			Organization:       []string{"OpenList"},
+			OrganizationalUnit: []string{string(req.Type)},
+			SerialNumber:       fmt.Sprint(req.UserID),
+		},
+		NotBefore:             req.NotBefore,
+		NotAfter:              req.NotAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		URIs: []*url.URL{{
+			Scheme: "openlist",
+			Host:   string(req.Type),
+			Path:   "/" + req.Username,
+		}},
+	}
+	switch req.Type {
+	case model.CertificateTypeUser:
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case model.CertificateTypeNode:
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+		if name := strings.ToLower(req.Username); dnsLabel.MatchString(name) {
+			tmpl.DNSNames = []string{name}
+		}
+	default:
+		return nil, fmt.Errorf("unknown certificate type: %s", req.Type)
+	}
+	if conf.Conf != nil && conf.Conf.SiteURL != "" {
+		base := strings.TrimSuffix(conf.Conf.SiteURL, "/")
+		tmpl.CRLDistributionPoints = []string{base + CRLPath}
+		tmpl.OCSPServer = []string{base + OCSPPath}
+	}
+	return tmpl, nil
+}
+
+// ParseCSR decodes a PEM encoded PKCS#10 request and checks its signature
+// and public key. Only the public key is used when signing; the subject is
+// always derived from the requesting tenant.
+func ParseCSR(data string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST" {
+		return nil, errs.NewErr(errs.InvalidCSR, "failed to parse PEM block containing the request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, errs.NewErr(errs.InvalidCSR, "%s", err.Error())
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, errs.NewErr(errs.InvalidCSR, "bad signature: %s", err.Error())
+	}
+	if err = checkPublicKey(csr.PublicKey); err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+func checkPublicKey(pub crypto.PublicKey) error {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if size := k.N.BitLen(); size < minRSAKeySize || size > maxRSAKeySize {
+			return errs.NewErr(errs.InvalidCSR, "RSA key size %d is not between %d and %d", size, minRSAKeySize, maxRSAKeySize)
+		}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return errs.NewErr(errs.InvalidCSR, "unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+	default:
+		return errs.NewErr(errs.InvalidCSR, "unsupported public key type %T", pub)
+	}
+	return nil
+}
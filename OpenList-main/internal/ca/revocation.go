@@ -0,0 +1,84 @@
+package ca
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+// ErrForeignIssuer is returned for an OCSP request about a certificate of another issuer,
+// which RFC 6960 answers with the unauthorized status
+var ErrForeignIssuer = errors.New("certificate was not issued by this authority")
+
+// CreateCRL signs a DER encoded certificate revocation list with the active authority.
+func CreateCRL(entries []x509.RevocationListEntry, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	a := Get()
+	if a == nil {
+		return nil, errors.New("certificate authority is not initialized")
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		// CRL numbers only need to be monotonically increasing
+		Number:     big.NewInt(thisUpdate.UnixNano()),
+		ThisUpdate: thisUpdate,
+		NextUpdate: nextUpdate,
+	}, a.Cert, a.Signer)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create CRL")
+	}
+	return der, nil
+}
+
+// ParseOCSPRequest parses a DER encoded OCSP request and checks that it asks
+// about a certificate issued by the active authority.
+func ParseOCSPRequest(data []byte) (*ocsp.Request, error) {
+	a := Get()
+	if a == nil {
+		return nil, errors.New("certificate authority is not initialized")
+	}
+	req, err := ocsp.ParseRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	if !req.HashAlgorithm.Available() {
+		return nil, errors.New("unsupported OCSP hash algorithm")
+	}
+	keyHash, err := issuerKeyHash(a.Cert, req.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(keyHash, req.IssuerKeyHash) {
+		return nil, ErrForeignIssuer
+	}
+	return req, nil
+}
+
+// CreateOCSPResponse signs an OCSP response with the active authority.
+func CreateOCSPResponse(tmpl ocsp.Response) ([]byte, error) {
+	a := Get()
+	if a == nil {
+		return nil, errors.New("certificate authority is not initialized")
+	}
+	return ocsp.CreateResponse(a.Cert, a.Cert, tmpl, a.Signer)
+}
+
+func issuerKeyHash(issuer *x509.Certificate, hash crypto.Hash) ([]byte, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	h := hash.New()
+	h.Write(spki.PublicKey.RightAlign())
+	return h.Sum(nil), nil
+}
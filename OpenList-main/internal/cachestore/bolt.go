@@ -0,0 +1,108 @@
+package cachestore
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("cache")
+
+// boltPurgeInterval is how often the expired entries are removed from the file,
+// until then they are only skipped
+const boltPurgeInterval = 10 * time.Minute
+
+// Bolt stores the entries in a bbolt file, each value is prefixed with its expiry in unix nanoseconds
+type Bolt struct {
+	db    *bolt.DB
+	purge *cron.Cron
+}
+
+func NewBolt(file string) (*Bolt, error) {
+	db, err := bolt.Open(file, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed open cache file %s", file)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.WithStack(err)
+	}
+	b := &Bolt{db: db}
+	b.Purge()
+	b.purge = cron.NewCron(boltPurgeInterval)
+	b.purge.Do(b.Purge)
+	return b, nil
+}
+
+func (b *Bolt) Get(key string) (value []byte, ok bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get([]byte(key))
+		if len(v) < 8 || boltExpired(v, time.Now()) {
+			return nil
+		}
+		// the value is only valid in the transaction
+		value, ok = append([]byte(nil), v[8:]...), true
+		return nil
+	})
+	return value, ok, errors.WithStack(err)
+}
+
+func (b *Bolt) Set(key string, value []byte, ttl time.Duration) error {
+	v := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(v, uint64(time.Now().Add(ttl).UnixNano()))
+	copy(v[8:], value)
+	return errors.WithStack(b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), v)
+	}))
+}
+
+func (b *Bolt) Del(key string) error {
+	return errors.WithStack(b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	}))
+}
+
+// Purge removes the expired entries
+func (b *Bolt) Purge() {
+	now := time.Now()
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		// deleting while iterating with a cursor skips entries, so the keys are collected first
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			if len(v) < 8 || boltExpired(v, now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("failed purge the cache file: %+v", err)
+	}
+}
+
+func (b *Bolt) Close() error {
+	b.purge.Stop()
+	return errors.WithStack(b.db.Close())
+}
+
+func boltExpired(v []byte, now time.Time) bool {
+	return int64(binary.BigEndian.Uint64(v)) <= now.UnixNano()
+}
@@ -0,0 +1,42 @@
+package cachestore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBolt(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.db")
+	b, err := NewBolt(file)
+	if err != nil {
+		t.Fatalf("failed to open: %+v", err)
+	}
+	if err = b.Set("kept", []byte("a"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Set("expired", []byte("b"), -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Get("expired"); ok {
+		t.Errorf("expect the expired entry to be skipped")
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the entries survive a reopen, which purges the expired ones
+	if b, err = NewBolt(file); err != nil {
+		t.Fatalf("failed to reopen: %+v", err)
+	}
+	defer b.Close()
+	if v, ok, _ := b.Get("kept"); !ok || string(v) != "a" {
+		t.Errorf("expect the entry to be kept, got %q", v)
+	}
+	if err = b.Del("kept"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Get("kept"); ok {
+		t.Errorf("expect the entry to be deleted")
+	}
+}
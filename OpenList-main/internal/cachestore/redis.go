@@ -0,0 +1,204 @@
+package cachestore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	redisTimeout  = 5 * time.Second
+	redisMaxIdle  = 16
+	redisNilReply = -1
+	// redisBackoff is how long the server is not tried after it failed,
+	// so that the requests don't each wait for the timeout while it is down
+	redisBackoff = 10 * time.Second
+)
+
+var (
+	errRedisNil  = errors.New("redis: nil")
+	errRedisDown = errors.New("redis: server failed recently, not tried until the backoff ends")
+)
+
+// Redis talks the RESP protocol of redis, so that any compatible server (valkey, keydb, dragonfly...)
+// can be shared by several instances. Only the few commands the cache needs are implemented.
+type Redis struct {
+	addr     string
+	password string
+	db       int
+	prefix   string
+	idle     chan *redisConn
+	// downUntil is the time in unix nanoseconds the server is tried again after a failure
+	downUntil atomic.Int64
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func NewRedis(addr, password string, db int, prefix string) *Redis {
+	return &Redis{
+		addr:     addr,
+		password: password,
+		db:       db,
+		prefix:   prefix,
+		idle:     make(chan *redisConn, redisMaxIdle),
+	}
+}
+
+func (s *Redis) Get(key string) ([]byte, bool, error) {
+	v, err := s.do("GET", s.prefix+key)
+	if errors.Is(err, errRedisNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, false, errors.Errorf("redis: unexpected reply %v", v)
+	}
+	return b, true, nil
+}
+
+func (s *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	_, err := s.do("SET", s.prefix+key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (s *Redis) Del(key string) error {
+	_, err := s.do("DEL", s.prefix+key)
+	return err
+}
+
+func (s *Redis) Close() error {
+	for {
+		select {
+		case c := <-s.idle:
+			_ = c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *Redis) do(args ...string) (any, error) {
+	if time.Now().UnixNano() < s.downUntil.Load() {
+		return nil, errRedisDown
+	}
+	c, err := s.conn()
+	if err != nil {
+		s.failed()
+		return nil, err
+	}
+	v, err := c.do(args...)
+	// a server error leaves the connection usable, any other one may leave a reply unread
+	var replyErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &replyErr) {
+		_ = c.Close()
+		s.failed()
+		return nil, err
+	}
+	select {
+	case s.idle <- c:
+	default:
+		_ = c.Close()
+	}
+	return v, err
+}
+
+// failed starts the backoff, the idle connections are likely broken too
+func (s *Redis) failed() {
+	s.downUntil.Store(time.Now().Add(redisBackoff).UnixNano())
+	_ = s.Close()
+}
+
+func (s *Redis) conn() (*redisConn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+	nc, err := net.DialTimeout("tcp", s.addr, redisTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed connect redis %s", s.addr)
+	}
+	c := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	if s.password != "" {
+		if _, err = c.do("AUTH", s.password); err != nil {
+			_ = c.Close()
+			return nil, errors.WithMessage(err, "failed auth redis")
+		}
+	}
+	if s.db != 0 {
+		if _, err = c.do("SELECT", strconv.Itoa(s.db)); err != nil {
+			_ = c.Close()
+			return nil, errors.WithMessage(err, "failed select redis db")
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) do(args ...string) (any, error) {
+	if err := c.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	w := bufio.NewWriter(c.Conn)
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return c.reply()
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// reply reads a simple string, an error, an integer or a bulk string
+func (c *redisConn) reply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return n, errors.WithStack(err)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if n == redisNilReply {
+			return nil, errRedisNil
+		}
+		b := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, b); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return b[:n], nil
+	default:
+		return nil, errors.Errorf("redis: unsupported reply %q", line)
+	}
+}
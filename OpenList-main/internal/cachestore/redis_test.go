@@ -0,0 +1,29 @@
+package cachestore
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRedisBackoff(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	r := NewRedis(addr, "", 0, "")
+	if _, _, err = r.Get("a"); err == nil || errors.Is(err, errRedisDown) {
+		t.Fatalf("expect the connection to fail, got %v", err)
+	}
+	// the server is not tried again until the backoff ends
+	if _, _, err = r.Get("a"); !errors.Is(err, errRedisDown) {
+		t.Errorf("expect the backoff error, got %v", err)
+	}
+	r.downUntil.Store(0)
+	if err = r.Set("a", []byte("b"), 0); err == nil || errors.Is(err, errRedisDown) {
+		t.Errorf("expect the server to be tried again after the backoff, got %v", err)
+	}
+}
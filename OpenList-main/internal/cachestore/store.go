@@ -0,0 +1,34 @@
+// Package cachestore keeps the listing and link caches of the storages outside of the process,
+// so that they survive a restart or are shared by several instances.
+package cachestore
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/pkg/errors"
+)
+
+// Store is a key value store whose values expire.
+// A failing store is treated as a cache miss by its users, so the errors are only for logging.
+type Store interface {
+	// Get returns false if the key does not exist or is expired
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Del(key string) error
+	Close() error
+}
+
+// New opens the store of the config, nil is returned for the in-memory cache
+func New(c conf.Cache) (Store, error) {
+	switch c.Type {
+	case "", "memory":
+		return nil, nil
+	case "bolt":
+		return NewBolt(c.BoltFile)
+	case "redis":
+		return NewRedis(c.RedisAddress, c.RedisPassword, c.RedisDB, c.Prefix), nil
+	default:
+		return nil, errors.Errorf("unknown cache type: %s", c.Type)
+	}
+}
@@ -0,0 +1,196 @@
+// Package cluster lets several instances serve the same database.
+// The nodes announce themselves in the database, broadcast their changes through it,
+// and elect a leader with a lease for the jobs that must run once.
+package cluster
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const leaderLease = "leader"
+
+// the events are read again for this long, so that the ones committed late or
+// stamped by a node with a slightly different clock are not missed
+const eventWindow = time.Minute
+
+var (
+	node      model.ClusterNode
+	leader    atomic.Bool
+	heartbeat *cron.Cron
+	poll      *cron.Cron
+
+	// the events applied within the window
+	applied   = make(map[uint]time.Time)
+	appliedMu sync.Mutex
+)
+
+func Enabled() bool {
+	return conf.Conf.Cluster.Enable
+}
+
+// NodeName is the name of this node, empty if the cluster mode is off
+func NodeName() string {
+	return node.Name
+}
+
+// IsLeader tells whether this node runs the jobs of the cluster, a single instance always does
+func IsLeader() bool {
+	return !Enabled() || leader.Load()
+}
+
+func nodeTimeout() time.Duration {
+	return time.Duration(max(conf.Conf.Cluster.NodeTimeout, 3)) * time.Second
+}
+
+// Start joins the cluster, it should be called before the storages are loaded
+func Start() error {
+	if !Enabled() {
+		return nil
+	}
+	name := conf.Conf.Cluster.NodeName
+	if name == "" {
+		var err error
+		if name, err = os.Hostname(); err != nil {
+			return errors.Wrap(err, "failed get hostname for the node name")
+		}
+	}
+	now := time.Now()
+	node = model.ClusterNode{Name: name, StartedAt: now, LastSeen: now}
+	if err := db.SaveClusterNode(&node); err != nil {
+		return errors.WithMessage(err, "failed join cluster")
+	}
+	renewLeader()
+	op.SetChangeNotifier(publish)
+
+	// only the changes made from now on are applied, the rest is loaded from the database anyway
+	since := now
+	heartbeat = cron.NewCron(nodeTimeout() / 3)
+	heartbeat.Do(beat)
+	poll = cron.NewCron(time.Duration(max(conf.Conf.Cluster.PollInterval, 1)) * time.Second)
+	poll.Do(func() {
+		since = pollEvents(since)
+	})
+	log.Infof("joined cluster as node %s, leader: %t", node.Name, leader.Load())
+	return nil
+}
+
+// Stop leaves the cluster, so that another node takes the lead at once
+func Stop() {
+	if !Enabled() || node.Name == "" {
+		return
+	}
+	heartbeat.Stop()
+	poll.Stop()
+	if err := db.ReleaseClusterLease(leaderLease, node.Name); err != nil {
+		log.Errorf("failed release the leader lease: %+v", err)
+	}
+	if err := db.DeleteClusterNode(node.Name); err != nil {
+		log.Errorf("failed leave cluster: %+v", err)
+	}
+}
+
+func beat() {
+	node.LastSeen = time.Now()
+	if err := db.SaveClusterNode(&node); err != nil {
+		log.Errorf("failed save the heartbeat of node %s: %+v", node.Name, err)
+	}
+	renewLeader()
+	if !leader.Load() {
+		return
+	}
+	takeOverTasks()
+	if err := db.DeleteClusterEventsBefore(time.Now().Add(-10 * eventWindow)); err != nil {
+		log.Errorf("failed delete old cluster events: %+v", err)
+	}
+}
+
+func renewLeader() {
+	ok, err := db.AcquireClusterLease(leaderLease, node.Name, nodeTimeout())
+	if err != nil {
+		log.Errorf("failed acquire the leader lease: %+v", err)
+		// keep the lead until the lease surely expired on the others
+		return
+	}
+	if leader.Swap(ok) != ok {
+		log.Infof("node %s is the leader: %t", node.Name, ok)
+	}
+}
+
+func publish(typ, key string) {
+	e := &model.ClusterEvent{CreatedAt: time.Now(), Node: node.Name, Type: typ, Key: key}
+	if err := db.CreateClusterEvent(e); err != nil {
+		log.Errorf("failed broadcast %s change of %s: %+v", typ, key, err)
+	}
+}
+
+// pollEvents applies the new events of the other nodes and returns where the next poll starts
+func pollEvents(since time.Time) time.Time {
+	events, err := db.GetClusterEventsSince(since, node.Name)
+	if err != nil {
+		log.Errorf("failed get cluster events: %+v", err)
+		return since
+	}
+	appliedMu.Lock()
+	defer appliedMu.Unlock()
+	for _, e := range events {
+		if _, ok := applied[e.ID]; ok {
+			continue
+		}
+		applied[e.ID] = e.CreatedAt
+		log.Debugf("apply %s change of %s from node %s", e.Type, e.Key, e.Node)
+		if err := op.ApplyChange(context.Background(), e.Type, e.Key); err != nil {
+			log.Errorf("failed apply %s change of %s from node %s: %+v", e.Type, e.Key, e.Node, err)
+		}
+	}
+	next := time.Now().Add(-eventWindow)
+	if next.Before(since) {
+		next = since
+	}
+	for id, t := range applied {
+		if t.Before(next) {
+			delete(applied, id)
+		}
+	}
+	return next
+}
+
+type NodeInfo struct {
+	model.ClusterNode
+	Alive   bool `json:"alive"`
+	Leader  bool `json:"leader"`
+	Current bool `json:"current"`
+}
+
+// Nodes returns the nodes which joined the cluster
+func Nodes() ([]NodeInfo, error) {
+	nodes, err := db.GetClusterNodes()
+	if err != nil {
+		return nil, err
+	}
+	var holder string
+	if l, err := db.GetClusterLease(leaderLease); err == nil && l.ExpiresAt.After(time.Now()) {
+		holder = l.Holder
+	}
+	infos := make([]NodeInfo, len(nodes))
+	for i, n := range nodes {
+		infos[i] = NodeInfo{
+			ClusterNode: n,
+			Alive:       time.Since(n.LastSeen) < nodeTimeout(),
+			Leader:      n.Name == holder,
+			Current:     n.Name == node.Name,
+		}
+	}
+	return infos, nil
+}
@@ -0,0 +1,89 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestCluster(t *testing.T) {
+	conf.Conf.Cluster.Enable = true
+	conf.Conf.Cluster.NodeName = "a"
+	defer func() { conf.Conf.Cluster.Enable = false }()
+
+	// a node which crashed with a copy task left
+	gone := &model.ClusterNode{Name: "b", StartedAt: time.Now().Add(-time.Hour), LastSeen: time.Now().Add(-time.Hour)}
+	if err := db.SaveClusterNode(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTaskData(&model.TaskItem{Key: "copy@b", PersistData: `[{"id":"1"}]`}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Start(); err != nil {
+		t.Fatalf("failed to start: %+v", err)
+	}
+	defer Stop()
+	if !IsLeader() {
+		t.Fatalf("expect the only node to lead")
+	}
+
+	PrepareTasks([]string{"copy"})
+	if item, err := db.GetTaskDataByType("copy@a"); err != nil || item.PersistData != `[{"id":"1"}]` {
+		t.Errorf("expect the task of the gone node to be taken over, got %+v, %+v", item, err)
+	}
+	if _, err := db.GetTaskDataByType("copy@b"); err == nil {
+		t.Errorf("expect the tasks of the gone node to be removed")
+	}
+	if nodes, _ := Nodes(); len(nodes) != 1 || !nodes[0].Current || !nodes[0].Leader {
+		t.Errorf("expect only this node to be left, got %+v", nodes)
+	}
+
+	// a node which crashes while this one runs
+	gone = &model.ClusterNode{Name: "c", StartedAt: time.Now().Add(-time.Hour), LastSeen: time.Now().Add(-time.Hour)}
+	if err := db.SaveClusterNode(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTaskData(&model.TaskItem{Key: "copy@c", PersistData: `[{"id":"2"}]`}); err != nil {
+		t.Fatal(err)
+	}
+	var adopted string
+	RegisterTaskAdopter("copy", func(tasks []byte) error {
+		adopted = string(tasks)
+		return nil
+	})
+	beat()
+	if adopted != `[{"id":"2"}]` {
+		t.Errorf("expect the task of the node gone at runtime to be taken over, got %s", adopted)
+	}
+	if _, err := db.GetTaskDataByType("copy@c"); err == nil {
+		t.Errorf("expect the tasks of the node gone at runtime to be removed")
+	}
+
+	release, err := Lock("index")
+	if err != nil {
+		t.Fatalf("failed to lock: %+v", err)
+	}
+	// another node can't take it until it is released
+	if ok, _ := db.AcquireClusterLease("lock:index", "b", time.Minute); ok {
+		t.Errorf("expect the lock to be held")
+	}
+	release()
+	if ok, _ := db.AcquireClusterLease("lock:index", "b", time.Minute); !ok {
+		t.Errorf("expect the released lock to be free")
+	}
+}
@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Lock makes sure a job runs on a single node at a time, the returned func releases it.
+// The lock is kept while this node is alive, so a crashed node frees it after the node timeout.
+func Lock(name string) (release func(), err error) {
+	if !Enabled() {
+		return func() {}, nil
+	}
+	name = "lock:" + name
+	ok, err := db.AcquireClusterLease(name, node.Name, nodeTimeout())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if l, err := db.GetClusterLease(name); err == nil {
+			return nil, errors.Errorf("it is running on node %s", l.Holder)
+		}
+		return nil, errors.New("it is running on another node")
+	}
+	renew := cron.NewCron(nodeTimeout() / 3)
+	renew.Do(func() {
+		if _, err := db.AcquireClusterLease(name, node.Name, nodeTimeout()); err != nil {
+			log.Errorf("failed renew %s: %+v", name, err)
+		}
+	})
+	return func() {
+		renew.Stop()
+		if err := db.ReleaseClusterLease(name, node.Name); err != nil {
+			log.Errorf("failed release %s: %+v", name, err)
+		}
+	}, nil
+}
@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// TaskKey is the key the persisted tasks of the type are kept under.
+// Each node keeps its own tasks, so that a task is never restored by two nodes.
+func TaskKey(typ string) string {
+	if !Enabled() {
+		return typ
+	}
+	return typ + "@" + node.Name
+}
+
+// TaskAdopter adds the persisted tasks taken over from a node which is gone to a running task manager
+type TaskAdopter func(tasks []byte) error
+
+var (
+	taskAdopters   = make(map[string]TaskAdopter)
+	taskAdoptersMu sync.Mutex
+)
+
+// RegisterTaskAdopter lets the leader hand the tasks of the type left by the nodes gone while it runs
+// to the running task manager, it should be called once the manager is created
+func RegisterTaskAdopter(typ string, adopt TaskAdopter) {
+	if !Enabled() {
+		return
+	}
+	taskAdoptersMu.Lock()
+	defer taskAdoptersMu.Unlock()
+	taskAdopters[typ] = adopt
+}
+
+// PrepareTasks creates the task rows of this node, and if it is the leader,
+// takes over the tasks left by the nodes which are gone and the ones persisted before the cluster mode.
+// It should be called before the task managers load their tasks.
+func PrepareTasks(types []string) {
+	if !Enabled() {
+		return
+	}
+	var gone []string
+	if IsLeader() {
+		gone = goneNodes()
+	}
+	for _, typ := range types {
+		own := TaskKey(typ)
+		tasks := loadTasks(own)
+		if IsLeader() {
+			tasks = append(tasks, adoptTasks(typ, false)...)
+			for _, name := range gone {
+				tasks = append(tasks, adoptTasks(typ+"@"+name, true)...)
+			}
+		}
+		data, _ := json.Marshal(tasks)
+		item := &model.TaskItem{Key: own, PersistData: string(data)}
+		var err error
+		if _, e := db.GetTaskDataByType(own); e != nil {
+			err = db.CreateTaskData(item)
+		} else {
+			err = db.UpdateTaskData(item)
+		}
+		if err != nil {
+			log.Errorf("failed save the %s tasks of node %s: %+v", typ, node.Name, err)
+		}
+	}
+	for _, name := range gone {
+		if err := db.DeleteClusterNode(name); err != nil {
+			log.Errorf("failed remove node %s: %+v", name, err)
+		}
+	}
+}
+
+// takeOverTasks hands the tasks of the nodes which are gone to the running task managers and removes the nodes,
+// the leader calls it on each heartbeat
+func takeOverTasks() {
+	taskAdoptersMu.Lock()
+	defer taskAdoptersMu.Unlock()
+	// before the task managers run, PrepareTasks takes over the tasks
+	if len(taskAdopters) == 0 {
+		return
+	}
+	for _, name := range goneNodes() {
+		for typ, adopt := range taskAdopters {
+			tasks := adoptTasks(typ+"@"+name, true)
+			if len(tasks) == 0 {
+				continue
+			}
+			data, _ := json.Marshal(tasks)
+			if err := adopt(data); err != nil {
+				log.Errorf("failed take over the %s tasks of node %s: %+v", typ, name, err)
+			}
+		}
+		if err := db.DeleteClusterNode(name); err != nil {
+			log.Errorf("failed remove node %s: %+v", name, err)
+		}
+	}
+}
+
+// goneNodes returns the names of the other nodes which stopped beating
+func goneNodes() []string {
+	nodes, err := Nodes()
+	if err != nil {
+		log.Errorf("failed get cluster nodes: %+v", err)
+		return nil
+	}
+	var gone []string
+	for _, n := range nodes {
+		if !n.Alive && !n.Current {
+			gone = append(gone, n.Name)
+		}
+	}
+	return gone
+}
+
+func loadTasks(key string) []json.RawMessage {
+	item, err := db.GetTaskDataByType(key)
+	if err != nil {
+		return nil
+	}
+	var tasks []json.RawMessage
+	if err = json.Unmarshal([]byte(item.PersistData), &tasks); err != nil {
+		log.Warnf("failed parse the tasks of %s: %+v", key, err)
+	}
+	return tasks
+}
+
+// adoptTasks empties or drops the tasks persisted under the key and returns them
+func adoptTasks(key string, drop bool) []json.RawMessage {
+	tasks := loadTasks(key)
+	var err error
+	if drop {
+		err = db.DeleteTaskData(key)
+	} else if len(tasks) > 0 {
+		err = db.UpdateTaskData(&model.TaskItem{Key: key, PersistData: "[]"})
+	}
+	if err != nil {
+		log.Errorf("failed take over the tasks of %s: %+v", key, err)
+		return nil
+	}
+	if len(tasks) > 0 {
+		log.Infof("took over %d tasks of %s", len(tasks), key)
+	}
+	return tasks
+}
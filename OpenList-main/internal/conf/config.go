@@ -35,6 +35,7 @@ type Scheme struct {
 	UnixFile     string `json:"unix_file" env:"UNIX_FILE"`
 	UnixFilePerm string `json:"unix_file_perm" env:"UNIX_FILE_PERM"`
 	EnableH2c    bool   `json:"enable_h2c" env:"ENABLE_H2C"`
+	ClientAuth   string `json:"client_auth" env:"CLIENT_AUTH"` // none, request or require a tenant CA signed client certificate
 }
 
 type LogConfig struct {
@@ -72,6 +73,7 @@ type TasksConfig struct {
 	Move               TaskConfig `json:"move" envPrefix:"MOVE_"`
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
+	MirrorCheck        TaskConfig `json:"mirror_check" envPrefix:"MIRROR_CHECK_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -105,6 +107,30 @@ type SFTP struct {
 	Listen string `json:"listen" env:"LISTEN"`
 }
 
+// Cache is where the listings and the links of the storages are cached,
+// bolt keeps them in a file across restarts and redis shares them between instances
+type Cache struct {
+	Type          string `json:"type" env:"TYPE"` // memory, bolt or redis
+	BoltFile      string `json:"bolt_file" env:"BOLT_FILE"`
+	RedisAddress  string `json:"redis_address" env:"REDIS_ADDRESS"`
+	RedisPassword string `json:"redis_password" env:"REDIS_PASSWORD"`
+	RedisDB       int    `json:"redis_db" env:"REDIS_DB"`
+	Prefix        string `json:"prefix" env:"PREFIX"` // prepended to the keys, for instances sharing a redis with others
+}
+
+// Cluster lets several instances share one database, they exchange their changes through it
+type Cluster struct {
+	Enable       bool   `json:"enable" env:"ENABLE"`
+	NodeName     string `json:"node_name" env:"NODE_NAME"`         // unique and stable across restarts, the hostname if empty
+	PollInterval int    `json:"poll_interval" env:"POLL_INTERVAL"` // seconds between the checks for the changes of other nodes
+	NodeTimeout  int    `json:"node_timeout" env:"NODE_TIMEOUT"`   // seconds without a heartbeat after which a node is gone
+}
+
+type CA struct {
+	CertFile string `json:"cert_file" env:"CERT_FILE"`
+	KeyFile  string `json:"key_file" env:"KEY_FILE"`
+}
+
 type Config struct {
 	Force                 bool        `json:"force" env:"FORCE"`
 	SiteURL               string      `json:"site_url" env:"SITE_URL"`
@@ -129,6 +155,9 @@ type Config struct {
 	S3                    S3          `json:"s3" envPrefix:"S3_"`
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
+	CA                    CA          `json:"ca" envPrefix:"CA_"`
+	Cache                 Cache       `json:"cache" envPrefix:"CACHE_"`
+	Cluster               Cluster     `json:"cluster" envPrefix:"CLUSTER_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
 }
 
@@ -137,6 +166,7 @@ func DefaultConfig(dataDir string) *Config {
 	indexDir := filepath.Join(dataDir, "bleve")
 	logPath := filepath.Join(dataDir, "log/log.log")
 	dbPath := filepath.Join(dataDir, "data.db")
+	cachePath := filepath.Join(dataDir, "cache.db")
 	return &Config{
 		Scheme: Scheme{
 			Address:    "0.0.0.0",
@@ -146,6 +176,7 @@ func DefaultConfig(dataDir string) *Config {
 			ForceHttps: false,
 			CertFile:   "",
 			KeyFile:    "",
+			ClientAuth: "none",
 		},
 		JwtSecret:      random.String(16),
 		TokenExpiresIn: 48,
@@ -214,6 +245,9 @@ func DefaultConfig(dataDir string) *Config {
 				Workers:  5,
 				MaxRetry: 2,
 			},
+			MirrorCheck: TaskConfig{
+				Workers: 1,
+			},
 			AllowRetryCanceled: false,
 		},
 		Cors: Cors{
@@ -242,6 +276,21 @@ func DefaultConfig(dataDir string) *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		CA: CA{
+			CertFile: "",
+			KeyFile:  "",
+		},
+		Cache: Cache{
+			Type:         "memory",
+			BoltFile:     cachePath,
+			RedisAddress: "localhost:6379",
+			Prefix:       "openlist:",
+		},
+		Cluster: Cluster{
+			Enable:       false,
+			PollInterval: 2,
+			NodeTimeout:  30,
+		},
 		LastLaunchedVersion: "",
 	}
 }
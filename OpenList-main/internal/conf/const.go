@@ -50,6 +50,24 @@ const (
 	ShareArchivePreview     = "share_archive_preview"
 	ShareForceProxy         = "share_force_proxy"
 	ShareSummaryContent     = "share_summary_content"
+	CertificateExpiringDays = "certificate_expiring_days"
+
+	// password policy and account lockout
+	PasswordMinLength    = "password_min_length"
+	PasswordMinClasses   = "password_min_classes"
+	PasswordHistory      = "password_history"
+	PasswordMaxAge       = "password_max_age"
+	AccountLockThreshold = "account_lock_threshold"
+	AccountLockDuration  = "account_lock_duration"
+	Force2FA             = "force_2fa"
+
+	// audit log
+	AuditLogEnabled   = "audit_log_enabled"
+	AuditLogRetention = "audit_log_retention"
+
+	// storage health
+	StorageHealthCheckInterval = "storage_health_check_interval"
+	StorageHealthMaxBackoff    = "storage_health_max_backoff"
 
 	// index
 	SearchIndex     = "search_index"
@@ -102,6 +120,9 @@ const (
 	SSODefaultDir        = "sso_default_dir"
 	SSODefaultPermission = "sso_default_permission"
 	SSOCompatibilityMode = "sso_compatibility_mode"
+	SSOGroupsClaim       = "sso_groups_claim"
+	OIDCProviderEnabled  = "oidc_provider_enabled"
+	SCIMToken            = "scim_token"
 
 	// ldap
 	LdapLoginEnabled      = "ldap_login_enabled"
@@ -113,11 +134,14 @@ const (
 	LdapDefaultPermission = "ldap_default_permission"
 	LdapDefaultDir        = "ldap_default_dir"
 	LdapLoginTips         = "ldap_login_tips"
+	LdapGroupAttribute    = "ldap_group_attribute"
+	LdapSyncInterval      = "ldap_sync_interval"
 
 	// s3
 	S3Buckets         = "s3_buckets"
 	S3AccessKeyId     = "s3_access_key_id"
 	S3SecretAccessKey = "s3_secret_access_key"
+	S3User            = "s3_user"
 
 	// qbittorrent
 	QbittorrentUrl      = "qbittorrent_url"
@@ -173,4 +197,9 @@ const (
 	UserAgentKey
 	PathKey
 	SharingIDKey
+	NoQuotaKey
+	ApiTokenKey
+	SessionKey
+	ProtocolKey
+	NoAuditKey
 )
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetPathACLById(id uint) (*model.PathACL, error) {
+	var a model.PathACL
+	if err := db.First(&a, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get path acl")
+	}
+	return &a, nil
+}
+
+func GetPathACLs(pageIndex, pageSize int) (acls []model.PathACL, count int64, err error) {
+	aclDB := db.Model(&model.PathACL{})
+	if err = aclDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get path acls count")
+	}
+	if err = aclDB.Order(columnName("path")).Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&acls).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find path acls")
+	}
+	return acls, count, nil
+}
+
+func GetAllPathACLs() (acls []model.PathACL, err error) {
+	if err = db.Find(&acls).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find path acls")
+	}
+	return acls, nil
+}
+
+func CreatePathACL(a *model.PathACL) error {
+	return errors.WithStack(db.Create(a).Error)
+}
+
+func UpdatePathACL(a *model.PathACL) error {
+	return errors.WithStack(db.Save(a).Error)
+}
+
+func DeletePathACLById(id uint) error {
+	return errors.WithStack(db.Delete(&model.PathACL{}, id).Error)
+}
+
+func DeletePathACLsBySubject(subjectType string, subjectID uint) error {
+	return errors.WithStack(db.Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).Delete(&model.PathACL{}).Error)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetApiTokensByUserId(userId uint, pageIndex, pageSize int) (tokens []model.ApiToken, count int64, err error) {
+	tokenDB := db.Model(&model.ApiToken{}).Where("user_id = ?", userId)
+	if err := tokenDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get user's tokens count")
+	}
+	if err := tokenDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&tokens).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get find user's tokens")
+	}
+	return tokens, count, nil
+}
+
+func GetApiTokenById(id uint) (*model.ApiToken, error) {
+	var t model.ApiToken
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get token")
+	}
+	return &t, nil
+}
+
+func GetApiTokenByHash(hash string) (*model.ApiToken, error) {
+	t := model.ApiToken{TokenHash: hash}
+	if err := db.Where(t).First(&t).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find token")
+	}
+	return &t, nil
+}
+
+func CreateApiToken(t *model.ApiToken) error {
+	return errors.WithStack(db.Create(t).Error)
+}
+
+func UpdateApiToken(t *model.ApiToken) error {
+	return errors.WithStack(db.Save(t).Error)
+}
+
+func UpdateApiTokenLastUsed(id uint, lastUsed time.Time) error {
+	return errors.WithStack(db.Model(&model.ApiToken{}).Where("id = ?", id).Update("last_used_at", lastUsed).Error)
+}
+
+func DeleteApiTokensByUserId(userId uint) error {
+	return errors.WithStack(db.Where("user_id = ?", userId).Delete(&model.ApiToken{}).Error)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func CreateAuditEvent(e *model.AuditEvent) error {
+	return errors.WithStack(db.Create(e).Error)
+}
+
+func auditFilter(f model.AuditFilter) *gorm.DB {
+	tx := db.Model(&model.AuditEvent{})
+	if f.Username != "" {
+		tx = tx.Where("username = ?", f.Username)
+	}
+	if f.Action != "" {
+		tx = tx.Where("action = ? OR action LIKE ?", f.Action, f.Action+".%")
+	}
+	if f.Protocol != "" {
+		tx = tx.Where("protocol = ?", f.Protocol)
+	}
+	if f.Target != "" {
+		tx = tx.Where("target LIKE ?", f.Target+"%")
+	}
+	if f.Success != nil {
+		tx = tx.Where("success = ?", *f.Success)
+	}
+	if f.Since != nil {
+		tx = tx.Where("created_at >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		tx = tx.Where("created_at < ?", *f.Until)
+	}
+	return tx
+}
+
+func GetAuditEvents(f model.AuditFilter, pageIndex, pageSize int) (events []model.AuditEvent, count int64, err error) {
+	tx := auditFilter(f)
+	if err := tx.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get audit events count")
+	}
+	if err := tx.Order("id DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find audit events")
+	}
+	return events, count, nil
+}
+
+// WalkAuditEvents calls fn with the matched events in batches, oldest first
+func WalkAuditEvents(f model.AuditFilter, batchSize int, fn func([]model.AuditEvent) error) error {
+	var events []model.AuditEvent
+	err := auditFilter(f).Order("id").FindInBatches(&events, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(events)
+	}).Error
+	return errors.WithStack(err)
+}
+
+func DeleteAuditEventsBefore(t time.Time) (int64, error) {
+	res := db.Where("created_at < ?", t).Delete(&model.AuditEvent{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
@@ -6,12 +6,18 @@ import (
 	
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 // --- Certificate Functions ---
 
-func GetCertificates(pageIndex, pageSize int) (certs []model.Certificate, count int64, err error) {
+// GetCertificates 分页获取证书，expiringWithinDays > 0 时只返回该天数内到期的有效证书
+func GetCertificates(pageIndex, pageSize, expiringWithinDays int) (certs []model.Certificate, count int64, err error) {
 	certDB := db.Model(&model.Certificate{})
+	if expiringWithinDays > 0 {
+		certDB = certDB.Where("(status = ? OR status = ?) AND expiration_date <= ?",
+			model.CertificateStatusValid, model.CertificateStatusExpiring, time.Now().AddDate(0, 0, expiringWithinDays))
+	}
 	if err := certDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get certificates count")
 	}
@@ -41,18 +47,81 @@ func GetCertificateByOwnerID(ownerID uint) (*model.Certificate, error) {
 	return &cert, nil
 }
 
-func CreateCertificate(cert *model.Certificate) error {
-	return errors.WithStack(db.Create(cert).Error)
+// GetLatestCertificateByOwnerID 获取所有者最近签发的证书，包括已过期的证书，用于续期
+func GetLatestCertificateByOwnerID(ownerID uint) (*model.Certificate, error) {
+	var cert model.Certificate
+	if err := db.Where("owner_id = ?", ownerID).Order(fmt.Sprintf("%s DESC", columnName("id"))).First(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// MarkCertificatesExpiring 将在 before 之前到期的有效证书标记为即将过期
+func MarkCertificatesExpiring(before time.Time) (int64, error) {
+	return markCertificates(model.CertificateStatusExpiring, model.CertificateActionExpiring,
+		"status = ? AND expiration_date <= ?", model.CertificateStatusValid, before)
+}
+
+// MarkCertificatesExpired 将已经到期的有效或即将过期证书标记为已过期
+func MarkCertificatesExpired(now time.Time) (int64, error) {
+	return markCertificates(model.CertificateStatusExpired, model.CertificateActionExpire,
+		"(status = ? OR status = ?) AND expiration_date <= ?", model.CertificateStatusValid, model.CertificateStatusExpiring, now)
+}
+
+// markCertificates 在一个事务中变更所有符合条件的证书的状态，并逐个记录由后台任务引起的事件
+func markCertificates(status model.CertificateStatus, action model.CertificateAction, query string, args ...any) (int64, error) {
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var certs []model.Certificate
+		if err := tx.Where(query, args...).Find(&certs).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		for i := range certs {
+			event := model.CertificateEvent{Action: action, Actor: model.CertificateSystemActor}
+			if err := changeCertificateStatus(tx, &certs[i], status, event); err != nil {
+				return err
+			}
+		}
+		count = int64(len(certs))
+		return nil
+	})
+	return count, err
+}
+
+// GetCertificateBySerial 根据序列号获取证书，包含已删除的证书
+func GetCertificateBySerial(serial string) (*model.Certificate, error) {
+	var cert model.Certificate
+	if err := db.Unscoped().Where("serial_number = ?", serial).First(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// GetRevokedCertificates 获取需要写入CRL的证书：已吊销、已被取代或已删除且由CA签发的证书
+func GetRevokedCertificates() ([]model.Certificate, error) {
+	var certs []model.Certificate
+	if err := db.Unscoped().Where("serial_number <> '' AND (status = ? OR status = ? OR deleted_at IS NOT NULL)",
+		model.CertificateStatusRevoked, model.CertificateStatusSuperseded).
+		Find(&certs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get revoked certificates")
+	}
+	return certs, nil
 }
 
 func UpdateCertificate(cert *model.Certificate) error {
 	return errors.WithStack(db.Save(cert).Error)
 }
 
-func DeleteCertificate(id uint) error {
-	return errors.WithStack(db.Delete(&model.Certificate{}, id).Error)
+// ClearCertificatePrivateKey 仅清除证书的私钥列，返回私钥是否由本次调用清除
+func ClearCertificatePrivateKey(id uint) (bool, error) {
+	res := db.Model(&model.Certificate{}).Where("id = ? AND private_key <> ''", id).Update("private_key", "")
+	if res.Error != nil {
+		return false, errors.Wrapf(res.Error, "failed clear certificate private key")
+	}
+	return res.RowsAffected > 0, nil
 }
 
+
 // --- CertificateRequest Functions ---
 
 func GetCertificateRequests(pageIndex, pageSize int) (reqs []model.CertificateRequest, count int64, err error) {
@@ -91,11 +160,3 @@ func GetPendingCertificateRequestByUserID(userID uint) (*model.CertificateReques
 	}
 	return &request, nil
 }
-
-func CreateCertificateRequest(req *model.CertificateRequest) error {
-	return errors.WithStack(db.Create(req).Error)
-}
-
-func UpdateCertificateRequest(req *model.CertificateRequest) error {
-	return errors.WithStack(db.Save(req).Error)
-}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 证书状态变更与其事件记录在同一个事务中写入，任一步失败都会整体回滚
+
+// GetCertificateHistory 获取证书的全部事件，包括签发该证书的申请的事件，按发生顺序排列
+func GetCertificateHistory(cert *model.Certificate) ([]model.CertificateEvent, error) {
+	var events []model.CertificateEvent
+	query := db.Where("certificate_id = ?", cert.ID)
+	if cert.RequestID != 0 {
+		query = query.Or("request_id = ?", cert.RequestID)
+	}
+	if err := query.Order("id ASC").Find(&events).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get history of certificate: %d", cert.ID)
+	}
+	return events, nil
+}
+
+// CreateCertificate 保存证书并记录事件
+func CreateCertificate(cert *model.Certificate, event model.CertificateEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(cert).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		event.CertificateID = cert.ID
+		event.NewStatus = cert.Status
+		return errors.WithStack(tx.Create(&event).Error)
+	})
+}
+
+// CreateCertificateRequest 保存证书申请并记录事件
+func CreateCertificateRequest(req *model.CertificateRequest, event model.CertificateEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(req).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		event.RequestID = req.ID
+		event.NewStatus = req.Status
+		return errors.WithStack(tx.Create(&event).Error)
+	})
+}
+
+// ApproveCertificateRequest 在一个事务中批准申请、保存签发的证书，续期时同时将旧证书标记为已取代
+func ApproveCertificateRequest(req *model.CertificateRequest, cert, predecessor *model.Certificate, event model.CertificateEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := closeCertificateRequest(tx, req); err != nil {
+			return err
+		}
+		if err := tx.Create(cert).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		approved := event
+		approved.CertificateID = cert.ID
+		approved.RequestID = req.ID
+		approved.OldStatus = model.CertificateStatusPending
+		approved.NewStatus = req.Status
+		if err := tx.Create(&approved).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		if predecessor == nil {
+			return nil
+		}
+		superseded := event
+		superseded.Action = model.CertificateActionSupersede
+		superseded.RequestID = req.ID
+		return changeCertificateStatus(tx, predecessor, model.CertificateStatusSuperseded, superseded)
+	})
+}
+
+// RejectCertificateRequest 在一个事务中拒绝申请并记录事件
+func RejectCertificateRequest(req *model.CertificateRequest, event model.CertificateEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := closeCertificateRequest(tx, req); err != nil {
+			return err
+		}
+		event.RequestID = req.ID
+		event.OldStatus = model.CertificateStatusPending
+		event.NewStatus = req.Status
+		return errors.WithStack(tx.Create(&event).Error)
+	})
+}
+
+// ChangeCertificateStatus 在一个事务中变更证书状态并记录事件
+func ChangeCertificateStatus(cert *model.Certificate, status model.CertificateStatus, event model.CertificateEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return changeCertificateStatus(tx, cert, status, event)
+	})
+}
+
+// DeleteCertificate 在一个事务中删除证书并记录事件
+func DeleteCertificate(id uint, event model.CertificateEvent) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var cert model.Certificate
+		if err := tx.First(&cert, id).Error; err != nil {
+			return errors.Wrapf(err, "failed get certificate by id: %d", id)
+		}
+		if err := tx.Delete(&cert).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		event.CertificateID = cert.ID
+		event.OldStatus = cert.Status
+		event.NewStatus = cert.Status
+		return errors.WithStack(tx.Create(&event).Error)
+	})
+}
+
+// UpdateCertificateDetails 在一个事务中修改证书名称和过期日期并记录事件，修改前后的值记录在事件理由中
+func UpdateCertificateDetails(id uint, name string, expirationDate time.Time, event model.CertificateEvent) (*model.Certificate, error) {
+	var cert model.Certificate
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&cert, id).Error; err != nil {
+			return errors.Wrapf(err, "failed get certificate by id: %d", id)
+		}
+		event.Reason = fmt.Sprintf("name: %s -> %s, expiration date: %s -> %s", cert.Name, name,
+			cert.ExpirationDate.Format(time.DateOnly), expirationDate.Format(time.DateOnly))
+		cert.Name = name
+		cert.ExpirationDate = expirationDate
+		if err := tx.Save(&cert).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		event.CertificateID = cert.ID
+		event.OldStatus = cert.Status
+		event.NewStatus = cert.Status
+		return errors.WithStack(tx.Create(&event).Error)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// closeCertificateRequest 保存审批结果，只有仍处于待审批状态的申请才会被更新，防止并发重复审批
+func closeCertificateRequest(tx *gorm.DB, req *model.CertificateRequest) error {
+	res := tx.Model(req).Where("status = ?", model.CertificateStatusPending).Select("*").Updates(req)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errs.CertificateRequestNotPending
+	}
+	return nil
+}
+
+// changeCertificateStatus 将证书从当前状态变更为 status 并记录事件
+// 证书状态已被其他操作修改时返回错误
+func changeCertificateStatus(tx *gorm.DB, cert *model.Certificate, status model.CertificateStatus, event model.CertificateEvent) error {
+	res := tx.Model(&model.Certificate{}).Where("id = ? AND status = ?", cert.ID, cert.Status).
+		Updates(map[string]any{"status": status, "revoked_at": cert.RevokedAt})
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return errors.Errorf("certificate %d is no longer %s", cert.ID, cert.Status)
+	}
+	event.CertificateID = cert.ID
+	event.OldStatus = cert.Status
+	event.NewStatus = status
+	cert.Status = status
+	return errors.WithStack(tx.Create(&event).Error)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func SaveClusterNode(n *model.ClusterNode) error {
+	return errors.WithStack(db.Save(n).Error)
+}
+
+func GetClusterNodes() (nodes []model.ClusterNode, err error) {
+	if err := db.Order("name").Find(&nodes).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find cluster nodes")
+	}
+	return nodes, nil
+}
+
+func DeleteClusterNode(name string) error {
+	return errors.WithStack(db.Where("name = ?", name).Delete(&model.ClusterNode{}).Error)
+}
+
+func CreateClusterEvent(e *model.ClusterEvent) error {
+	return errors.WithStack(db.Create(e).Error)
+}
+
+// GetClusterEventsSince returns the events of the other nodes created since t, oldest first
+func GetClusterEventsSince(t time.Time, node string) (events []model.ClusterEvent, err error) {
+	if err := db.Where("created_at >= ? AND node <> ?", t, node).Order("id").Find(&events).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find cluster events")
+	}
+	return events, nil
+}
+
+func DeleteClusterEventsBefore(t time.Time) error {
+	return errors.WithStack(db.Where("created_at < ?", t).Delete(&model.ClusterEvent{}).Error)
+}
+
+// AcquireClusterLease takes the lease if it is free or expired, or extends it if the holder has it already
+func AcquireClusterLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res := db.Model(&model.ClusterLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]any{"holder": holder, "expires_at": now.Add(ttl)})
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	// either another node holds it or it was never taken, in which case only one insert succeeds
+	err := db.Create(&model.ClusterLease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}).Error
+	return err == nil, nil
+}
+
+func GetClusterLease(name string) (*model.ClusterLease, error) {
+	var l model.ClusterLease
+	if err := db.Where("name = ?", name).First(&l).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get cluster lease")
+	}
+	return &l, nil
+}
+
+func ReleaseClusterLease(name, holder string) error {
+	return errors.WithStack(db.Where("name = ? AND holder = ?", name, holder).Delete(&model.ClusterLease{}).Error)
+}
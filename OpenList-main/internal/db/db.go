@@ -23,6 +23,22 @@ func Init(d *gorm.DB) {
 		new(model.SharingDB),
 		new(model.Certificate),
 		new(model.CertificateRequest),
+		new(model.CertificateEvent),
+		new(model.Tenant),
+		new(model.QuotaUsage),
+		new(model.ApiToken),
+		new(model.Group),
+		new(model.GroupMeta),
+		new(model.UserGroup),
+		new(model.PathACL),
+		new(model.OIDCClient),
+		new(model.OIDCAuthCode),
+		new(model.OIDCSigningKey),
+		new(model.Session),
+		new(model.AuditEvent),
+		new(model.ClusterNode),
+		new(model.ClusterEvent),
+		new(model.ClusterLease),
 	)
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func GetGroupById(id uint) (*model.Group, error) {
+	var g model.Group
+	if err := db.Preload("Metas").First(&g, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get group")
+	}
+	return &g, nil
+}
+
+func GetGroups(pageIndex, pageSize int) (groups []model.Group, count int64, err error) {
+	groupDB := db.Model(&model.Group{})
+	if err := groupDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get groups count")
+	}
+	if err := groupDB.Preload("Metas").Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&groups).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get find groups")
+	}
+	return groups, count, nil
+}
+
+// GetExternalGroups returns the groups whose membership is mapped from LDAP or SSO
+func GetExternalGroups() (groups []model.Group, err error) {
+	if err := db.Where("external_names <> ''").Find(&groups).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get external groups")
+	}
+	return groups, nil
+}
+
+// GetGroupsByUserId returns the groups of the user with their meta overrides, ordered by id
+func GetGroupsByUserId(userId uint) (groups []model.Group, err error) {
+	err = db.Preload("Metas").
+		Where("id IN (?)", db.Model(&model.UserGroup{}).Select("group_id").Where("user_id = ?", userId)).
+		Order(columnName("id")).Find(&groups).Error
+	return groups, errors.Wrapf(err, "failed get user's groups")
+}
+
+func GetGroupMembers(groupId uint, pageIndex, pageSize int) (users []model.User, count int64, err error) {
+	userDB := db.Model(&model.User{}).Where("id IN (?)", db.Model(&model.UserGroup{}).Select("user_id").Where("group_id = ?", groupId))
+	if err := userDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get group members count")
+	}
+	if err := userDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get find group members")
+	}
+	return users, count, nil
+}
+
+func CreateGroup(g *model.Group) error {
+	return errors.WithStack(db.Create(g).Error)
+}
+
+// UpdateGroup saves the group and replaces its meta overrides
+func UpdateGroup(g *model.Group) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Metas").Save(g).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", g.ID).Delete(&model.GroupMeta{}).Error; err != nil {
+			return err
+		}
+		for i := range g.Metas {
+			g.Metas[i].ID = 0
+			g.Metas[i].GroupID = g.ID
+		}
+		if len(g.Metas) == 0 {
+			return nil
+		}
+		return tx.Create(&g.Metas).Error
+	}))
+}
+
+func DeleteGroupById(id uint) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&model.UserGroup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&model.GroupMeta{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Group{}, id).Error
+	}))
+}
+
+func DeleteUserGroupsByUserId(userId uint) error {
+	return errors.WithStack(db.Where("user_id = ?", userId).Delete(&model.UserGroup{}).Error)
+}
+
+func AddUserToGroup(userId, groupId uint) error {
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserGroup{UserID: userId, GroupID: groupId}).Error
+	return errors.WithStack(err)
+}
+
+func RemoveUserFromGroup(userId, groupId uint) error {
+	return errors.WithStack(db.Where("user_id = ? AND group_id = ?", userId, groupId).Delete(&model.UserGroup{}).Error)
+}
+
+// SetUserGroups replaces the groups of the user
+func SetUserGroups(userId uint, groupIds []uint) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&model.UserGroup{}).Error; err != nil {
+			return err
+		}
+		for _, groupId := range groupIds {
+			if err := tx.Create(&model.UserGroup{UserID: userId, GroupID: groupId}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
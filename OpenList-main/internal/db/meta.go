@@ -29,8 +29,8 @@ func UpdateMeta(u *model.Meta) error {
 	return errors.WithStack(db.Save(u).Error)
 }
 
-func GetMetas(pageIndex, pageSize int) (metas []model.Meta, count int64, err error) {
-	metaDB := db.Model(&model.Meta{})
+func GetMetas(pageIndex, pageSize int, tenantID *uint) (metas []model.Meta, count int64, err error) {
+	metaDB := db.Model(&model.Meta{}).Scopes(withTenant(tenantID))
 	if err = metaDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get metas count")
 	}
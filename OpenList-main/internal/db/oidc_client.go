@@ -0,0 +1,86 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetOIDCClientById(id uint) (*model.OIDCClient, error) {
+	var c model.OIDCClient
+	if err := db.First(&c, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get oidc client")
+	}
+	return &c, nil
+}
+
+func GetOIDCClientByClientID(clientID string) (*model.OIDCClient, error) {
+	c := model.OIDCClient{ClientID: clientID}
+	if err := db.Where(c).First(&c).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get oidc client")
+	}
+	return &c, nil
+}
+
+func GetOIDCClients(pageIndex, pageSize int) (clients []model.OIDCClient, count int64, err error) {
+	clientDB := db.Model(&model.OIDCClient{})
+	if err = clientDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get oidc clients count")
+	}
+	if err = clientDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&clients).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find oidc clients")
+	}
+	return clients, count, nil
+}
+
+func CreateOIDCClient(c *model.OIDCClient) error {
+	return errors.WithStack(db.Create(c).Error)
+}
+
+func UpdateOIDCClient(c *model.OIDCClient) error {
+	return errors.WithStack(db.Save(c).Error)
+}
+
+func DeleteOIDCClientById(id uint) error {
+	return errors.WithStack(db.Delete(&model.OIDCClient{}, id).Error)
+}
+
+func CreateOIDCAuthCode(c *model.OIDCAuthCode) error {
+	return errors.WithStack(db.Create(c).Error)
+}
+
+// TakeOIDCAuthCode deletes an unexpired code and returns it, a code can only be taken once even by racing nodes
+func TakeOIDCAuthCode(codeHash string) (*model.OIDCAuthCode, error) {
+	var c model.OIDCAuthCode
+	if err := db.Where("code_hash = ? AND expires_at > ?", codeHash, time.Now()).First(&c).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get oidc auth code")
+	}
+	res := db.Where("code_hash = ?", codeHash).Delete(&model.OIDCAuthCode{})
+	if res.Error != nil {
+		return nil, errors.WithStack(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.Wrapf(gorm.ErrRecordNotFound, "oidc auth code is taken already")
+	}
+	return &c, nil
+}
+
+func DeleteExpiredOIDCAuthCodes() error {
+	return errors.WithStack(db.Where("expires_at <= ?", time.Now()).Delete(&model.OIDCAuthCode{}).Error)
+}
+
+func GetOIDCSigningKey() (*model.OIDCSigningKey, error) {
+	var k model.OIDCSigningKey
+	if err := db.Order(columnName("id")).First(&k).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get oidc signing key")
+	}
+	return &k, nil
+}
+
+// CreateOIDCSigningKey stores the first signing key, only one node succeeds when several start at once
+func CreateOIDCSigningKey(k *model.OIDCSigningKey) error {
+	k.ID = 1
+	return errors.WithStack(db.Create(k).Error)
+}
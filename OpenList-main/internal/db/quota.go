@@ -0,0 +1,75 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetQuotaUsage returns the usage of the owner, a zero usage is returned if nothing has been charged yet
+func GetQuotaUsage(scope string, ownerID uint) (*model.QuotaUsage, error) {
+	usage := model.QuotaUsage{Scope: scope, OwnerID: ownerID}
+	if err := db.Where(&usage).FirstOrInit(&usage).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get quota usage")
+	}
+	return &usage, nil
+}
+
+// ReserveQuota charges bytes and objects to all the quotas in one transaction,
+// nothing is charged if any of the limits would be exceeded
+func ReserveQuota(quotas []model.Quota, bytes, objects int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, q := range quotas {
+			if err := ensureQuotaUsage(tx, q.Scope, q.OwnerID); err != nil {
+				return err
+			}
+			query := tx.Model(&model.QuotaUsage{}).Where("scope = ? AND owner_id = ?", q.Scope, q.OwnerID)
+			if bytes > 0 && q.LimitBytes > 0 {
+				query = query.Where("used_bytes + ? <= ?", bytes, q.LimitBytes)
+			}
+			if objects > 0 && q.LimitObjects > 0 {
+				query = query.Where("used_objects + ? <= ?", objects, q.LimitObjects)
+			}
+			res := query.Updates(map[string]any{
+				"used_bytes":   gorm.Expr("used_bytes + ?", bytes),
+				"used_objects": gorm.Expr("used_objects + ?", objects),
+			})
+			if res.Error != nil {
+				return errors.Wrapf(res.Error, "failed reserve quota")
+			}
+			if res.RowsAffected == 0 {
+				return errs.NewErr(errs.QuotaExceeded, "%s %d", q.Scope, q.OwnerID)
+			}
+		}
+		return nil
+	})
+}
+
+// ReleaseQuota gives bytes and objects back to all the quotas, the usage never drops below zero
+func ReleaseQuota(quotas []model.Quota, bytes, objects int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, q := range quotas {
+			err := tx.Model(&model.QuotaUsage{}).Where("scope = ? AND owner_id = ?", q.Scope, q.OwnerID).
+				Updates(map[string]any{
+					"used_bytes":   gorm.Expr("CASE WHEN used_bytes < ? THEN 0 ELSE used_bytes - ? END", bytes, bytes),
+					"used_objects": gorm.Expr("CASE WHEN used_objects < ? THEN 0 ELSE used_objects - ? END", objects, objects),
+				}).Error
+			if err != nil {
+				return errors.Wrapf(err, "failed release quota")
+			}
+		}
+		return nil
+	})
+}
+
+func DeleteQuotaUsage(scope string, ownerID uint) error {
+	return errors.WithStack(db.Where("scope = ? AND owner_id = ?", scope, ownerID).Delete(&model.QuotaUsage{}).Error)
+}
+
+func ensureQuotaUsage(tx *gorm.DB, scope string, ownerID uint) error {
+	usage := model.QuotaUsage{Scope: scope, OwnerID: ownerID}
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error
+	return errors.Wrapf(err, "failed create quota usage")
+}
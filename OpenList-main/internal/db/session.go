@@ -0,0 +1,44 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetSessionsByUserId(userId uint) (sessions []model.Session, err error) {
+	if err := db.Where("user_id = ? AND expires_at > ?", userId, time.Now()).
+		Order("last_seen DESC").Find(&sessions).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find user's sessions")
+	}
+	return sessions, nil
+}
+
+func GetSessionById(id string) (*model.Session, error) {
+	var s model.Session
+	if err := db.Where("id = ?", id).First(&s).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get session")
+	}
+	return &s, nil
+}
+
+func CreateSession(s *model.Session) error {
+	return errors.WithStack(db.Create(s).Error)
+}
+
+func UpdateSessionLastSeen(id string, lastSeen time.Time) error {
+	return errors.WithStack(db.Model(&model.Session{}).Where("id = ?", id).Update("last_seen", lastSeen).Error)
+}
+
+func DeleteSessionById(id string) error {
+	return errors.WithStack(db.Where("id = ?", id).Delete(&model.Session{}).Error)
+}
+
+func DeleteSessionsByUserId(userId uint) error {
+	return errors.WithStack(db.Where("user_id = ?", userId).Delete(&model.Session{}).Error)
+}
+
+func DeleteExpiredSessions(userId uint) error {
+	return errors.WithStack(db.Where("user_id = ? AND expires_at <= ?", userId, time.Now()).Delete(&model.Session{}).Error)
+}
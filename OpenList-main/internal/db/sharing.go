@@ -14,8 +14,8 @@ func GetSharingById(id string) (*model.SharingDB, error) {
 	return &s, nil
 }
 
-func GetSharings(pageIndex, pageSize int) (sharings []model.SharingDB, count int64, err error) {
-	sharingDB := db.Model(&model.SharingDB{})
+func GetSharings(pageIndex, pageSize int, tenantID *uint) (sharings []model.SharingDB, count int64, err error) {
+	sharingDB := db.Model(&model.SharingDB{}).Scopes(withTenant(tenantID))
 	if err := sharingDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get sharings count")
 	}
@@ -27,9 +27,9 @@ func DeleteStorageById(id uint) error {
 	return errors.WithStack(db.Delete(&model.Storage{}, id).Error)
 }
 
-// GetStorages Get all storages from database order by index
-func GetStorages(pageIndex, pageSize int) ([]model.Storage, int64, error) {
-	storageDB := db.Model(&model.Storage{})
+// GetStorages Get all storages from database order by index, optionally only of a tenant
+func GetStorages(pageIndex, pageSize int, tenantID *uint) ([]model.Storage, int64, error) {
+	storageDB := db.Model(&model.Storage{}).Scopes(withTenant(tenantID))
 	var count int64
 	if err := storageDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get storages count")
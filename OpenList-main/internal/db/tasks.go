@@ -46,3 +46,7 @@ func UpdateTaskDataFunc(type_s string, enabled bool) func([]byte) error {
 		return UpdateTaskData(&model.TaskItem{Key: type_s, PersistData: s})
 	}
 }
+
+func DeleteTaskData(key string) error {
+	return errors.WithStack(db.Where("key = ?", key).Delete(&model.TaskItem{}).Error)
+}
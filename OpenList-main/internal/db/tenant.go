@@ -0,0 +1,69 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetTenantById(id uint) (*model.Tenant, error) {
+	var t model.Tenant
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get tenant")
+	}
+	return &t, nil
+}
+
+func GetTenantByName(name string) (*model.Tenant, error) {
+	t := model.Tenant{Name: name}
+	if err := db.Where(t).First(&t).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get tenant")
+	}
+	return &t, nil
+}
+
+func GetTenants(pageIndex, pageSize int) (tenants []model.Tenant, count int64, err error) {
+	tenantDB := db.Model(&model.Tenant{})
+	if err := tenantDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get tenants count")
+	}
+	if err := tenantDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&tenants).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get find tenants")
+	}
+	return tenants, count, nil
+}
+
+func CreateTenant(t *model.Tenant) error {
+	return errors.WithStack(db.Create(t).Error)
+}
+
+func UpdateTenant(t *model.Tenant) error {
+	return errors.WithStack(db.Save(t).Error)
+}
+
+func DeleteTenantById(id uint) error {
+	return errors.WithStack(db.Delete(&model.Tenant{}, id).Error)
+}
+
+// CountTenantResources count users, storages, metas and sharings in the namespace of the tenant
+func CountTenantResources(id uint) (int64, error) {
+	var total int64
+	for _, m := range []any{&model.User{}, &model.Storage{}, &model.Meta{}, &model.SharingDB{}} {
+		var count int64
+		if err := db.Model(m).Where("tenant_id = ?", id).Count(&count).Error; err != nil {
+			return 0, errors.Wrapf(err, "failed count tenant resources")
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// withTenant filters records by tenant, all records are returned if tenantID is nil
+func withTenant(tenantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return tx
+		}
+		return tx.Where("tenant_id = ?", *tenantID)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"encoding/base64"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -49,8 +50,8 @@ func UpdateUser(u *model.User) error {
 	return errors.WithStack(db.Save(u).Error)
 }
 
-func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err error) {
-	userDB := db.Model(&model.User{})
+func GetUsers(pageIndex, pageSize int, tenantID *uint) (users []model.User, count int64, err error) {
+	userDB := db.Model(&model.User{}).Scopes(withTenant(tenantID))
 	if err := userDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get users count")
 	}
@@ -100,3 +101,20 @@ func RemoveAuthn(u *model.User, id string) error {
 	}
 	return UpdateAuthn(u.ID, string(res))
 }
+
+// GetLdapUsers returns the users signing in with LDAP
+func GetLdapUsers() (users []model.User, err error) {
+	if err := db.Where("ldap_dn <> ?", "").Order(columnName("id")).Find(&users).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get ldap users")
+	}
+	return users, nil
+}
+
+func UpdateUserLockout(id uint, failedLogins int, lockedAt *time.Time) error {
+	return errors.WithStack(db.Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]any{"failed_logins": failedLogins, "locked_at": lockedAt}).Error)
+}
+
+func UpdateUserRecoveryCodes(id uint, recoveryCodes string) error {
+	return errors.WithStack(db.Model(&model.User{}).Where("id = ?", id).Update("recovery_codes", recoveryCodes).Error)
+}
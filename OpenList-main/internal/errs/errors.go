@@ -27,11 +27,31 @@ var (
 	WrongShareCode  = errors.New("wrong share code")
 	InvalidSharing  = errors.New("invalid sharing")
 	SharingNotFound = errors.New("sharing not found")
-	
+
+	// Tenant errors
+	TenantNotFound  = errors.New("tenant not found")
+	TenantDisabled  = errors.New("tenant is disabled")
+	TenantNotEmpty  = errors.New("tenant still has users, storages, metas or sharings")
+	PathNotInTenant = errors.New("path is outside of the tenant namespace")
+
+	QuotaExceeded = errors.New("storage quota exceeded")
+
 	// Certificate errors
-	CertificateAlreadyExists   = errors.New("certificate already exists")
-	CertificateRequestPending  = errors.New("certificate request is pending")
+	CertificateAlreadyExists     = errors.New("certificate already exists")
+	CertificateRequestPending    = errors.New("certificate request is pending")
 	CertificateRequestNotPending = errors.New("certificate request is not pending")
+	CertificateCSRRequired       = errors.New("a certificate signing request is required for node certificates")
+	CertificateNotRenewable      = errors.New("certificate can not be renewed")
+	CertificateAlreadyRevoked    = errors.New("certificate has already been revoked")
+	InvalidCSR                   = errors.New("invalid certificate signing request")
+	CertificateProofRequired     = errors.New("a signed certificate login challenge is required")
+	InvalidCertificateProof      = errors.New("invalid certificate login challenge signature")
+
+	// OIDC provider errors, named after the error codes of RFC 6749
+	OIDCInvalidRequest = errors.New("invalid_request")
+	OIDCInvalidClient  = errors.New("invalid_client")
+	OIDCInvalidGrant   = errors.New("invalid_grant")
+	OIDCInvalidToken   = errors.New("invalid_token")
 )
 
 // NewErr wrap constant error with an extra message
@@ -49,4 +69,4 @@ func IsNotSupportError(err error) bool {
 }
 func IsNotImplement(err error) bool {
 	return errors.Is(pkgerr.Cause(err), NotImplement)
-}
\ No newline at end of file
+}
@@ -7,4 +7,15 @@ var (
 	EmptyPassword      = errors.New("password is empty")
 	WrongPassword      = errors.New("password is incorrect")
 	DeleteAdminOrGuest = errors.New("cannot delete admin or guest")
+	UserNotInTenant    = errors.New("user does not belong to the tenant")
+	ExceedPermission   = errors.New("permission exceeds the granted permission")
+	InvalidApiToken    = errors.New("api token is invalid, expired or revoked")
+	ApiTokenNotFound   = errors.New("api token not found")
+	SessionRevoked     = errors.New("session has expired or been revoked, login please")
+	WeakPassword       = errors.New("password does not meet the password policy")
+	PasswordReused     = errors.New("password has been used recently")
+	PasswordExpired    = errors.New("password has expired, change it please")
+	AccountLocked      = errors.New("account is locked after too many failed sign-in attempts")
+	TwoFactorRequired  = errors.New("2FA is required for this account, enroll TOTP or WebAuthn please")
+	TwoFactorDisabled  = errors.New("2FA is not enabled")
 )
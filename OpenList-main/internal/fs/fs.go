@@ -3,9 +3,11 @@ package fs
 import (
 	"context"
 	"io"
+	stdpath "path"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -17,6 +19,8 @@ import (
 // the param named path of functions in this package is a mount path
 // So, the purpose of this package is to convert mount path to actual path
 // then pass the actual path to the op package
+// the path ACLs of the user in ctx are checked here, so that no frontend can bypass them,
+// and the mutations are recorded in the audit log here for the same reason
 
 type ListArgs struct {
 	Refresh bool
@@ -24,6 +28,9 @@ type ListArgs struct {
 }
 
 func List(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathList); err != nil {
+		return nil, err
+	}
 	res, err := list(ctx, path, args)
 	if err != nil {
 		if !args.NoLog {
@@ -39,6 +46,9 @@ type GetArgs struct {
 }
 
 func Get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathRead); err != nil {
+		return nil, err
+	}
 	res, err := get(ctx, path)
 	if err != nil {
 		if !args.NoLog {
@@ -50,6 +60,9 @@ func Get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
 }
 
 func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathRead); err != nil {
+		return nil, nil, err
+	}
 	res, file, err := link(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed link %s: %+v", path, err)
@@ -58,56 +71,90 @@ func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, m
 	return res, file, nil
 }
 
-func MakeDir(ctx context.Context, path string, lazyCache ...bool) error {
-	err := makeDir(ctx, path, lazyCache...)
+func MakeDir(ctx context.Context, path string, lazyCache ...bool) (err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditMkdir, path, "", err) }()
+	if err = op.CheckPathACL(ctx, path, model.PathWrite); err != nil {
+		return err
+	}
+	err = makeDir(ctx, path, lazyCache...)
 	if err != nil {
 		log.Errorf("failed make dir %s: %+v", path, err)
 	}
 	return err
 }
 
-func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
-	req, err := transfer(ctx, move, srcPath, dstDirPath, lazyCache...)
+func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) (req task.TaskExtensionInfo, err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditMove, srcPath, dstDirPath, err) }()
+	if err = op.CheckPathACL(ctx, srcPath, model.PathDelete); err != nil {
+		return nil, err
+	}
+	if err = op.CheckPathACL(ctx, dstDirPath, model.PathWrite); err != nil {
+		return nil, err
+	}
+	req, err = transfer(ctx, move, srcPath, dstDirPath, lazyCache...)
 	if err != nil {
 		log.Errorf("failed move %s to %s: %+v", srcPath, dstDirPath, err)
 	}
 	return req, err
 }
 
-func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
-	res, err := transfer(ctx, copy, srcObjPath, dstDirPath, lazyCache...)
+func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (res task.TaskExtensionInfo, err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditCopy, srcObjPath, dstDirPath, err) }()
+	if err = op.CheckPathACL(ctx, srcObjPath, model.PathRead); err != nil {
+		return nil, err
+	}
+	if err = op.CheckPathACL(ctx, dstDirPath, model.PathWrite); err != nil {
+		return nil, err
+	}
+	res, err = transfer(ctx, copy, srcObjPath, dstDirPath, lazyCache...)
 	if err != nil {
 		log.Errorf("failed copy %s to %s: %+v", srcObjPath, dstDirPath, err)
 	}
 	return res, err
 }
 
-func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) error {
-	err := rename(ctx, srcPath, dstName, lazyCache...)
+func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) (err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditRename, srcPath, dstName, err) }()
+	if err = op.CheckPathACL(ctx, srcPath, model.PathWrite); err != nil {
+		return err
+	}
+	err = rename(ctx, srcPath, dstName, lazyCache...)
 	if err != nil {
 		log.Errorf("failed rename %s to %s: %+v", srcPath, dstName, err)
 	}
 	return err
 }
 
-func Remove(ctx context.Context, path string) error {
-	err := remove(ctx, path)
+func Remove(ctx context.Context, path string) (err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditRemove, path, "", err) }()
+	if err = op.CheckPathACL(ctx, path, model.PathDelete); err != nil {
+		return err
+	}
+	err = remove(ctx, path)
 	if err != nil {
 		log.Errorf("failed remove %s: %+v", path, err)
 	}
 	return err
 }
 
-func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
-	err := putDirectly(ctx, dstDirPath, file, lazyCache...)
+func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) (err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditUpload, stdpath.Join(dstDirPath, file.GetName()), "", err) }()
+	if err = op.CheckPathACL(ctx, dstDirPath, model.PathWrite); err != nil {
+		return err
+	}
+	err = putDirectly(ctx, dstDirPath, file, lazyCache...)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
 	}
 	return err
 }
 
-func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) (task.TaskExtensionInfo, error) {
-	t, err := putAsTask(ctx, dstDirPath, file)
+func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) (t task.TaskExtensionInfo, err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditUpload, stdpath.Join(dstDirPath, file.GetName()), "", err) }()
+	if err = op.CheckPathACL(ctx, dstDirPath, model.PathWrite); err != nil {
+		return nil, err
+	}
+	t, err = putAsTask(ctx, dstDirPath, file)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
 	}
@@ -115,6 +162,9 @@ func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 }
 
 func ArchiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (*model.ArchiveMetaProvider, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathRead); err != nil {
+		return nil, err
+	}
 	meta, err := archiveMeta(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed get archive meta %s: %+v", path, err)
@@ -123,6 +173,9 @@ func ArchiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (
 }
 
 func ArchiveList(ctx context.Context, path string, args model.ArchiveListArgs) ([]model.Obj, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathRead); err != nil {
+		return nil, err
+	}
 	objs, err := archiveList(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed list archive [%s]%s: %+v", path, args.InnerPath, err)
@@ -131,6 +184,12 @@ func ArchiveList(ctx context.Context, path string, args model.ArchiveListArgs) (
 }
 
 func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args model.ArchiveDecompressArgs, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	if err := op.CheckPathACL(ctx, srcObjPath, model.PathRead); err != nil {
+		return nil, err
+	}
+	if err := op.CheckPathACL(ctx, dstDirPath, model.PathWrite); err != nil {
+		return nil, err
+	}
 	t, err := archiveDecompress(ctx, srcObjPath, dstDirPath, args, lazyCache...)
 	if err != nil {
 		log.Errorf("failed decompress [%s]%s: %+v", srcObjPath, args.InnerPath, err)
@@ -139,6 +198,9 @@ func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 }
 
 func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (*model.Link, model.Obj, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathRead); err != nil {
+		return nil, nil, err
+	}
 	l, obj, err := archiveDriverExtract(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed extract [%s]%s: %+v", path, args.InnerPath, err)
@@ -147,6 +209,9 @@ func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveIn
 }
 
 func ArchiveInternalExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error) {
+	if err := op.CheckPathACL(ctx, path, model.PathRead); err != nil {
+		return nil, 0, err
+	}
 	l, obj, err := archiveInternalExtract(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed extract [%s]%s: %+v", path, args.InnerPath, err)
@@ -154,6 +219,13 @@ func ArchiveInternalExtract(ctx context.Context, path string, args model.Archive
 	return l, obj, err
 }
 
+// BackendCtx marks ctx for the operations a driver like union or mirror runs on the mounts it is made of,
+// they are parts of the operation on the driver, which is charged to the quotas and audited once by op and fs
+func BackendCtx(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, conf.NoQuotaKey, struct{}{})
+	return context.WithValue(ctx, conf.NoAuditKey, struct{}{})
+}
+
 type GetStoragesArgs struct {
 }
 
@@ -166,6 +238,9 @@ func GetStorage(path string, args *GetStoragesArgs) (driver.Driver, error) {
 }
 
 func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
+	if err := op.CheckPathACL(ctx, args.Path, model.PathRead); err != nil {
+		return nil, err
+	}
 	res, err := other(ctx, args)
 	if err != nil {
 		log.Errorf("failed get other %s: %+v", args.Path, err)
@@ -173,7 +248,11 @@ func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
 	return res, err
 }
 
-func PutURL(ctx context.Context, path, dstName, urlStr string) error {
+func PutURL(ctx context.Context, path, dstName, urlStr string) (err error) {
+	defer func() { op.RecordAudit(ctx, model.AuditUpload, stdpath.Join(path, dstName), "", err) }()
+	if err = op.CheckPathACL(ctx, path, model.PathWrite); err != nil {
+		return err
+	}
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"context"
+	stdpath "path"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -16,6 +17,12 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 	meta, _ := ctx.Value(conf.MetaKey).(*model.Meta)
 	user, _ := ctx.Value(conf.UserKey).(*model.User)
 	virtualFiles := op.GetStorageVirtualFilesByPath(path)
+	if user != nil {
+		// hide the tenant namespaces from the users outside of them
+		virtualFiles = utils.SliceFilter(virtualFiles, func(f model.Obj) bool {
+			return user.InNamespace(stdpath.Join(path, f.GetName()))
+		})
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil && len(virtualFiles) == 0 {
 		return nil, errors.WithMessage(err, "failed get storage")
@@ -42,6 +49,10 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 		om.InitHideReg(meta.Hide)
 	}
 	objs := om.Merge(_objs, virtualFiles...)
+	// hide the objects the path ACLs of the user deny reading
+	objs = utils.SliceFilter(objs, func(o model.Obj) bool {
+		return op.CheckPathACL(ctx, stdpath.Join(path, o.GetName()), model.PathRead) == nil
+	})
 	return objs, nil
 }
 
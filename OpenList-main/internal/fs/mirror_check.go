@@ -0,0 +1,141 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxReportedDivergences is how many divergences the error of a check lists
+const maxReportedDivergences = 20
+
+// MirrorCheckTask compares the tree of a primary dir with the trees of its mirrors,
+// the task fails with the divergences found, if any
+type MirrorCheckTask struct {
+	task.TaskExtension
+	Primary     string
+	Mirrors     []string
+	Status      string
+	checked     int
+	divergences []string
+}
+
+func (t *MirrorCheckTask) GetName() string {
+	return fmt.Sprintf("check mirrors of [%s]: %s", t.Primary, strings.Join(t.Mirrors, ", "))
+}
+
+func (t *MirrorCheckTask) GetStatus() string {
+	return t.Status
+}
+
+func (t *MirrorCheckTask) Run() error {
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	t.checked, t.divergences = 0, nil
+	if err := t.compare("", t.Mirrors); err != nil {
+		return err
+	}
+	t.Status = fmt.Sprintf("checked %d objects, %d divergences", t.checked, len(t.divergences))
+	if len(t.divergences) == 0 {
+		return nil
+	}
+	reported := t.divergences
+	if len(reported) > maxReportedDivergences {
+		reported = reported[:maxReportedDivergences]
+	}
+	return errors.Errorf("%d divergences found: %s", len(t.divergences), strings.Join(reported, "; "))
+}
+
+func (t *MirrorCheckTask) diverge(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Warnf("mirror check of [%s]: %s", t.Primary, msg)
+	t.divergences = append(t.divergences, msg)
+}
+
+// compare compares the dir of the primary with the same dir of the mirrors having it
+func (t *MirrorCheckTask) compare(dir string, mirrors []string) error {
+	if utils.IsCanceled(t.Ctx()) {
+		return t.Ctx().Err()
+	}
+	t.Status = "checking " + stdpath.Join("/", dir)
+	objs, err := list(t.Ctx(), stdpath.Join(t.Primary, dir), &ListArgs{Refresh: true, NoLog: true})
+	if err != nil {
+		return errors.WithMessagef(err, "failed list primary dir [%s]", dir)
+	}
+	t.checked += len(objs)
+	mirrorObjs := make([]map[string]model.Obj, len(mirrors))
+	for i, mirror := range mirrors {
+		tmp, err := list(t.Ctx(), stdpath.Join(mirror, dir), &ListArgs{Refresh: true, NoLog: true})
+		if err != nil {
+			t.diverge("failed list [%s]: %s", stdpath.Join(mirror, dir), err)
+			continue
+		}
+		mirrorObjs[i] = make(map[string]model.Obj, len(tmp))
+		for _, obj := range tmp {
+			mirrorObjs[i][obj.GetName()] = obj
+		}
+	}
+	for _, obj := range objs {
+		path := stdpath.Join(dir, obj.GetName())
+		var subMirrors []string
+		for i, mirror := range mirrors {
+			if mirrorObjs[i] == nil {
+				continue
+			}
+			mObj, ok := mirrorObjs[i][obj.GetName()]
+			if !ok {
+				t.diverge("[%s] missing in [%s]", path, mirror)
+				continue
+			}
+			delete(mirrorObjs[i], obj.GetName())
+			switch {
+			case obj.IsDir() != mObj.IsDir():
+				t.diverge("[%s] is a dir in only one of [%s] and [%s]", path, t.Primary, mirror)
+			case obj.IsDir():
+				subMirrors = append(subMirrors, mirror)
+			case obj.GetSize() != mObj.GetSize():
+				t.diverge("[%s] is %d bytes in [%s] but %d bytes in [%s]", path, obj.GetSize(), t.Primary, mObj.GetSize(), mirror)
+			}
+		}
+		if obj.IsDir() && len(subMirrors) > 0 {
+			if err := t.compare(path, subMirrors); err != nil {
+				return err
+			}
+		}
+	}
+	for i, mirror := range mirrors {
+		for name := range mirrorObjs[i] {
+			t.diverge("[%s] only exists in [%s]", stdpath.Join(dir, name), mirror)
+		}
+	}
+	return nil
+}
+
+var MirrorCheckTaskManager *tache.Manager[*MirrorCheckTask]
+
+// CheckMirrors add a task comparing the primary dir with its mirror dirs
+func CheckMirrors(ctx context.Context, primary string, mirrors []string) (task.TaskExtensionInfo, error) {
+	if len(mirrors) == 0 {
+		return nil, errors.New("no mirror to check")
+	}
+	t := &MirrorCheckTask{
+		Primary: utils.FixAndCleanPath(primary),
+		Mirrors: utils.MustSliceConvert(mirrors, utils.FixAndCleanPath),
+	}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	MirrorCheckTaskManager.Add(t)
+	return t, nil
+}
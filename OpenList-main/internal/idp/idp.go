@@ -0,0 +1,147 @@
+package idp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const signingKeyName = "signing.key"
+
+// Provider holds the key OpenList signs its ID and access tokens with when acting as an OIDC provider.
+type Provider struct {
+	Key   *rsa.PrivateKey
+	KeyID string
+}
+
+var (
+	provider *Provider
+	mu       sync.RWMutex
+)
+
+// Init loads the signing key from the database, so that all the nodes of a cluster sign with the same key.
+// The key is generated on the first start, or taken from the data directory where earlier versions kept it.
+func Init() error {
+	k, err := db.GetOIDCSigningKey()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		k, err = createSigningKey()
+	}
+	if err != nil {
+		return err
+	}
+	key, err := parseSigningKey([]byte(k.KeyPEM))
+	if err != nil {
+		return err
+	}
+	Set(New(key))
+	return nil
+}
+
+// createSigningKey stores the key kept in the data directory by earlier versions or a new one,
+// unless another node has stored its key first, the stored key is returned
+func createSigningKey() (*model.OIDCSigningKey, error) {
+	keyPath := filepath.Join(flags.DataDir, "idp", signingKeyName)
+	var keyPEM []byte
+	if utils.Exists(keyPath) {
+		var err error
+		if keyPEM, err = os.ReadFile(keyPath); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to generate signing key")
+		}
+		if keyPEM, err = ca.EncodePrivateKeyPEM(key); err != nil {
+			return nil, err
+		}
+		utils.Log.Infof("generated new OIDC signing key")
+	}
+	k := &model.OIDCSigningKey{KeyPEM: string(keyPEM)}
+	if err := db.CreateOIDCSigningKey(k); err != nil {
+		return db.GetOIDCSigningKey()
+	}
+	return k, nil
+}
+
+// Set replaces the active provider.
+func Set(p *Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+}
+
+// Get returns the active provider, or nil if Init has not been called.
+func Get() *Provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	return provider
+}
+
+func parseSigningKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	signer, err := ca.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to parse signing key")
+	}
+	key, ok := signer.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA key")
+	}
+	return key, nil
+}
+
+// New builds a Provider from a key, the key id is derived from the public key.
+func New(key *rsa.PrivateKey) *Provider {
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	sum := sha256.Sum256(der)
+	return &Provider{Key: key, KeyID: base64.RawURLEncoding.EncodeToString(sum[:12])}
+}
+
+// Sign returns the claims as a RS256 signed JWT.
+func (p *Provider) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.KeyID
+	s, err := token.SignedString(p.Key)
+	return s, errors.WithStack(err)
+}
+
+// Parse verifies a JWT signed by Sign and decodes it into claims.
+func (p *Provider) Parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return &p.Key.PublicKey, nil
+	})
+	return errors.WithStack(err)
+}
+
+// JWKS returns the JSON Web Key Set clients verify the tokens with.
+func (p *Provider) JWKS() map[string]any {
+	pub := p.Key.PublicKey
+	return map[string]any{
+		"keys": []map[string]any{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.KeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	}
+}
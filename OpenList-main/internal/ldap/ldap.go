@@ -0,0 +1,101 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/pkg/errors"
+	"gopkg.in/ldap.v3"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user does not exist")
+	ErrTooManyEntries = errors.New("too many entries returned")
+)
+
+// lockAttributes are read to tell whether an account is locked in the directory
+var lockAttributes = []string{"nsAccountLock", "userAccountControl", "pwdAccountLockedTime"}
+
+// adAccountDisable is the ACCOUNTDISABLE flag of userAccountControl in Active Directory
+const adAccountDisable = 0x2
+
+func Dial(server string) (*ldap.Conn, error) {
+	if strings.HasPrefix(server, "ldaps://") {
+		return ldap.DialTLS("tcp", strings.TrimPrefix(server, "ldaps://"), &tls.Config{InsecureSkipVerify: true})
+	}
+	return ldap.Dial("tcp", strings.TrimPrefix(server, "ldap://"))
+}
+
+// Connect dials the configured server and binds with the manager account if there is one
+func Connect() (*ldap.Conn, error) {
+	l, err := Dial(setting.GetStr(conf.LdapServer))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to LDAP")
+	}
+	managerDN := setting.GetStr(conf.LdapManagerDN)
+	managerPassword := setting.GetStr(conf.LdapManagerPassword)
+	if managerDN != "" && managerPassword != "" {
+		if err = l.Bind(managerDN, managerPassword); err != nil {
+			l.Close()
+			return nil, errors.Wrap(err, "failed to bind to LDAP")
+		}
+	}
+	return l, nil
+}
+
+// SearchUser looks up the entry of the user with the group and lock attributes
+func SearchUser(l *ldap.Conn, username string) (*ldap.Entry, error) {
+	attributes := append([]string{"dn"}, lockAttributes...)
+	if attr := setting.GetStr(conf.LdapGroupAttribute); attr != "" {
+		attributes = append(attributes, attr)
+	}
+	sr, err := l.Search(ldap.NewSearchRequest(
+		setting.GetStr(conf.LdapUserSearchBase),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(setting.GetStr(conf.LdapUserSearchFilter), ldap.EscapeFilter(username)),
+		attributes,
+		nil,
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "LDAP search failed")
+	}
+	switch len(sr.Entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+		return sr.Entries[0], nil
+	default:
+		return nil, ErrTooManyEntries
+	}
+}
+
+// Groups returns the groups of the entry, nil if no group attribute is configured
+func Groups(entry *ldap.Entry) []string {
+	attr := setting.GetStr(conf.LdapGroupAttribute)
+	if attr == "" {
+		return nil
+	}
+	groups := entry.GetAttributeValues(attr)
+	if groups == nil {
+		groups = []string{}
+	}
+	return groups
+}
+
+// Locked reports whether the account of the entry is locked or disabled,
+// it understands 389-ds/FreeIPA, Active Directory and the ppolicy overlay of OpenLDAP
+func Locked(entry *ldap.Entry) bool {
+	if strings.EqualFold(entry.GetAttributeValue("nsAccountLock"), "true") {
+		return true
+	}
+	if v := entry.GetAttributeValue("userAccountControl"); v != "" {
+		if flags, err := strconv.ParseInt(v, 10, 64); err == nil && flags&adAccountDisable != 0 {
+			return true
+		}
+	}
+	return entry.GetAttributeValue("pwdAccountLockedTime") != ""
+}
@@ -0,0 +1,27 @@
+package ldap
+
+import (
+	"testing"
+
+	"gopkg.in/ldap.v3"
+)
+
+func TestLocked(t *testing.T) {
+	var tests = []struct {
+		attributes map[string][]string
+		locked     bool
+	}{
+		{map[string][]string{}, false},
+		{map[string][]string{"nsAccountLock": {"TRUE"}}, true},
+		{map[string][]string{"nsAccountLock": {"false"}}, false},
+		{map[string][]string{"userAccountControl": {"514"}}, true},
+		{map[string][]string{"userAccountControl": {"512"}}, false},
+		{map[string][]string{"pwdAccountLockedTime": {"20260101000000Z"}}, true},
+	}
+	for _, tt := range tests {
+		entry := ldap.NewEntry("uid=alice,ou=people,dc=example,dc=com", tt.attributes)
+		if locked := Locked(entry); locked != tt.locked {
+			t.Errorf("attributes %v: expect locked %v, got %v", tt.attributes, tt.locked, locked)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package ldap
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	SyncDisabled      = "disabled"
+	SyncGroupsUpdated = "groups_updated"
+	SyncSkipped       = "skipped"
+)
+
+type SyncChange struct {
+	Username string `json:"username"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason"`
+}
+
+// SyncReport is what a reconciliation of the LDAP users changed
+type SyncReport struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Checked    int          `json:"checked"`
+	Changes    []SyncChange `json:"changes"`
+	Error      string       `json:"error,omitempty"`
+}
+
+var (
+	syncMu     sync.Mutex
+	lastReport *SyncReport
+)
+
+// LastSyncReport returns the report of the last reconciliation, nil if it never ran
+func LastSyncReport() *SyncReport {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	return lastReport
+}
+
+// Sync reconciles the users signing in with LDAP with the directory: users whose entries
+// were removed or locked are disabled, the groups of the others are synced.
+// It stops at the first directory error so that an unreachable server never disables anyone.
+func Sync() (*SyncReport, error) {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	report := &SyncReport{StartedAt: time.Now(), Changes: []SyncChange{}}
+	err := reconcile(report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.FinishedAt = time.Now()
+	lastReport = report
+	return report, err
+}
+
+func reconcile(report *SyncReport) error {
+	if !setting.GetBool(conf.LdapLoginEnabled) {
+		return errors.New("ldap is not enabled")
+	}
+	users, err := op.GetLdapUsers()
+	if err != nil {
+		return err
+	}
+	l, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for _, u := range users {
+		if u.Disabled || u.IsAdmin() {
+			continue
+		}
+		report.Checked++
+		entry, err := SearchUser(l, u.Username)
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			err = disable(report, u.ID, "removed from the directory")
+		case errors.Is(err, ErrTooManyEntries):
+			report.Changes = append(report.Changes, SyncChange{Username: u.Username, Action: SyncSkipped, Reason: err.Error()})
+			err = nil
+		case err != nil:
+		case Locked(entry):
+			err = disable(report, u.ID, "locked in the directory")
+		default:
+			err = syncGroups(report, u.ID, entry.DN, Groups(entry))
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "failed to reconcile %s", u.Username)
+		}
+	}
+	return nil
+}
+
+func disable(report *SyncReport, id uint, reason string) error {
+	user, err := op.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	user.Disabled = true
+	if err = op.UpdateUser(user); err != nil {
+		return err
+	}
+	report.Changes = append(report.Changes, SyncChange{Username: user.Username, Action: SyncDisabled, Reason: reason})
+	return nil
+}
+
+func syncGroups(report *SyncReport, id uint, dn string, groups []string) error {
+	user, err := op.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if user.LdapDN != dn {
+		user.LdapDN = dn
+		if err = op.UpdateUser(user); err != nil {
+			return err
+		}
+	}
+	if groups == nil {
+		return nil
+	}
+	before := slices.Clone(user.GroupIDs)
+	if err = op.SyncExternalGroups(user, groups); err != nil {
+		return err
+	}
+	slices.Sort(before)
+	after := slices.Clone(user.GroupIDs)
+	slices.Sort(after)
+	if !slices.Equal(before, after) {
+		// the cached user still has the old groups
+		if err = op.DelUserCache(user.Username); err != nil {
+			return err
+		}
+		report.Changes = append(report.Changes, SyncChange{Username: user.Username, Action: SyncGroupsUpdated, Reason: "group membership changed in the directory"})
+	}
+	return nil
+}
+
+// Log logs a summary of the reconciliation
+func (r *SyncReport) Log() {
+	if r.Error != "" {
+		utils.Log.Errorf("ldap sync failed after checking %d users: %s", r.Checked, r.Error)
+	}
+	for _, c := range r.Changes {
+		utils.Log.Infof("ldap sync: %s %s, %s", c.Action, c.Username, c.Reason)
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// PathAction is an action on a path which path ACLs allow or deny
+type PathAction int32
+
+const (
+	PathRead   PathAction = 1 << iota // get, download and preview
+	PathWrite                         // upload, make dir, rename and the target of copy or move
+	PathDelete                        // remove and the source of move
+	PathShare                         // create sharings
+	PathList                          // list the objects of a directory
+)
+
+const (
+	ACLSubjectUser  = "user"
+	ACLSubjectGroup = "group"
+)
+
+// PathACL allows or denies actions on a path to a user or to the members of a group
+type PathACL struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Path        string `json:"path" gorm:"index" binding:"required"`
+	SubjectType string `json:"subject_type" binding:"oneof=user group"`
+	SubjectID   uint   `json:"subject_id" binding:"required"`
+	Allow       int32  `json:"allow"` // bitmask of PathAction
+	Deny        int32  `json:"deny"`  // bitmask of PathAction, takes precedence over allow on the same path
+	Sub         bool   `json:"sub"`   // the entry is inherited by sub paths
+	Remark      string `json:"remark"`
+}
+
+func (a *PathACL) IsApply(path string) bool {
+	return utils.PathEqual(a.Path, path) || (a.Sub && utils.IsSubPath(a.Path, path))
+}
+
+// IsFor reports whether the entry applies to the user directly or through one of its groups
+func (a *PathACL) IsFor(user *User) bool {
+	switch a.SubjectType {
+	case ACLSubjectUser:
+		return a.SubjectID == user.ID
+	case ACLSubjectGroup:
+		return slices.Contains(user.GroupIDs, a.SubjectID)
+	}
+	return false
+}
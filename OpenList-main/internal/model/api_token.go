@@ -0,0 +1,46 @@
+package model
+
+import (
+	stdpath "path"
+	"time"
+)
+
+// ApiTokenPrefix marks personal API tokens in the Authorization header
+const ApiTokenPrefix = "olt_"
+
+// ApiToken is a long-lived personal token which acts as its owner within a narrower scope
+type ApiToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Hint       string     `json:"hint"`        // the leading characters of the token to tell tokens apart
+	PathPrefix string     `json:"path_prefix"` // the token can only access this path under the base path of the owner
+	Permission int32      `json:"permission"`  // a subset of the permission of the owner, see User.Permission
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *ApiToken) IsExpired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+func (t *ApiToken) IsActive() bool {
+	return t.RevokedAt == nil && !t.IsExpired()
+}
+
+// Scope returns a copy of the owner limited to the token: the permission is intersected,
+// the base path is narrowed to the path prefix and admins act as general users
+func (t *ApiToken) Scope(owner *User) *User {
+	u := *owner
+	if u.IsAdmin() {
+		u.Role = GENERAL
+	}
+	u.Permission = owner.EffectivePermission() & t.Permission
+	u.BasePath = stdpath.Join(owner.EffectiveBasePath(), t.PathPrefix)
+	u.GroupPermission, u.GroupBasePath, u.GroupMetas = 0, "", nil
+	u.Scoped = true
+	return &u
+}
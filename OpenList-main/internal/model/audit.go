@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// the protocols an audit event can come from
+const (
+	ProtocolWeb  = "web"
+	ProtocolDav  = "dav"
+	ProtocolFTP  = "ftp"
+	ProtocolSFTP = "sftp"
+	ProtocolS3   = "s3"
+	// actions run by OpenList itself, such as offline download tasks
+	ProtocolInternal = "internal"
+)
+
+// the actions recorded outside of the routes, the audited routes are recorded by their path,
+// such as admin.user.create for /api/admin/user/create
+const (
+	AuditLogin       = "auth.login"
+	AuditLogout      = "auth.logout"
+	AuditMkdir       = "fs.mkdir"
+	AuditRename      = "fs.rename"
+	AuditMove        = "fs.move"
+	AuditCopy        = "fs.copy"
+	AuditRemove      = "fs.remove"
+	AuditUpload      = "fs.upload"
+	AuditShareAccess = "share.access"
+)
+
+// AuditEvent is an action of a user, it is never changed once recorded
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username" gorm:"index"`
+	IP        string    `json:"ip"`
+	Protocol  string    `json:"protocol"`
+	Action    string    `json:"action" gorm:"index"`
+	Target    string    `json:"target" gorm:"type:text"` // the path or the id of what the action is on
+	Detail    string    `json:"detail" gorm:"type:text"` // e.g. the destination of a move
+	Success   bool      `json:"success"`
+	Error     string    `json:"error" gorm:"type:text"`
+}
+
+type AuditFilter struct {
+	Username string     `json:"username" form:"username"`
+	Action   string     `json:"action" form:"action"` // matches the action and the actions under it, e.g. fs matches fs.remove
+	Protocol string     `json:"protocol" form:"protocol"`
+	Target   string     `json:"target" form:"target"` // matches the targets starting with it
+	Success  *bool      `json:"success" form:"success"`
+	Since    *time.Time `json:"since" form:"since"`
+	Until    *time.Time `json:"until" form:"until"`
+}
@@ -1,40 +1,47 @@
 package model
 
 import (
-"time"
-"gorm.io/gorm"
+	"gorm.io/gorm"
+	"time"
 )
 
 // CertificateType 证书类型
 type CertificateType string
 
 const (
-CertificateTypeUser CertificateType = "user" // 用户证书
-CertificateTypeNode CertificateType = "node" // 节点证书
+	CertificateTypeUser CertificateType = "user" // 用户证书
+	CertificateTypeNode CertificateType = "node" // 节点证书
 )
 
 // CertificateStatus 证书状态
 type CertificateStatus string
 
 const (
-CertificateStatusPending  CertificateStatus = "pending"   // 待审批
-CertificateStatusValid    CertificateStatus = "valid"     // 有效
-CertificateStatusExpiring CertificateStatus = "expiring"  // 即将过期
-CertificateStatusRevoked  CertificateStatus = "revoked"   // 已吊销
-CertificateStatusRejected CertificateStatus = "rejected"  // 已拒绝
+	CertificateStatusPending    CertificateStatus = "pending"    // 待审批
+	CertificateStatusValid      CertificateStatus = "valid"      // 有效
+	CertificateStatusExpiring   CertificateStatus = "expiring"   // 即将过期
+	CertificateStatusRevoked    CertificateStatus = "revoked"    // 已吊销
+	CertificateStatusRejected   CertificateStatus = "rejected"   // 已拒绝
+	CertificateStatusExpired    CertificateStatus = "expired"    // 已过期
+	CertificateStatusSuperseded CertificateStatus = "superseded" // 已被续期后的新证书取代
 )
 
 // Certificate 证书实体
 type Certificate struct {
-	ID             uint              `json:"id" gorm:"primaryKey"`                        // unique key
-	Name           string            `json:"name" gorm:"not null;index"`                 // 证书名称
-	Type           CertificateType   `json:"type" gorm:"not null;index"`                 // 证书类型
-	Status         CertificateStatus `json:"status" gorm:"not null;index"`               // 证书状态
-	Owner          string            `json:"owner" gorm:"not null;index"`                // 证书所有者(用户名)
-	OwnerID        uint              `json:"owner_id" gorm:"index"`                      // 证书所有者ID
-	Content        string            `json:"content" gorm:"type:text"`                   // 证书内容(PEM格式)
-	IssuedDate     time.Time         `json:"issued_date"`                                // 颁发日期
-	ExpirationDate time.Time         `json:"expiration_date"`                            // 过期日期
+	ID             uint              `json:"id" gorm:"primaryKey"`                  // unique key
+	Name           string            `json:"name" gorm:"not null;index"`            // 证书名称
+	Type           CertificateType   `json:"type" gorm:"not null;index"`            // 证书类型
+	Status         CertificateStatus `json:"status" gorm:"not null;index"`          // 证书状态
+	Owner          string            `json:"owner" gorm:"not null;index"`           // 证书所有者(用户名)
+	OwnerID        uint              `json:"owner_id" gorm:"index"`                 // 证书所有者ID
+	SerialNumber   string            `json:"serial_number" gorm:"index"`            // 证书序列号(十六进制)
+	Content        string            `json:"content" gorm:"type:text"`              // 证书内容(PEM格式)
+	PrivateKey     string            `json:"-" gorm:"type:text"`                    // 私钥(PEM格式)，首次下载后清除
+	IssuedDate     time.Time         `json:"issued_date"`                           // 颁发日期
+	ExpirationDate time.Time         `json:"expiration_date"`                       // 过期日期
+	RevokedAt      *time.Time        `json:"revoked_at,omitempty"`                  // 吊销时间
+	PredecessorID  uint              `json:"predecessor_id,omitempty" gorm:"index"` // 续期前的旧证书ID
+	RequestID      uint              `json:"request_id,omitempty" gorm:"index"`     // 签发该证书的申请ID
 	CreatedAt      time.Time         `json:"created_at"`
 	UpdatedAt      time.Time         `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
@@ -42,12 +49,14 @@ type Certificate struct {
 
 // CertificateRequest 证书申请实体
 type CertificateRequest struct {
-	ID             uint              `json:"id" gorm:"primaryKey"`                        // unique key
+	ID             uint              `json:"id" gorm:"primaryKey"`                       // unique key
 	UserName       string            `json:"user_name" gorm:"not null;index"`            // 申请人用户名
 	UserID         uint              `json:"user_id" gorm:"index"`                       // 申请人用户ID
 	Type           CertificateType   `json:"type" gorm:"not null"`                       // 申请证书类型
 	Status         CertificateStatus `json:"status" gorm:"not null;index"`               // 申请状态
 	Reason         string            `json:"reason" gorm:"type:text"`                    // 申请理由
+	CSR            string            `json:"csr,omitempty" gorm:"type:text"`             // 租户提交的PKCS#10证书签名请求(PEM格式)，可选
+	RenewalOf      uint              `json:"renewal_of,omitempty" gorm:"index"`          // 续期申请对应的旧证书ID，0表示新申请
 	ApprovedBy     string            `json:"approved_by,omitempty"`                      // 审批人
 	ApprovedAt     *time.Time        `json:"approved_at,omitempty"`                      // 审批时间
 	RejectedBy     string            `json:"rejected_by,omitempty"`                      // 拒绝人
@@ -58,27 +67,76 @@ type CertificateRequest struct {
 	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
 }
 
+// CertificateAction 证书事件类型
+type CertificateAction string
+
+const (
+	CertificateActionCreate    CertificateAction = "create"    // 管理员手动导入证书
+	CertificateActionRequest   CertificateAction = "request"   // 提交申请
+	CertificateActionApprove   CertificateAction = "approve"   // 批准申请并签发证书
+	CertificateActionReject    CertificateAction = "reject"    // 拒绝申请
+	CertificateActionRevoke    CertificateAction = "revoke"    // 吊销证书
+	CertificateActionSupersede CertificateAction = "supersede" // 续期后旧证书被取代
+	CertificateActionExpiring  CertificateAction = "expiring"  // 进入即将过期状态
+	CertificateActionExpire    CertificateAction = "expire"    // 证书过期
+	CertificateActionDelete    CertificateAction = "delete"    // 删除证书
+	CertificateActionUpdate    CertificateAction = "update"    // 修改证书名称或过期日期
+)
+
+// CertificateSystemActor 后台任务引起的状态变更使用的操作人
+const CertificateSystemActor = "system"
+
+// CertificateEvent 证书及证书申请的状态变更记录，只增不改，用于审计
+type CertificateEvent struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	CertificateID uint              `json:"certificate_id,omitempty" gorm:"index"` // 证书ID，申请被拒绝等事件为0
+	RequestID     uint              `json:"request_id,omitempty" gorm:"index"`     // 证书申请ID
+	Action        CertificateAction `json:"action" gorm:"not null"`                // 事件类型
+	Actor         string            `json:"actor" gorm:"not null"`                 // 操作人用户名，后台任务为system
+	ActorID       uint              `json:"actor_id,omitempty"`                    // 操作人用户ID
+	IP            string            `json:"ip,omitempty"`                          // 操作人IP
+	OldStatus     CertificateStatus `json:"old_status,omitempty"`                  // 变更前状态
+	NewStatus     CertificateStatus `json:"new_status,omitempty"`                  // 变更后状态
+	Reason        string            `json:"reason,omitempty" gorm:"type:text"`     // 操作理由
+	CreatedAt     time.Time         `json:"created_at" gorm:"index"`
+}
+
 // IsValid 检查证书是否有效
 func (c *Certificate) IsValid() bool {
-return c.Status == CertificateStatusValid || c.Status == CertificateStatusExpiring
+	return c.Status == CertificateStatusValid || c.Status == CertificateStatusExpiring
+}
+
+// HasPrivateKey 私钥是否尚未被下载
+func (c *Certificate) HasPrivateKey() bool {
+	return c.PrivateKey != ""
 }
 
 // IsExpired 检查证书是否过期
 func (c *Certificate) IsExpired() bool {
-return time.Now().After(c.ExpirationDate)
+	return time.Now().After(c.ExpirationDate)
+}
+
+// IsRenewable 已过期或即将过期的证书均可续期，已吊销的证书不可续期
+func (c *Certificate) IsRenewable() bool {
+	return c.IsValid() || c.Status == CertificateStatusExpired
 }
 
 // IsPending 检查申请是否待审批
 func (cr *CertificateRequest) IsPending() bool {
-return cr.Status == CertificateStatusPending
+	return cr.Status == CertificateStatusPending
 }
 
 // IsApproved 检查申请是否已批准
 func (cr *CertificateRequest) IsApproved() bool {
-return cr.Status == CertificateStatusValid
+	return cr.Status == CertificateStatusValid
+}
+
+// IsRenewal 检查是否为续期申请
+func (cr *CertificateRequest) IsRenewal() bool {
+	return cr.RenewalOf != 0
 }
 
 // IsRejected 检查申请是否已拒绝
 func (cr *CertificateRequest) IsRejected() bool {
-return cr.Status == CertificateStatusRejected
+	return cr.Status == CertificateStatusRejected
 }
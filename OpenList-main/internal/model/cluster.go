@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type ClusterNode struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:255"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ClusterEvent is a change made by a node which the other nodes apply
+type ClusterEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	Node      string    `json:"node" gorm:"size:255"`
+	Type      string    `json:"type"`
+	Key       string    `json:"key" gorm:"type:text"`
+}
+
+// ClusterLease is held by one node until it expires, it elects the leader and guards the cluster wide jobs
+type ClusterLease struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:64"`
+	Holder    string    `json:"holder" gorm:"size:255"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// Group grants its permission, base path and meta overrides to all of its members
+type Group struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Name       string `json:"name" gorm:"unique" binding:"required"`
+	Permission int32  `json:"permission"` // merged into the permission of the members, see User.Permission
+	BasePath   string `json:"base_path"`  // base path of the members which have none of their own
+	// ExternalNames are the LDAP or SSO groups mapped to this group, one per line
+	ExternalNames string      `json:"external_names" gorm:"type:text"`
+	Remark        string      `json:"remark"`
+	Metas         []GroupMeta `json:"metas" gorm:"foreignKey:GroupID"`
+}
+
+// GroupMeta overrides the meta of a path for the members of a group
+type GroupMeta struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	GroupID    uint   `json:"-" gorm:"index"`
+	Path       string `json:"path"`
+	NoPassword bool   `json:"no_password"` // members access the path without the meta password
+	Write      bool   `json:"write"`       // members can write to the path
+	Sub        bool   `json:"sub"`         // the override also applies to sub paths
+}
+
+// UserGroup is the membership of a user in a group
+type UserGroup struct {
+	UserID  uint `json:"user_id" gorm:"primaryKey"`
+	GroupID uint `json:"group_id" gorm:"primaryKey;index"`
+}
+
+func (m *GroupMeta) IsApply(path string) bool {
+	return utils.PathEqual(m.Path, path) || (m.Sub && utils.IsSubPath(m.Path, path))
+}
+
+// IsExternal reports whether the group membership is managed by LDAP or SSO
+func (g *Group) IsExternal() bool {
+	return strings.TrimSpace(g.ExternalNames) != ""
+}
+
+// MatchExternal reports whether an external group maps to this group,
+// a distinguished name such as cn=dev,ou=groups,dc=example,dc=com also matches by its first value
+func (g *Group) MatchExternal(name string) bool {
+	short := name
+	if rdn, _, _ := strings.Cut(name, ","); strings.Contains(rdn, "=") {
+		_, short, _ = strings.Cut(rdn, "=")
+	}
+	for _, n := range strings.Split(g.ExternalNames, "\n") {
+		n = strings.TrimSpace(n)
+		if n != "" && (strings.EqualFold(n, name) || strings.EqualFold(n, short)) {
+			return true
+		}
+	}
+	return false
+}
@@ -13,4 +13,5 @@ type Meta struct {
 	RSub      bool   `json:"r_sub"`
 	Header    string `json:"header"`
 	HeaderSub bool   `json:"header_sub"`
+	TenantID  uint   `json:"tenant_id" gorm:"index"`
 }
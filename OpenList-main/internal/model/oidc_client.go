@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// OIDCClient is an application allowed to log in users with OpenList as OIDC provider
+type OIDCClient struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ClientID   string `json:"client_id" gorm:"size:64;uniqueIndex"`
+	SecretHash string `json:"-"`
+	Name       string `json:"name" binding:"required"`
+	// RedirectURIs are the allowed redirect uris, one per line, compared exactly
+	RedirectURIs string `json:"redirect_uris" gorm:"type:text"`
+	// Public clients have no secret and must use PKCE
+	Public    bool      `json:"public"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *OIDCClient) AllowRedirect(uri string) bool {
+	for _, u := range strings.Split(c.RedirectURIs, "\n") {
+		if u = strings.TrimSpace(u); u != "" && u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCAuthRequest is an authorization request approved by a user, kept until its code is exchanged
+type OIDCAuthRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	UserID              uint
+	AuthTime            time.Time
+}
+
+// OIDCAuthCode is an issued authorization code, only the hash of the code is stored
+type OIDCAuthCode struct {
+	CodeHash        string `gorm:"primaryKey;size:64"`
+	OIDCAuthRequest `gorm:"embedded"`
+	ExpiresAt       time.Time `gorm:"index"`
+}
+
+// OIDCSigningKey is the key the ID and access tokens are signed with, stored once for all the nodes of a cluster
+type OIDCSigningKey struct {
+	ID        uint   `gorm:"primaryKey"`
+	KeyPEM    string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+// OIDCTokens is the token response of the token endpoint
+type OIDCTokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope"`
+}
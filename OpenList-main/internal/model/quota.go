@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+const (
+	QuotaScopeUser   = "user"
+	QuotaScopeTenant = "tenant"
+)
+
+// QuotaUsage is the storage charged to a user or a tenant
+type QuotaUsage struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	Scope       string    `json:"scope" gorm:"size:16;uniqueIndex:idx_quota_usage_owner"`
+	OwnerID     uint      `json:"owner_id" gorm:"uniqueIndex:idx_quota_usage_owner"`
+	UsedBytes   int64     `json:"used_bytes"`
+	UsedObjects int64     `json:"used_objects"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Quota is the limit and the usage of a user or a tenant, a zero limit means unlimited
+type Quota struct {
+	Scope        string `json:"scope"`
+	OwnerID      uint   `json:"owner_id"`
+	LimitBytes   int64  `json:"limit_bytes"`
+	LimitObjects int64  `json:"limit_objects"`
+	UsedBytes    int64  `json:"used_bytes"`
+	UsedObjects  int64  `json:"used_objects"`
+}
+
+// AvailableBytes returns the bytes that can still be written, -1 if unlimited
+func (q *Quota) AvailableBytes() int64 {
+	if q.LimitBytes <= 0 {
+		return -1
+	}
+	return max(q.LimitBytes-q.UsedBytes, 0)
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Session is a login of a user, the login token carries its id and stops working once it is revoked
+type Session struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:32"`
+	UserID    uint      `json:"-" gorm:"index"`
+	Device    string    `json:"device"` // a short description of the client derived from the user agent
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
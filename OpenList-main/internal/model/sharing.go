@@ -10,6 +10,7 @@ type SharingDB struct {
 	Accessed    int        `json:"accessed"`
 	MaxAccessed int        `json:"max_accessed"`
 	CreatorId   uint       `json:"-"`
+	TenantID    uint       `json:"tenant_id" gorm:"index"`
 	Disabled    bool       `json:"disabled"`
 	Remark      string     `json:"remark"`
 	Readme      string     `json:"readme" gorm:"type:text"`
@@ -19,6 +19,13 @@ type Storage struct {
 	EnableSign      bool      `json:"enable_sign"`
 	Sort
 	Proxy
+	Tenancy
+}
+
+// Tenancy is embedded rather than a plain field so that it does not
+// collide with the tenant_id addition of some drivers
+type Tenancy struct {
+	TenantID uint `json:"tenant_id" gorm:"index"` // tenant namespace the storage is mounted in, 0 if global
 }
 
 type Sort struct {
@@ -55,3 +62,9 @@ func (p Proxy) Webdav302() bool {
 func (p Proxy) WebdavProxyURL() bool {
 	return p.WebdavPolicy == "use_proxy_url"
 }
+
+// StorageDetails is the space of the backend of a storage, in bytes
+type StorageDetails struct {
+	TotalSpace int64 `json:"total_space"`
+	FreeSpace  int64 `json:"free_space"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// HealthCheck is a probe or a re-init of a storage by the health checker
+type HealthCheck struct {
+	Time    time.Time `json:"time"`
+	Healthy bool      `json:"healthy"`
+	Reinit  bool      `json:"reinit"`  // the storage was initialized again instead of probed
+	Latency int64     `json:"latency"` // in milliseconds
+	Error   string    `json:"error"`
+}
+
+// StorageHealth is the health of a storage seen by the health checker, kept in memory only
+type StorageHealth struct {
+	ID        uint          `json:"id"`
+	MountPath string        `json:"mount_path"`
+	Driver    string        `json:"driver"`
+	Status    string        `json:"status"`
+	Failures  int           `json:"failures"` // consecutive failed checks, the backoff grows with them
+	NextCheck time.Time     `json:"next_check"`
+	History   []HealthCheck `json:"history"` // latest last
+}
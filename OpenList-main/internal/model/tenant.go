@@ -0,0 +1,73 @@
+package model
+
+import (
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// TenantsRoot 所有租户命名空间的父目录
+// 租户的存储、元信息和用户基础路径都位于 TenantsRoot/<租户名> 之下
+const TenantsRoot = "/@tenants"
+
+// TenantCertLogin 租户登录时对证书持有证明的要求
+type TenantCertLogin string
+
+const (
+	TenantCertLoginDisabled TenantCertLogin = "disabled" // 只使用密码登录，默认值
+	TenantCertLoginOptional TenantCertLogin = "optional" // 提交了证书签名时必须验证通过
+	TenantCertLoginRequired TenantCertLogin = "required" // 必须提交有效证书对登录挑战的签名
+)
+
+// Tenant 租户实体，每个租户拥有独立的命名空间
+type Tenant struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`                  // unique key
+	Name         string          `json:"name" gorm:"unique" binding:"required"` // 租户标识，用作命名空间目录名，创建后不可修改
+	DisplayName  string          `json:"display_name"`                          // 显示名称
+	Disabled     bool            `json:"disabled"`                              // 禁用后租户命名空间内的存储均不可访问
+	Remark       string          `json:"remark"`
+	QuotaBytes   int64           `json:"quota_bytes"`   // 租户命名空间内存储的总字节数上限，0表示不限制
+	QuotaObjects int64           `json:"quota_objects"` // 租户命名空间内存储的文件数上限，0表示不限制
+	CertLogin    TenantCertLogin `json:"cert_login"`    // 租户用户登录时的证书要求，为空时视为 disabled
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// TenantReq 按租户筛选列表，TenantID 为空时返回全部，为0时只返回不属于任何租户的记录
+type TenantReq struct {
+	TenantID *uint `json:"tenant_id" form:"tenant_id"`
+}
+
+// CertLoginMode 租户的证书登录模式
+func (t *Tenant) CertLoginMode() TenantCertLogin {
+	if t.CertLogin == "" {
+		return TenantCertLoginDisabled
+	}
+	return t.CertLogin
+}
+
+// RootPath 租户命名空间的根目录
+func (t *Tenant) RootPath() string {
+	return TenantRootPath(t.Name)
+}
+
+func TenantRootPath(name string) string {
+	return stdpath.Join(TenantsRoot, name)
+}
+
+// IsTenantPath 路径是否位于租户命名空间中
+func IsTenantPath(path string) bool {
+	return utils.IsSubPath(TenantsRoot, path)
+}
+
+// TenantNameOfPath 获取路径所属租户的名称
+func TenantNameOfPath(path string) (string, bool) {
+	path = utils.FixAndCleanPath(path)
+	if !IsTenantPath(path) {
+		return "", false
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(path, TenantsRoot), "/"), "/")
+	return name, name != ""
+}
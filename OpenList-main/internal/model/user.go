@@ -41,7 +41,13 @@ type User struct {
 	Password string `json:"password"`                                  // password
 	BasePath string `json:"base_path"`                                 // base path
 	Role     int    `json:"role"`                                      // user's role
+	TenantID uint   `json:"tenant_id" gorm:"index"`                    // tenant the user belongs to, 0 if none
 	Disabled bool   `json:"disabled"`
+	// disabled by the deletion from the directory provisioning the user through SCIM
+	Deprovisioned bool `json:"deprovisioned"`
+	// Storage quota of the user, 0 for unlimited
+	QuotaBytes   int64 `json:"quota_bytes"`
+	QuotaObjects int64 `json:"quota_objects"`
 	// Determine permissions by bit
 	//   0:  can see hidden files
 	//   1:  can access without password
@@ -60,8 +66,25 @@ type User struct {
 	//   14: can share
 	Permission int32  `json:"permission"`
 	OtpSecret  string `json:"-"`
-	SsoID      string `json:"sso_id"` // unique by sso platform
+	SsoID      string `json:"sso_id"`                   // unique by sso platform
+	LdapDN     string `json:"ldap_dn"`                  // dn of the directory entry if the user signs in with LDAP
+	ExternalID string `json:"external_id" gorm:"index"` // id in the directory provisioning the user through SCIM
 	Authn      string `gorm:"type:text" json:"-"`
+	// hashes of the unused one-time recovery codes of 2FA, one per line
+	RecoveryCodes string `json:"-" gorm:"type:text"`
+	// salted hashes of the previous passwords, newest first, one "salt:hash" per line
+	PwdHistory string `json:"-" gorm:"type:text"`
+	// failed sign-in attempts since the last successful one, the account is locked at LockedAt
+	// once they reach the threshold
+	FailedLogins int        `json:"failed_logins"`
+	LockedAt     *time.Time `json:"locked_at"`
+	// resolved from the groups of the user when it is loaded, never stored
+	GroupIDs        []uint      `json:"group_ids" gorm:"-"`
+	GroupPermission int32       `json:"-" gorm:"-"`
+	GroupBasePath   string      `json:"-" gorm:"-"`
+	GroupMetas      []GroupMeta `json:"-" gorm:"-"`
+	// set on the copy of the user acting through an API token, whose permission is then all it is granted
+	Scoped bool `json:"-" gorm:"-"`
 }
 
 func (u *User) IsGuest() bool {
@@ -86,6 +109,11 @@ func (u *User) ValidatePwdStaticHash(pwdStaticHash string) error {
 	return nil
 }
 
+// IsLocked reports whether the account is locked, a zero duration locks it until it is unlocked
+func (u *User) IsLocked(duration time.Duration) bool {
+	return u.LockedAt != nil && (duration <= 0 || time.Since(*u.LockedAt) < duration)
+}
+
 func (u *User) SetPassword(pwd string) *User {
 	u.Salt = random.String(16)
 	u.PwdHash = TwoHashPwd(pwd, u.Salt)
@@ -93,68 +121,120 @@ func (u *User) SetPassword(pwd string) *User {
 	return u
 }
 
+// EffectivePermission is the union of the permission of the user and of its groups
+func (u *User) EffectivePermission() int32 {
+	return u.Permission | u.GroupPermission
+}
+
+// EffectiveBasePath is the base path of the user, or of its groups if the user has none
+func (u *User) EffectiveBasePath() string {
+	if u.BasePath != "" {
+		return u.BasePath
+	}
+	if u.GroupBasePath != "" {
+		return u.GroupBasePath
+	}
+	return "/"
+}
+
+// GroupMetaOf returns the nearest meta override of the groups of the user which applies to the path
+func (u *User) GroupMetaOf(path string) *GroupMeta {
+	var nearest *GroupMeta
+	for i := range u.GroupMetas {
+		m := &u.GroupMetas[i]
+		if m.IsApply(path) && (nearest == nil || len(m.Path) > len(nearest.Path)) {
+			nearest = m
+		}
+	}
+	return nearest
+}
+
 func (u *User) CanSeeHides() bool {
-	return u.Permission&1 == 1
+	return u.EffectivePermission()&1 == 1
 }
 
 func (u *User) CanAccessWithoutPassword() bool {
-	return (u.Permission>>1)&1 == 1
+	return (u.EffectivePermission()>>1)&1 == 1
 }
 
 func (u *User) CanAddOfflineDownloadTasks() bool {
-	return (u.Permission>>2)&1 == 1
+	return (u.EffectivePermission()>>2)&1 == 1
 }
 
 func (u *User) CanWrite() bool {
-	return (u.Permission>>3)&1 == 1
+	return (u.EffectivePermission()>>3)&1 == 1
+}
+
+// WithoutWrite returns a copy of the user whose permission and the permission of its groups lack the write bit
+func (u *User) WithoutWrite() *User {
+	c := *u
+	c.Permission &^= 1 << 3
+	c.GroupPermission &^= 1 << 3
+	return &c
 }
 
 func (u *User) CanRename() bool {
-	return (u.Permission>>4)&1 == 1
+	return (u.EffectivePermission()>>4)&1 == 1
 }
 
 func (u *User) CanMove() bool {
-	return (u.Permission>>5)&1 == 1
+	return (u.EffectivePermission()>>5)&1 == 1
 }
 
 func (u *User) CanCopy() bool {
-	return (u.Permission>>6)&1 == 1
+	return (u.EffectivePermission()>>6)&1 == 1
 }
 
 func (u *User) CanRemove() bool {
-	return (u.Permission>>7)&1 == 1
+	return (u.EffectivePermission()>>7)&1 == 1
 }
 
 func (u *User) CanWebdavRead() bool {
-	return (u.Permission>>8)&1 == 1
+	return (u.EffectivePermission()>>8)&1 == 1
 }
 
 func (u *User) CanWebdavManage() bool {
-	return (u.Permission>>9)&1 == 1
+	return (u.EffectivePermission()>>9)&1 == 1
 }
 
 func (u *User) CanFTPAccess() bool {
-	return (u.Permission>>10)&1 == 1
+	return (u.EffectivePermission()>>10)&1 == 1
 }
 
 func (u *User) CanFTPManage() bool {
-	return (u.Permission>>11)&1 == 1
+	return (u.EffectivePermission()>>11)&1 == 1
 }
 
 func (u *User) CanReadArchives() bool {
-	return (u.Permission>>12)&1 == 1
+	return (u.EffectivePermission()>>12)&1 == 1
 }
 
 func (u *User) CanDecompress() bool {
-	return (u.Permission>>13)&1 == 1
+	return (u.EffectivePermission()>>13)&1 == 1
 }
 
 func (u *User) CanShare() bool {
-	return (u.Permission>>14)&1 == 1
+	return (u.EffectivePermission()>>14)&1 == 1
 }
 
 func (u *User) JoinPath(reqPath string) (string, error) {
-	return utils.JoinBasePath(u.BasePath, reqPath)
+	path, err := utils.JoinBasePath(u.EffectiveBasePath(), reqPath)
+	if err != nil {
+		return "", err
+	}
+	if !u.InNamespace(path) {
+		return "", errors.WithStack(errs.PermissionDenied)
+	}
+	return path, nil
+}
+
+// InNamespace reports whether the user may access the path:
+// tenant users are confined to the tenant namespaces and other users except admin may not enter them
+func (u *User) InNamespace(path string) bool {
+	if u.IsAdmin() {
+		return true
+	}
+	return (u.TenantID != 0) == IsTenantPath(path)
 }
 
 func StaticHash(password string) string {
@@ -326,7 +326,9 @@ func removeObjTemp(t *TransferTask) {
 	if err != nil || srcObj.IsDir() {
 		return
 	}
-	if err := op.Remove(t.Ctx(), t.SrcStorage, t.SrcActualPath); err != nil {
+	// the temp obj was never charged to any quota
+	ctx := context.WithValue(t.Ctx(), conf.NoQuotaKey, struct{}{})
+	if err := op.Remove(ctx, t.SrcStorage, t.SrcActualPath); err != nil {
 		log.Errorf("failed to delete temp obj %s, error: %s", t.SrcActualPath, err.Error())
 	}
 }
@@ -0,0 +1,234 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/dlclark/regexp2"
+	"github.com/pkg/errors"
+)
+
+// the path ACLs are few and checked on every access, so all of them are kept in memory
+var (
+	pathACLs      []model.PathACL
+	pathACLsValid bool
+	pathACLsMu    sync.RWMutex
+)
+
+func GetPathACLById(id uint) (*model.PathACL, error) {
+	return db.GetPathACLById(id)
+}
+
+func GetPathACLs(pageIndex, pageSize int) ([]model.PathACL, int64, error) {
+	return db.GetPathACLs(pageIndex, pageSize)
+}
+
+func CreatePathACL(a *model.PathACL) error {
+	if err := checkPathACL(a); err != nil {
+		return err
+	}
+	defer dropCache(ChangePathACL, "")
+	return db.CreatePathACL(a)
+}
+
+func UpdatePathACL(a *model.PathACL) error {
+	if _, err := db.GetPathACLById(a.ID); err != nil {
+		return err
+	}
+	if err := checkPathACL(a); err != nil {
+		return err
+	}
+	defer dropCache(ChangePathACL, "")
+	return db.UpdatePathACL(a)
+}
+
+func DeletePathACLById(id uint) error {
+	defer dropCache(ChangePathACL, "")
+	return db.DeletePathACLById(id)
+}
+
+func deletePathACLsBySubject(subjectType string, subjectID uint) error {
+	defer dropCache(ChangePathACL, "")
+	return db.DeletePathACLsBySubject(subjectType, subjectID)
+}
+
+func checkPathACL(a *model.PathACL) error {
+	a.Path = utils.FixAndCleanPath(a.Path)
+	switch a.SubjectType {
+	case model.ACLSubjectUser:
+		if _, err := db.GetUserById(a.SubjectID); err != nil {
+			return err
+		}
+	case model.ACLSubjectGroup:
+		if _, err := db.GetGroupById(a.SubjectID); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unknown acl subject type: %s", a.SubjectType)
+	}
+	return nil
+}
+
+func delPathACLsCache() {
+	pathACLsMu.Lock()
+	defer pathACLsMu.Unlock()
+	pathACLs, pathACLsValid = nil, false
+}
+
+func getPathACLs() ([]model.PathACL, error) {
+	pathACLsMu.RLock()
+	acls, valid := pathACLs, pathACLsValid
+	pathACLsMu.RUnlock()
+	if valid {
+		return acls, nil
+	}
+	pathACLsMu.Lock()
+	defer pathACLsMu.Unlock()
+	if !pathACLsValid {
+		acls, err := db.GetAllPathACLs()
+		if err != nil {
+			return nil, err
+		}
+		pathACLs, pathACLsValid = acls, true
+	}
+	return pathACLs, nil
+}
+
+// decidePathACL returns whether the ACLs of the user decide the action on the path and if so whether they allow it.
+// The entries on the nearest path deciding the action win, a deny wins over an allow on the same path.
+// Admins are never restricted by ACLs.
+func decidePathACL(user *model.User, path string, action model.PathAction) (decided, allowed bool, err error) {
+	if user == nil || user.IsAdmin() {
+		return false, false, nil
+	}
+	acls, err := getPathACLs()
+	if err != nil {
+		return false, false, err
+	}
+	depth := -1
+	for i := range acls {
+		a := &acls[i]
+		if (a.Allow|a.Deny)&int32(action) == 0 || !a.IsApply(path) || !a.IsFor(user) {
+			continue
+		}
+		// all the entries applying to the path are on it or its parents, the longest is the nearest
+		d := len(a.Path)
+		deny := a.Deny&int32(action) != 0
+		switch {
+		case d > depth:
+			depth, allowed = d, !deny
+		case d == depth && deny:
+			allowed = false
+		}
+	}
+	return depth >= 0, allowed, nil
+}
+
+// CheckPathACL fails with errs.PermissionDenied if the ACLs of the user in ctx deny the action on the path.
+// It is the check of fs, which does not know the metas and passwords the frontends have checked with CanPath.
+func CheckPathACL(ctx context.Context, path string, action model.PathAction) error {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	decided, allowed, err := decidePathACL(user, path, action)
+	if err != nil {
+		return err
+	}
+	if decided && !allowed {
+		return errors.WithStack(errs.PermissionDenied)
+	}
+	return nil
+}
+
+// CanPath is the single check of an action of a user on a path for all the frontends.
+// An ACL of the user or of its groups decides the action if there is one, so an allow grants
+// the action on its subtree even if the permissions of the user lack it. Otherwise the permissions
+// of the user and the nearest meta of the path decide. A user acting through an API token is never
+// granted more than the scope of the token, an allow then only lifts a deny on a parent path.
+func CanPath(user *model.User, meta *model.Meta, path string, action model.PathAction, password string) bool {
+	decided, allowed, err := decidePathACL(user, path, action)
+	if err != nil {
+		utils.Log.Errorf("failed check acl of %s: %+v", path, err)
+		return false
+	}
+	if decided && (!allowed || !user.Scoped) {
+		return allowed
+	}
+	switch action {
+	case model.PathRead, model.PathList:
+		return canAccess(user, meta, path, password)
+	case model.PathWrite:
+		return user.CanWrite() || canWriteMeta(user, meta, path)
+	case model.PathDelete:
+		return user.CanRemove()
+	case model.PathShare:
+		return user.CanShare()
+	}
+	return false
+}
+
+// CanPathNoPassword is CanPath for the frontends with no way to give the password of a meta, WebDAV and S3.
+// The nearest meta of the path still applies but its password is not asked.
+func CanPathNoPassword(user *model.User, path string, action model.PathAction) bool {
+	meta, _ := GetNearestMeta(path)
+	if meta != nil && meta.Password != "" {
+		m := *meta
+		m.Password = ""
+		meta = &m
+	}
+	return CanPath(user, meta, path, action, "")
+}
+
+// canWriteMeta reports whether the meta or a meta override of the user's groups allows writing to the path
+func canWriteMeta(user *model.User, meta *model.Meta, path string) bool {
+	if m := user.GroupMetaOf(path); m != nil && m.Write {
+		return true
+	}
+	if meta == nil || !meta.Write {
+		return false
+	}
+	return meta.WSub || meta.Path == path
+}
+
+func isMetaApply(metaPath, reqPath string, applySub bool) bool {
+	if utils.PathEqual(metaPath, reqPath) {
+		return true
+	}
+	return utils.IsSubPath(metaPath, reqPath) && applySub
+}
+
+func canAccess(user *model.User, meta *model.Meta, reqPath string, password string) bool {
+	// if the reqPath is in hide (only can check the nearest meta) and user can't see hides, can't access
+	if meta != nil && !user.CanSeeHides() && meta.Hide != "" &&
+		isMetaApply(meta.Path, stdpath.Dir(reqPath), meta.HSub) { // the meta should apply to the parent of current path
+		for _, hide := range strings.Split(meta.Hide, "\n") {
+			re := regexp2.MustCompile(hide, regexp2.None)
+			if isMatch, _ := re.MatchString(stdpath.Base(reqPath)); isMatch {
+				return false
+			}
+		}
+	}
+	// if is not guest and can access without password
+	if user.CanAccessWithoutPassword() {
+		return true
+	}
+	// if a group of the user overrides the password of the path
+	if m := user.GroupMetaOf(reqPath); m != nil && m.NoPassword {
+		return true
+	}
+	// if meta is nil or password is empty, can access
+	if meta == nil || meta.Password == "" {
+		return true
+	}
+	// if meta doesn't apply to sub_folder, can access
+	if !utils.PathEqual(meta.Path, reqPath) && !meta.PSub {
+		return true
+	}
+	// validate password
+	return meta.Password == password
+}
@@ -0,0 +1,96 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestPathACL(t *testing.T) {
+	team := &model.Group{Name: "acl-team"}
+	if err := op.CreateGroup(team); err != nil {
+		t.Fatalf("failed to create group: %+v", err)
+	}
+	// the user may write and remove, the ACLs narrow that down
+	user := &model.User{Username: "acl", BasePath: "/", GroupIDs: []uint{team.ID}, Permission: 1<<3 | 1<<7}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	readonly := &model.User{Username: "acl-readonly", BasePath: "/", GroupIDs: []uint{team.ID}}
+	if err := op.CreateUser(readonly); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	acls := []model.PathACL{
+		{Path: "/projects", SubjectType: model.ACLSubjectGroup, SubjectID: team.ID,
+			Allow: int32(model.PathWrite | model.PathDelete), Sub: true},
+		{Path: "/projects/archive", SubjectType: model.ACLSubjectUser, SubjectID: user.ID,
+			Deny: int32(model.PathWrite), Sub: true},
+		{Path: "/secret", SubjectType: model.ACLSubjectUser, SubjectID: user.ID,
+			Allow: int32(model.PathRead)},
+		{Path: "/secret", SubjectType: model.ACLSubjectGroup, SubjectID: team.ID,
+			Deny: int32(model.PathRead)},
+	}
+	for i := range acls {
+		if err := op.CreatePathACL(&acls[i]); err != nil {
+			t.Fatalf("failed to create acl: %+v", err)
+		}
+	}
+	user, err := op.GetUserByName("acl")
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	var tests = []struct {
+		path    string
+		action  model.PathAction
+		allowed bool
+	}{
+		{"/projects/a", model.PathWrite, true},
+		{"/projects/a", model.PathDelete, true},
+		{"/projects/archive/a", model.PathWrite, false},
+		{"/projects/archive/a", model.PathDelete, true},
+		{"/secret", model.PathRead, false},
+		{"/secret/a", model.PathRead, true},
+		{"/other", model.PathWrite, true},
+		{"/other", model.PathRead, true},
+	}
+	for _, tt := range tests {
+		if allowed := op.CanPath(user, nil, tt.path, tt.action, ""); allowed != tt.allowed {
+			t.Errorf("action %d on %s: expect allowed %v, got %v", tt.action, tt.path, tt.allowed, allowed)
+		}
+	}
+	// an allow grants what the permissions lack, but never what the scope of an API token lacks
+	readonly, err = op.GetUserByName("acl-readonly")
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	token := (&model.ApiToken{Permission: readonly.Permission}).Scope(readonly)
+	for _, action := range []model.PathAction{model.PathWrite, model.PathDelete} {
+		if !op.CanPath(readonly, nil, "/projects/a", action, "") {
+			t.Errorf("expect action %d on /projects/a to be granted by the acl", action)
+		}
+		if op.CanPath(readonly, nil, "/other", action, "") {
+			t.Errorf("expect action %d on /other to be denied to a user without the permission", action)
+		}
+		if op.CanPath(token, nil, "/projects/a", action, "") {
+			t.Errorf("expect action %d to be denied to an api token without the permission", action)
+		}
+	}
+	ctx := context.WithValue(context.Background(), conf.UserKey, user)
+	if err = op.CheckPathACL(ctx, "/projects/archive/a", model.PathWrite); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("expect the denied write to fail, got %+v", err)
+	}
+	if err = op.CheckPathACL(ctx, "/other", model.PathWrite); err != nil {
+		t.Errorf("expect an undecided write to pass the fs check, got %+v", err)
+	}
+	if err = op.DeleteGroupById(team.ID); err != nil {
+		t.Fatalf("failed to delete group: %+v", err)
+	}
+	if _, total, _ := op.GetPathACLs(1, 10); total != 2 {
+		t.Errorf("expect the acls of the deleted group to be deleted, got %d left", total)
+	}
+}
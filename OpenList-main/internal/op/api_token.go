@@ -0,0 +1,116 @@
+package op
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// apiTokenCache caches tokens by hash, revoked tokens are removed at once
+var apiTokenCache = cache.NewMemCache(cache.WithShards[*model.ApiToken](2))
+
+// the last used time is written at most once per interval
+const apiTokenTouchInterval = time.Minute
+
+func hashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateApiToken creates a token for the user and returns the plain token, only its hash is stored
+func CreateApiToken(user *model.User, t *model.ApiToken) (string, error) {
+	if t.Name == "" {
+		return "", errors.New("token name is required")
+	}
+	if t.Permission&^user.EffectivePermission() != 0 {
+		return "", errors.WithStack(errs.ExceedPermission)
+	}
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now()) {
+		return "", errors.New("token expiry must be in the future")
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.WithStack(err)
+	}
+	token := model.ApiTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	t.ID = 0
+	t.UserID = user.ID
+	t.TokenHash = hashApiToken(token)
+	t.Hint = token[:len(model.ApiTokenPrefix)+6]
+	t.PathPrefix = utils.FixAndCleanPath(t.PathPrefix)
+	t.LastUsedAt, t.RevokedAt = nil, nil
+	if err := db.CreateApiToken(t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func GetApiTokensByUserId(userId uint, pageIndex, pageSize int) ([]model.ApiToken, int64, error) {
+	return db.GetApiTokensByUserId(userId, pageIndex, pageSize)
+}
+
+// RevokeApiToken revokes a token of the user, the token stops working immediately
+func RevokeApiToken(userId, id uint) error {
+	t, err := db.GetApiTokenById(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.WithStack(errs.ApiTokenNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if t.UserID != userId {
+		return errors.WithStack(errs.PermissionDenied)
+	}
+	if t.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	defer dropCache(ChangeApiToken, t.TokenHash)
+	return db.UpdateApiToken(t)
+}
+
+// GetUserByApiToken returns the owner of an active token, limited to the scope of the token
+func GetUserByApiToken(token string) (*model.User, *model.ApiToken, error) {
+	hash := hashApiToken(token)
+	t, ok := apiTokenCache.Get(hash)
+	if !ok {
+		var err error
+		t, err = db.GetApiTokenByHash(hash)
+		if err != nil {
+			return nil, nil, errors.WithStack(errs.InvalidApiToken)
+		}
+		apiTokenCache.Set(hash, t, cache.WithEx[*model.ApiToken](time.Hour))
+	}
+	if !t.IsActive() {
+		return nil, nil, errors.WithStack(errs.InvalidApiToken)
+	}
+	owner, err := GetUserById(t.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if owner.Disabled {
+		return nil, nil, errors.New("the owner of the token is disabled")
+	}
+	if t.LastUsedAt == nil || time.Since(*t.LastUsedAt) > apiTokenTouchInterval {
+		now := time.Now()
+		if err = db.UpdateApiTokenLastUsed(t.ID, now); err != nil {
+			return nil, nil, err
+		}
+		touched := *t
+		touched.LastUsedAt = &now
+		apiTokenCache.Set(hash, &touched, cache.WithEx[*model.ApiToken](time.Hour))
+		t = &touched
+	}
+	return t.Scope(owner), t, nil
+}
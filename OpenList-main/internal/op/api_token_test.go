@@ -0,0 +1,41 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestApiToken(t *testing.T) {
+	owner := &model.User{Username: "token_owner", BasePath: "/home", Permission: 0b1111}
+	if err := op.CreateUser(owner); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if _, err := op.CreateApiToken(owner, &model.ApiToken{Name: "exceed", Permission: 0b10000}); !errors.Is(err, errs.ExceedPermission) {
+		t.Errorf("expect a permission beyond the owner to be rejected, got %+v", err)
+	}
+	apiToken := &model.ApiToken{Name: "ci", PathPrefix: "ci/../artifacts", Permission: 0b1010}
+	token, err := op.CreateApiToken(owner, apiToken)
+	if err != nil {
+		t.Fatalf("failed to create token: %+v", err)
+	}
+	user, _, err := op.GetUserByApiToken(token)
+	if err != nil {
+		t.Fatalf("failed to authenticate with token: %+v", err)
+	}
+	if user.ID != owner.ID || user.Permission != 0b1010 || user.BasePath != "/home/artifacts" {
+		t.Errorf("expect the token to act as the owner within its scope, got %d %b %s", user.ID, user.Permission, user.BasePath)
+	}
+	if _, _, err = op.GetUserByApiToken(token + "x"); !errors.Is(err, errs.InvalidApiToken) {
+		t.Errorf("expect an unknown token to be rejected, got %+v", err)
+	}
+	if err = op.RevokeApiToken(owner.ID, apiToken.ID); err != nil {
+		t.Fatalf("failed to revoke token: %+v", err)
+	}
+	if _, _, err = op.GetUserByApiToken(token); !errors.Is(err, errs.InvalidApiToken) {
+		t.Errorf("expect a revoked token to be rejected, got %+v", err)
+	}
+}
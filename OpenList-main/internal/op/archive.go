@@ -495,12 +495,26 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 	if err != nil {
 		return errors.WithMessage(err, "failed to get dst dir")
 	}
+	// the extracted size is unknown before decompressing, the archive size is charged
+	// and settled to the extracted objects if the driver returns them
+	charge, err := chargeQuota(ctx, storage, max(srcObj.GetSize(), 0), 1)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			charge.refund()
+		}
+	}()
 
 	switch s := storage.(type) {
 	case driver.ArchiveDecompressResult:
 		var newObjs []model.Obj
 		newObjs, err = s.ArchiveDecompress(ctx, srcObj, dstDir, args)
 		if err == nil {
+			if len(newObjs) > 0 {
+				charge.settleObjs(ctx, storage, dstDirPath, newObjs)
+			}
 			if len(newObjs) > 0 {
 				for _, newObj := range newObjs {
 					addCacheObj(storage, dstDirPath, model.WrapObjName(newObj))
@@ -515,6 +529,7 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 			DeleteCache(storage, dstDirPath)
 		}
 	default:
+		charge.refund()
 		return errs.NotImplement
 	}
 	return errors.WithStack(err)
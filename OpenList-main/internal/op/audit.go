@@ -0,0 +1,96 @@
+package op
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// auditExportBatch is the number of events read at once while exporting
+const auditExportBatch = 500
+
+func auditEnabled() bool {
+	item, err := GetSettingItemByKey(conf.AuditLogEnabled)
+	return err != nil || item.Value != "false" && item.Value != "0"
+}
+
+// RecordAudit records an action of the user in ctx, err is the reason if the action failed.
+// The ip and the protocol are taken from ctx, actions without a protocol are run by OpenList itself.
+// Nothing is recorded for the parts of an action recorded already, which are marked by conf.NoAuditKey in ctx.
+func RecordAudit(ctx context.Context, action, target, detail string, err error) {
+	if ctx.Value(conf.NoAuditKey) != nil {
+		return
+	}
+	e := &model.AuditEvent{
+		Action: action,
+		Target: target,
+		Detail: detail,
+	}
+	if user, ok := ctx.Value(conf.UserKey).(*model.User); ok && user != nil {
+		e.UserID = user.ID
+		e.Username = user.Username
+	}
+	e.IP, _ = ctx.Value(conf.ClientIPKey).(string)
+	e.Protocol, _ = ctx.Value(conf.ProtocolKey).(string)
+	if e.Protocol == "" {
+		e.Protocol = model.ProtocolInternal
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	RecordAuditEvent(e)
+}
+
+// RecordLogin records a sign-in attempt through WebDAV, FTP or SFTP, the way of signing in is kept as the detail
+func RecordLogin(protocol, ip, username, method string, err error) {
+	e := &model.AuditEvent{
+		Username: username,
+		IP:       ip,
+		Protocol: protocol,
+		Action:   model.AuditLogin,
+		Detail:   method,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else if user, err := GetUserByName(username); err == nil {
+		e.UserID = user.ID
+	}
+	RecordAuditEvent(e)
+}
+
+// RecordAuditEvent stores the event unless the audit log is disabled,
+// a failure is only logged so that it never fails the action itself
+func RecordAuditEvent(e *model.AuditEvent) {
+	if !auditEnabled() {
+		return
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.Success = e.Error == ""
+	if err := db.CreateAuditEvent(e); err != nil {
+		log.Errorf("failed record audit event %s on %s: %+v", e.Action, e.Target, err)
+	}
+}
+
+func GetAuditEvents(f model.AuditFilter, pageIndex, pageSize int) ([]model.AuditEvent, int64, error) {
+	return db.GetAuditEvents(f, pageIndex, pageSize)
+}
+
+// WalkAuditEvents calls fn with the matched events in batches, oldest first
+func WalkAuditEvents(f model.AuditFilter, fn func([]model.AuditEvent) error) error {
+	return db.WalkAuditEvents(f, auditExportBatch, fn)
+}
+
+// DeleteExpiredAuditEvents removes the events older than the retention setting
+func DeleteExpiredAuditEvents() (int64, error) {
+	days := getSettingInt(conf.AuditLogRetention, 90)
+	if days <= 0 {
+		return 0, nil
+	}
+	return db.DeleteAuditEventsBefore(time.Now().AddDate(0, 0, -days))
+}
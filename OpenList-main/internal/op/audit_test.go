@@ -0,0 +1,62 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestAudit(t *testing.T) {
+	user := &model.User{ID: 42, Username: "auditor"}
+	ctx := context.WithValue(context.Background(), conf.UserKey, user)
+	ctx = context.WithValue(ctx, conf.ProtocolKey, model.ProtocolDav)
+	ctx = context.WithValue(ctx, conf.ClientIPKey, "10.0.0.1")
+	op.RecordAudit(ctx, model.AuditMkdir, "/audit/a", "", nil)
+	op.RecordAudit(ctx, model.AuditRemove, "/audit/a", "", errors.New("permission denied"))
+	op.RecordAudit(context.Background(), model.AuditUpload, "/audit/b", "", nil)
+	op.RecordAuditEvent(&model.AuditEvent{
+		CreatedAt: time.Now().AddDate(0, 0, -100),
+		Username:  "auditor",
+		Action:    model.AuditLogin,
+	})
+
+	events, total, err := op.GetAuditEvents(model.AuditFilter{Username: "auditor", Action: "fs"}, 1, 10)
+	if err != nil {
+		t.Fatalf("failed to get audit events: %+v", err)
+	}
+	if total != 2 || len(events) != 2 {
+		t.Fatalf("expect 2 fs events of the user, got %d", total)
+	}
+	// the newest first
+	e := events[0]
+	if e.Action != model.AuditRemove || e.Success || e.Error != "permission denied" {
+		t.Errorf("expect the failed removal first, got %+v", e)
+	}
+	if e.UserID != user.ID || e.IP != "10.0.0.1" || e.Protocol != model.ProtocolDav {
+		t.Errorf("expect the user, ip and protocol to be taken from ctx, got %+v", e)
+	}
+	failed := false
+	if _, total, _ = op.GetAuditEvents(model.AuditFilter{Target: "/audit/", Success: &failed}, 1, 10); total != 1 {
+		t.Errorf("expect 1 failed event under /audit/, got %d", total)
+	}
+	if events, _, _ = op.GetAuditEvents(model.AuditFilter{Target: "/audit/b"}, 1, 10); len(events) != 1 || events[0].Protocol != model.ProtocolInternal {
+		t.Errorf("expect the event without a protocol to be internal, got %+v", events)
+	}
+
+	if n, err := op.DeleteExpiredAuditEvents(); err != nil || n != 1 {
+		t.Errorf("expect the event past the retention to be deleted, got %d, %+v", n, err)
+	}
+	var walked int
+	err = op.WalkAuditEvents(model.AuditFilter{Username: "auditor"}, func(events []model.AuditEvent) error {
+		walked += len(events)
+		return nil
+	})
+	if err != nil || walked != 2 {
+		t.Errorf("expect 2 events left, got %d, %+v", walked, err)
+	}
+}
@@ -0,0 +1,261 @@
+package op
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cachestore"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheStore is where the listings and links are kept if configured, see SetCacheStore
+var cacheStore cachestore.Store
+
+// SetCacheStore moves the listing and link caches to the store, nil keeps them in memory.
+// It should be called before the storages are loaded.
+func SetCacheStore(s cachestore.Store) {
+	cacheStore = s
+}
+
+// storedCache is a cache kept in the cache store when one is configured.
+// The values the store can't hold, such as the objects of the drivers with their own types
+// which are not registered with RegisterStorableObj, stay in memory and so are neither persisted nor shared.
+type storedCache[T any] struct {
+	mem    cache.ICache[T]
+	prefix string
+	encode func(T) ([]byte, bool)
+	decode func([]byte) (T, error)
+}
+
+func (c *storedCache[T]) Get(key string) (T, bool) {
+	if v, ok := c.mem.Get(key); ok || cacheStore == nil {
+		return v, ok
+	}
+	var zero T
+	b, ok, err := cacheStore.Get(c.prefix + key)
+	if err != nil {
+		log.Warnf("failed get %s from the cache store: %+v", key, err)
+	}
+	if !ok {
+		return zero, false
+	}
+	v, err := c.decode(b)
+	if err != nil {
+		log.Warnf("failed decode %s from the cache store: %+v", key, err)
+		return zero, false
+	}
+	return v, true
+}
+
+func (c *storedCache[T]) Set(key string, v T, ttl time.Duration) {
+	if cacheStore != nil {
+		if b, ok := c.encode(v); ok {
+			c.mem.Del(key)
+			// like in memory, a value without a ttl expires at once
+			if ttl <= 0 {
+				c.delStored(key)
+				return
+			}
+			if err := cacheStore.Set(c.prefix+key, b, ttl); err != nil {
+				log.Warnf("failed set %s in the cache store: %+v", key, err)
+			}
+			return
+		}
+		// the stale value in the store would hide the one in memory
+		c.delStored(key)
+	}
+	c.mem.Set(key, v, cache.WithEx[T](ttl))
+}
+
+func (c *storedCache[T]) Del(key string) {
+	c.mem.Del(key)
+	if cacheStore != nil {
+		c.delStored(key)
+	}
+}
+
+func (c *storedCache[T]) delStored(key string) {
+	if err := cacheStore.Del(c.prefix + key); err != nil {
+		log.Warnf("failed delete %s from the cache store: %+v", key, err)
+	}
+}
+
+var (
+	// the names of the types of the drivers' own objects which can be stored, and how they are restored
+	storableObjNames = make(map[reflect.Type]string)
+	storableObjTypes = make(map[string]func([]byte) (model.Obj, error))
+	// the types of the objects found not storable, each is logged once
+	unstorableObjs sync.Map
+)
+
+// RegisterStorableObj lets the listings with the objects of T, a type of a driver, be kept in the cache store.
+// The objects are stored as JSON, so the driver must need nothing but their exported fields to link or list them.
+// It should be called in the init of the driver, the name is stored with the objects and must not change.
+func RegisterStorableObj[T model.Obj](name string) {
+	storableObjNames[reflect.TypeFor[T]()] = name
+	storableObjTypes[name] = func(b []byte) (model.Obj, error) {
+		var obj T
+		err := json.Unmarshal(b, &obj)
+		return obj, err
+	}
+}
+
+// storedObj is an object of the model types, or of a type registered with RegisterStorableObj.
+// Objects of the other types of the drivers may carry unexported state they need to link or list.
+type storedObj struct {
+	ID        string    `json:"id,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Modified  time.Time `json:"modified"`
+	Ctime     time.Time `json:"ctime"`
+	IsFolder  bool      `json:"is_folder"`
+	Hash      string    `json:"hash,omitempty"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+	Url       string    `json:"url,omitempty"`
+	// the name was mapped by model.WrapObjName
+	Wrapped bool `json:"wrapped,omitempty"`
+	// the name the type of a driver's own object is registered with, and the object itself
+	Type string          `json:"type,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func encodeObjs(objs []model.Obj) ([]byte, bool) {
+	stored := make([]storedObj, len(objs))
+	for i, obj := range objs {
+		s := &stored[i]
+		if w, ok := obj.(*model.ObjWrapName); ok {
+			s.Wrapped = true
+			obj = w.Obj
+		}
+		var o *model.Object
+		switch v := obj.(type) {
+		case *model.Object:
+			o = v
+		case *model.ObjThumb:
+			o, s.Thumbnail = &v.Object, v.Thumbnail.Thumbnail
+		case *model.ObjectURL:
+			o, s.Url = &v.Object, v.Url.Url
+		case *model.ObjThumbURL:
+			o, s.Thumbnail, s.Url = &v.Object, v.Thumbnail.Thumbnail, v.Url.Url
+		default:
+			name, ok := storableObjNames[reflect.TypeOf(obj)]
+			if !ok {
+				if _, logged := unstorableObjs.LoadOrStore(reflect.TypeOf(obj), struct{}{}); !logged {
+					log.Infof("the objects of type %T can't be stored, the listings with them are kept in memory only", obj)
+				}
+				return nil, false
+			}
+			data, err := json.Marshal(obj)
+			if err != nil {
+				return nil, false
+			}
+			s.Type, s.Data = name, data
+			continue
+		}
+		s.ID, s.Path, s.Name, s.Size = o.ID, o.Path, o.Name, o.Size
+		s.Modified, s.Ctime, s.IsFolder = o.Modified, o.Ctime, o.IsFolder
+		if len(o.HashInfo.Export()) > 0 {
+			s.Hash = o.HashInfo.String()
+		}
+	}
+	b, err := json.Marshal(stored)
+	return b, err == nil
+}
+
+func decodeObjs(b []byte) ([]model.Obj, error) {
+	var stored []storedObj
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	objs := make([]model.Obj, len(stored))
+	for i, s := range stored {
+		if s.Type != "" {
+			decode, ok := storableObjTypes[s.Type]
+			if !ok {
+				return nil, errors.Errorf("unknown object type %s", s.Type)
+			}
+			obj, err := decode(s.Data)
+			if err != nil {
+				return nil, err
+			}
+			if s.Wrapped {
+				obj = model.WrapObjName(obj)
+			}
+			objs[i] = obj
+			continue
+		}
+		o := model.Object{
+			ID:       s.ID,
+			Path:     s.Path,
+			Name:     s.Name,
+			Size:     s.Size,
+			Modified: s.Modified,
+			Ctime:    s.Ctime,
+			IsFolder: s.IsFolder,
+		}
+		if s.Hash != "" {
+			o.HashInfo = utils.FromString(s.Hash)
+		}
+		var obj model.Obj
+		switch {
+		case s.Thumbnail != "" && s.Url != "":
+			obj = &model.ObjThumbURL{Object: o, Thumbnail: model.Thumbnail{Thumbnail: s.Thumbnail}, Url: model.Url{Url: s.Url}}
+		case s.Thumbnail != "":
+			obj = &model.ObjThumb{Object: o, Thumbnail: model.Thumbnail{Thumbnail: s.Thumbnail}}
+		case s.Url != "":
+			obj = &model.ObjectURL{Object: o, Url: model.Url{Url: s.Url}}
+		default:
+			obj = &o
+		}
+		if s.Wrapped {
+			obj = model.WrapObjName(obj)
+		}
+		objs[i] = obj
+	}
+	return objs, nil
+}
+
+// storedLink is a link to an url, the links served by a reader or a local file can't be stored
+type storedLink struct {
+	URL         string        `json:"url"`
+	Header      http.Header   `json:"header,omitempty"`
+	Expiration  time.Duration `json:"expiration"`
+	Concurrency int           `json:"concurrency,omitempty"`
+	PartSize    int           `json:"part_size,omitempty"`
+}
+
+func encodeLink(link *model.Link) ([]byte, bool) {
+	if link.URL == "" || link.RangeReader != nil || link.MFile != nil || link.Expiration == nil {
+		return nil, false
+	}
+	b, err := json.Marshal(storedLink{
+		URL:         link.URL,
+		Header:      link.Header,
+		Expiration:  *link.Expiration,
+		Concurrency: link.Concurrency,
+		PartSize:    link.PartSize,
+	})
+	return b, err == nil
+}
+
+func decodeLink(b []byte) (*model.Link, error) {
+	var s storedLink
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &model.Link{
+		URL:         s.URL,
+		Header:      s.Header,
+		Expiration:  &s.Expiration,
+		Concurrency: s.Concurrency,
+		PartSize:    s.PartSize,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package op_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cachestore"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestStoredListCache(t *testing.T) {
+	store, err := cachestore.NewBolt(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("failed to open the cache store: %+v", err)
+	}
+	defer store.Close()
+	op.SetCacheStore(store)
+	defer op.SetCacheStore(nil)
+
+	ctx := context.Background()
+	// the virtual driver makes up new files on every listing
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/stored_cache",
+		CacheExpiration: 30,
+		Addition:        `{"num_file":3,"num_folder":2,"max_file_size":1024,"min_file_size":1}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	defer op.DeleteStorageById(ctx, id)
+	storage, err := op.GetStorageByMountPath("/stored_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, err := op.List(ctx, storage, "/", model.ListArgs{})
+	if err != nil {
+		t.Fatalf("failed to list: %+v", err)
+	}
+	if _, ok, _ := store.Get("list:/stored_cache"); !ok {
+		t.Fatalf("expect the listing to be in the cache store")
+	}
+	cached, err := op.List(ctx, storage, "/", model.ListArgs{})
+	if err != nil {
+		t.Fatalf("failed to list: %+v", err)
+	}
+	if objNames(cached) != objNames(listed) {
+		t.Errorf("expect the listing to be served from the store, got %s, want %s", objNames(cached), objNames(listed))
+	}
+	if _, ok := model.UnwrapObj(cached[0]).(*model.Object); !ok {
+		t.Errorf("expect the type of the objects to be restored, got %T", model.UnwrapObj(cached[0]))
+	}
+
+	// the stored listing is updated in place by the mutations
+	if err = op.MakeDir(ctx, storage, "/new"); err != nil {
+		t.Fatalf("failed to make dir: %+v", err)
+	}
+	if cached, _ = op.List(ctx, storage, "/", model.ListArgs{}); len(cached) != len(listed)+1 {
+		t.Errorf("expect the new dir in the stored listing, got %s", objNames(cached))
+	}
+
+	op.ClearCache(storage, "/")
+	if _, ok, _ := store.Get("list:/stored_cache"); ok {
+		t.Errorf("expect the listing to be removed from the cache store")
+	}
+	if cached, _ = op.List(ctx, storage, "/", model.ListArgs{}); objNames(cached) == objNames(listed) {
+		t.Errorf("expect a new listing after the cache is cleared")
+	}
+}
+
+func objNames(objs []model.Obj) string {
+	var s string
+	for _, obj := range objs {
+		s += obj.GetName() + ","
+	}
+	return s
+}
@@ -1,58 +1,149 @@
 package op
 
 import (
-"fmt"
-"time"
-
-"github.com/OpenListTeam/OpenList/v4/internal/db"
-"github.com/OpenListTeam/OpenList/v4/internal/errs"
-"github.com/OpenListTeam/OpenList/v4/internal/model"
-"github.com/pkg/errors"
-"gorm.io/gorm"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 // --- Certificate Service ---
 
 var GetCertificateByID = db.GetCertificateByID
 var GetCertificates = db.GetCertificates
-var CreateCertificate = db.CreateCertificate
+
+// certificateEvent 构造由 actor 发起的证书事件
+func certificateEvent(action model.CertificateAction, actor *model.User, ip, reason string) model.CertificateEvent {
+	return model.CertificateEvent{
+		Action:  action,
+		Actor:   actor.Username,
+		ActorID: actor.ID,
+		IP:      ip,
+		Reason:  reason,
+	}
+}
+
+// CreateCertificate 管理员手动导入证书
+func CreateCertificate(cert *model.Certificate, actor *model.User, ip string) error {
+	return db.CreateCertificate(cert, certificateEvent(model.CertificateActionCreate, actor, ip, ""))
+}
+
+// GetCertificateHistory 获取证书从申请到吊销的全部状态变更记录
+func GetCertificateHistory(id uint) ([]model.CertificateEvent, error) {
+	cert, err := db.GetCertificateByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCertificateHistory(cert)
+}
 
 // GetCertificateForTenant 是租户端调用的核心服务
 func GetCertificateForTenant(ownerID uint) (*model.Certificate, error) {
-cert, err := db.GetCertificateByOwnerID(ownerID)
-if err != nil {
-// 如果错误不是 "记录未找到"，则是一个真正的数据库错误
-if !errors.Is(err, gorm.ErrRecordNotFound) {
-return nil, err
+	cert, err := db.GetCertificateByOwnerID(ownerID)
+	if err != nil {
+		// 如果错误不是 "记录未找到"，则是一个真正的数据库错误
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		// 如果是 "记录未找到"，是正常情况，说明租户没有证书
+		return nil, nil
+	}
+	return cert, nil
 }
-// 如果是 "记录未找到"，是正常情况，说明租户没有证书
-return nil, nil
-}
-return cert, nil
+
+// GetUserByClientCertificate 将已验证的TLS客户端证书映射到其所有者
+// 证书必须在证书表中存在、内容一致、未吊销且未过期，所有者须通过与令牌认证相同的检查
+func GetUserByClientCertificate(x509Cert *x509.Certificate) (*model.User, error) {
+	cert, err := db.GetCertificateBySerial(ca.SerialToHex(x509Cert.SerialNumber))
+	if err != nil {
+		return nil, errors.WithMessage(err, "unknown client certificate")
+	}
+	if cert.DeletedAt.Valid || !cert.IsValid() || cert.IsExpired() {
+		return nil, errors.Errorf("client certificate is %s", cert.Status)
+	}
+	stored, err := ca.ParseCertificatePEM([]byte(cert.Content))
+	if err != nil || !stored.Equal(x509Cert) {
+		return nil, errors.New("client certificate does not match the issued certificate")
+	}
+	user, err := GetUserById(cert.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkCertificateOwner(user); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
-func UpdateCertificateDetails(id uint, name string, expirationDate time.Time) (*model.Certificate, error) {
-cert, err := db.GetCertificateByID(id)
-if err != nil {
-return nil, err
+// checkCertificateOwner 访客和无效角色不能使用证书，禁用的用户以及不存在或已禁用租户的用户被拒绝
+func checkCertificateOwner(user *model.User) error {
+	if user.IsGuest() || user.Role != model.ADMIN && user.Role != model.GENERAL && user.Role != model.TENANT {
+		return errors.New("user role is not permitted to use a client certificate")
+	}
+	if user.Disabled {
+		return errors.New("the owner of the client certificate is disabled")
+	}
+	if user.TenantID == 0 {
+		return nil
+	}
+	tenant, err := GetTenantById(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if tenant.Disabled {
+		return errors.WithStack(errs.TenantDisabled)
+	}
+	return nil
 }
-cert.Name = name
-cert.ExpirationDate = expirationDate
-err = db.UpdateCertificate(cert)
-return cert, err
+
+// UpdateCertificateDetails 修改证书名称和过期日期，并记录事件
+func UpdateCertificateDetails(id uint, name string, expirationDate time.Time, actor *model.User, ip string) (*model.Certificate, error) {
+	return db.UpdateCertificateDetails(id, name, expirationDate, certificateEvent(model.CertificateActionUpdate, actor, ip, ""))
 }
 
-func RevokeCertificate(id uint) error {
-cert, err := db.GetCertificateByID(id)
-if err != nil {
-return err
+func RevokeCertificate(id uint, actor *model.User, ip, reason string) error {
+	cert, err := db.GetCertificateByID(id)
+	if err != nil {
+		return err
+	}
+	if cert.Status == model.CertificateStatusRevoked || cert.Status == model.CertificateStatusSuperseded {
+		return errs.CertificateAlreadyRevoked
+	}
+	now := time.Now()
+	cert.RevokedAt = &now
+	event := certificateEvent(model.CertificateActionRevoke, actor, ip, reason)
+	if err = db.ChangeCertificateStatus(cert, model.CertificateStatusRevoked, event); err != nil {
+		return err
+	}
+	refreshCRL()
+	return nil
 }
-cert.Status = model.CertificateStatusRevoked
-return db.UpdateCertificate(cert)
+
+func DeleteCertificate(id uint, actor *model.User, ip string) error {
+	if err := db.DeleteCertificate(id, certificateEvent(model.CertificateActionDelete, actor, ip, "")); err != nil {
+		return err
+	}
+	// 已删除的证书同样不应再被信任
+	refreshCRL()
+	return nil
 }
 
-func DeleteCertificate(id uint) error {
-return db.DeleteCertificate(id)
+// UpdateCertificateLifecycle 根据过期时间推进证书状态：有效 -> 即将过期 -> 已过期
+func UpdateCertificateLifecycle(expiringDays int) (expiring, expired int64, err error) {
+	now := time.Now()
+	expired, err = db.MarkCertificatesExpired(now)
+	if err != nil {
+		return 0, 0, err
+	}
+	expiring, err = db.MarkCertificatesExpiring(now.AddDate(0, 0, expiringDays))
+	return expiring, expired, err
 }
 
 // --- CertificateRequest Service ---
@@ -60,99 +151,242 @@ return db.DeleteCertificate(id)
 var GetCertificateRequests = db.GetCertificateRequests
 var GetCertificateRequestByID = db.GetCertificateRequestByID
 var GetTenantCertificateRequests = db.GetCertificateRequestsByUserID
-var CreateCertificateRequest = db.CreateCertificateRequest
 
-// CreateTenantCertificateRequest 租户申请证书的业务逻辑
-func CreateTenantCertificateRequest(user *model.User, reqType model.CertificateType, reason string) (*model.CertificateRequest, error) {
-// 1. 检查租户是否已经有了一个有效的证书
-existingCert, err := db.GetCertificateByOwnerID(user.ID)
-if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-return nil, errors.Wrap(err, "failed to check existing certificate")
-}
-if existingCert != nil && (existingCert.Status == model.CertificateStatusValid || existingCert.Status == model.CertificateStatusExpiring) {
-return nil, errs.CertificateAlreadyExists
+// CreateCertificateRequest 校验并保存证书申请
+func CreateCertificateRequest(req *model.CertificateRequest, actor *model.User, ip string) error {
+	if err := validateCertificateRequest(req); err != nil {
+		return err
+	}
+	return db.CreateCertificateRequest(req, certificateEvent(model.CertificateActionRequest, actor, ip, req.Reason))
 }
 
-// 2. 检查租户是否已经有一个正在处理的申请
-_, err = db.GetPendingCertificateRequestByUserID(user.ID)
-if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-return nil, errors.Wrap(err, "failed to check pending request")
-}
-if !errors.Is(err, gorm.ErrRecordNotFound) {
-return nil, errs.CertificateRequestPending
+// validateCertificateRequest 节点证书必须附带CSR，附带的CSR需通过签名、算法和密钥长度校验
+func validateCertificateRequest(req *model.CertificateRequest) error {
+	if req.CSR == "" {
+		if req.Type == model.CertificateTypeNode {
+			return errs.CertificateCSRRequired
+		}
+		return nil
+	}
+	_, err := ca.ParseCSR(req.CSR)
+	return err
 }
 
-// 3. 创建新的申请
-request := &model.CertificateRequest{
-UserName: user.Username,
-UserID:   user.ID,
-Type:     reqType,
-Status:   model.CertificateStatusPending,
-Reason:   reason,
-}
+// CreateTenantCertificateRequest 租户申请证书的业务逻辑
+func CreateTenantCertificateRequest(user *model.User, ip string, reqType model.CertificateType, reason, csr string) (*model.CertificateRequest, error) {
+	// 1. 检查租户是否已经有了一个有效的证书
+	existingCert, err := db.GetCertificateByOwnerID(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(err, "failed to check existing certificate")
+	}
+	if existingCert != nil && (existingCert.Status == model.CertificateStatusValid || existingCert.Status == model.CertificateStatusExpiring) {
+		return nil, errs.CertificateAlreadyExists
+	}
 
-if err := db.CreateCertificateRequest(request); err != nil {
-return nil, err
-}
-return request, nil
+	// 2. 检查租户是否已经有一个正在处理的申请
+	if err = checkNoPendingRequest(user.ID); err != nil {
+		return nil, err
+	}
+
+	// 3. 创建新的申请
+	request := &model.CertificateRequest{
+		UserName: user.Username,
+		UserID:   user.ID,
+		Type:     reqType,
+		Status:   model.CertificateStatusPending,
+		Reason:   reason,
+		CSR:      csr,
+	}
+
+	if err := CreateCertificateRequest(request, user, ip); err != nil {
+		return nil, err
+	}
+	return request, nil
 }
 
-// ApproveAndCreateCertificate 将批准和创建证书合并为一个事务性操作
-func ApproveAndCreateCertificate(reqID uint, adminUser *model.User) (*model.Certificate, error) {
-// 1. 获取申请记录
-req, err := db.GetCertificateRequestByID(reqID)
-if err != nil {
-return nil, err
+// CreateTenantRenewalRequest 租户申请续期其最近的证书，批准后会签发新密钥的证书并取代旧证书
+func CreateTenantRenewalRequest(user *model.User, ip, reason, csr string) (*model.CertificateRequest, error) {
+	cert, err := db.GetLatestCertificateByOwnerID(user.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errs.NewErr(errs.CertificateNotRenewable, "no certificate to renew")
+		}
+		return nil, errors.Wrap(err, "failed to get certificate to renew")
+	}
+	if !cert.IsRenewable() {
+		return nil, errs.NewErr(errs.CertificateNotRenewable, "certificate is %s", cert.Status)
+	}
+	if err = checkNoPendingRequest(user.ID); err != nil {
+		return nil, err
+	}
+	request := &model.CertificateRequest{
+		UserName:  user.Username,
+		UserID:    user.ID,
+		Type:      cert.Type,
+		Status:    model.CertificateStatusPending,
+		Reason:    reason,
+		CSR:       csr,
+		RenewalOf: cert.ID,
+	}
+	if err := CreateCertificateRequest(request, user, ip); err != nil {
+		return nil, err
+	}
+	return request, nil
 }
-if req.Status != model.CertificateStatusPending {
-return nil, errs.CertificateRequestNotPending
+
+func checkNoPendingRequest(userID uint) error {
+	_, err := db.GetPendingCertificateRequestByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Wrap(err, "failed to check pending request")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errs.CertificateRequestPending
+	}
+	return nil
 }
 
-// 2. 更新申请状态
-now := time.Now()
-req.Status = model.CertificateStatusValid
-req.ApprovedBy = adminUser.Username
-req.ApprovedAt = &now
-if err := db.UpdateCertificateRequest(req); err != nil {
-return nil, err
+// getRenewalPredecessor 获取续期申请对应的旧证书并校验其归属和状态
+func getRenewalPredecessor(req *model.CertificateRequest) (*model.Certificate, error) {
+	cert, err := db.GetCertificateByID(req.RenewalOf)
+	if err != nil {
+		return nil, err
+	}
+	if cert.OwnerID != req.UserID {
+		return nil, errs.NewErr(errs.CertificateNotRenewable, "certificate %d is not owned by %s", cert.ID, req.UserName)
+	}
+	if !cert.IsRenewable() {
+		return nil, errs.NewErr(errs.CertificateNotRenewable, "certificate is %s", cert.Status)
+	}
+	return cert, nil
 }
 
-// 3. 创建新证书
-certName := fmt.Sprintf("%s-%s-%s", req.UserName, req.Type, now.Format("20060102"))
-cert := &model.Certificate{
-Name:           certName,
-Type:           req.Type,
-Status:         model.CertificateStatusValid,
-Owner:          req.UserName,
-OwnerID:        req.UserID,
-IssuedDate:     now,
-ExpirationDate: now.AddDate(1, 0, 0), // 默认有效期1年
-Content:        fmt.Sprintf("-----BEGIN CERTIFICATE-----\n%s\n-----END CERTIFICATE-----", "This is a dummy certificate content."), // TODO: Replace with actual certificate generation logic
+// ApproveAndCreateCertificate 将批准和创建证书合并为一个事务性操作，并记录审计事件
+func ApproveAndCreateCertificate(reqID uint, adminUser *model.User, ip string) (*model.Certificate, error) {
+	// 1. 获取申请记录
+	req, err := db.GetCertificateRequestByID(reqID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != model.CertificateStatusPending {
+		return nil, errs.CertificateRequestNotPending
+	}
+
+	// 续期申请需要校验旧证书，并且必须更换密钥
+	var predecessor *model.Certificate
+	if req.IsRenewal() {
+		predecessor, err = getRenewalPredecessor(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	// 2. 由内置CA签发新证书，签发失败时不改变申请状态
+	// 附带CSR时只签发CSR中的公钥，私钥不经过服务器
+	issueReq := &ca.IssueReq{
+		Type:      req.Type,
+		Username:  req.UserName,
+		UserID:    req.UserID,
+		NotBefore: now,
+		NotAfter:  now.AddDate(1, 0, 0), // 默认有效期1年
+	}
+	var issued *ca.Issued
+	if req.CSR != "" {
+		csr, err := ca.ParseCSR(req.CSR)
+		if err != nil {
+			return nil, err
+		}
+		if predecessor != nil && samePublicKey(predecessor, csr.PublicKey) {
+			return nil, errs.NewErr(errs.InvalidCSR, "renewal must use a new key")
+		}
+		issued, err = ca.Sign(issueReq, csr.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		issued, err = ca.Issue(issueReq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 在一个事务中更新申请状态、保存证书，续期时旧证书被新证书取代
+	req.Status = model.CertificateStatusValid
+	req.ApprovedBy = adminUser.Username
+	req.ApprovedAt = &now
+	certName := fmt.Sprintf("%s-%s-%s", req.UserName, req.Type, now.Format("20060102"))
+	cert := &model.Certificate{
+		Name:           certName,
+		Type:           req.Type,
+		Status:         model.CertificateStatusValid,
+		Owner:          req.UserName,
+		OwnerID:        req.UserID,
+		SerialNumber:   issued.SerialHex(),
+		IssuedDate:     issued.Cert.NotBefore,
+		ExpirationDate: issued.Cert.NotAfter,
+		Content:        string(issued.CertPEM),
+		PrivateKey:     string(issued.KeyPEM),
+		RequestID:      req.ID,
+	}
+	if predecessor != nil {
+		cert.PredecessorID = predecessor.ID
+		predecessor.RevokedAt = &now
+	}
+	event := certificateEvent(model.CertificateActionApprove, adminUser, ip, "")
+	if err := db.ApproveCertificateRequest(req, cert, predecessor, event); err != nil {
+		return nil, err
+	}
+
+	// 4. 被取代的旧证书需要写入CRL
+	if predecessor != nil {
+		refreshCRL()
+	}
+	return cert, nil
 }
 
-if err := db.CreateCertificate(cert); err != nil {
-// 如果创建证书失败，最好能回滚申请状态的变更（需要事务支持）
-return nil, err
+func samePublicKey(cert *model.Certificate, pub crypto.PublicKey) bool {
+	c, err := ca.ParseCertificatePEM([]byte(cert.Content))
+	if err != nil {
+		return false
+	}
+	k, ok := c.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(pub)
 }
-return cert, nil
+
+// TakeCertificatePrivateKey 取出证书私钥并从数据库中清除，私钥只能被下载一次。
+// 只清除私钥列，不覆盖加载证书后发生的状态变更；并发下载时只有清除成功的一方能拿到私钥
+func TakeCertificatePrivateKey(cert *model.Certificate) (string, error) {
+	if !cert.HasPrivateKey() {
+		return "", nil
+	}
+	taken, err := db.ClearCertificatePrivateKey(cert.ID)
+	if err != nil || !taken {
+		return "", err
+	}
+	key := cert.PrivateKey
+	cert.PrivateKey = ""
+	return key, nil
 }
 
 // RejectCertificateRequest 拒绝证书申请
-func RejectCertificateRequest(reqID uint, adminUser *model.User, reason string) (*model.CertificateRequest, error) {
-req, err := db.GetCertificateRequestByID(reqID)
-if err != nil {
-return nil, err
-}
-if req.Status != model.CertificateStatusPending {
-return nil, errs.CertificateRequestNotPending
-}
+func RejectCertificateRequest(reqID uint, adminUser *model.User, ip, reason string) (*model.CertificateRequest, error) {
+	req, err := db.GetCertificateRequestByID(reqID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != model.CertificateStatusPending {
+		return nil, errs.CertificateRequestNotPending
+	}
 
-now := time.Now()
-req.Status = model.CertificateStatusRejected
-req.RejectedBy = adminUser.Username
-req.RejectedAt = &now
-req.RejectedReason = reason
+	now := time.Now()
+	req.Status = model.CertificateStatusRejected
+	req.RejectedBy = adminUser.Username
+	req.RejectedAt = &now
+	req.RejectedReason = reason
 
-err = db.UpdateCertificateRequest(req)
-return req, err
+	event := certificateEvent(model.CertificateActionReject, adminUser, ip, reason)
+	if err = db.RejectCertificateRequest(req, event); err != nil {
+		return nil, err
+	}
+	return req, nil
 }
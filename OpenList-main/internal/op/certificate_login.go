@@ -0,0 +1,106 @@
+package op
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+// 证书绑定的租户登录：服务端签发一次性挑战，客户端用租户证书的私钥对挑战签名，
+// 服务端用证书表中登记的证书内容验证签名，并确认证书未吊销、未过期且属于登录用户
+
+const CertLoginChallengeTTL = 2 * time.Minute
+
+// certLoginChallenges nonce -> 用户名，每个挑战只能使用一次
+var certLoginChallenges = cache.NewMemCache(cache.WithShards[string](2))
+
+// CertificateLoginMessage 客户端需要签名的内容，包含用户名以防挑战被用于其他用户
+func CertificateLoginMessage(username, nonce string) []byte {
+	return []byte("openlist-tenant-login\n" + username + "\n" + nonce)
+}
+
+// NewCertificateLoginChallenge 为用户签发登录挑战，不论用户是否存在都会签发，避免暴露用户名
+func NewCertificateLoginChallenge(username string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.WithStack(err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+	certLoginChallenges.Set(nonce, username, cache.WithEx[string](CertLoginChallengeTTL))
+	return nonce, nil
+}
+
+// CheckTenantCertificateLogin 按用户所属租户的证书登录模式检查证书持有证明，certPEM 为空表示未提交证明
+func CheckTenantCertificateLogin(user *model.User, certPEM, nonce, signature string) error {
+	mode := model.TenantCertLoginDisabled
+	if user.TenantID != 0 {
+		tenant, err := GetTenantById(user.TenantID)
+		if err != nil {
+			return err
+		}
+		mode = tenant.CertLoginMode()
+	}
+	switch {
+	case mode == model.TenantCertLoginDisabled:
+		return nil
+	case certPEM == "" && mode == model.TenantCertLoginRequired:
+		return errors.WithStack(errs.CertificateProofRequired)
+	case certPEM == "":
+		return nil
+	}
+	return verifyCertificateProof(user, certPEM, nonce, signature)
+}
+
+// CheckCertificateLoginRequired 要求证书登录的租户用户只能通过 TenantLogin 提交证书持有证明登录，
+// 密码、SSO、WebAuthn、LDAP 及 WebDAV/FTP/SFTP 等其他登录方式都会被拒绝
+func CheckCertificateLoginRequired(user *model.User) error {
+	return CheckTenantCertificateLogin(user, "", "", "")
+}
+
+func verifyCertificateProof(user *model.User, certPEM, nonce, signature string) error {
+	username, ok := certLoginChallenges.GetDel(nonce)
+	if !ok || username != user.Username {
+		return errs.NewErr(errs.InvalidCertificateProof, "unknown or expired challenge")
+	}
+	x509Cert, err := ca.ParseCertificatePEM([]byte(certPEM))
+	if err != nil {
+		return errs.NewErr(errs.InvalidCertificateProof, "invalid certificate: %v", err)
+	}
+	// 与TLS客户端证书认证使用相同的检查：内容与证书表一致，且未吊销、未过期
+	owner, err := GetUserByClientCertificate(x509Cert)
+	if err != nil {
+		return errs.NewErr(errs.InvalidCertificateProof, "%v", err)
+	}
+	if owner.ID != user.ID {
+		return errs.NewErr(errs.InvalidCertificateProof, "certificate is not issued to %s", user.Username)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errs.NewErr(errs.InvalidCertificateProof, "signature is not base64 encoded")
+	}
+	var algo x509.SignatureAlgorithm
+	switch x509Cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		algo = x509.SHA256WithRSA
+	case *ecdsa.PublicKey:
+		algo = x509.ECDSAWithSHA256
+	case ed25519.PublicKey:
+		algo = x509.PureEd25519
+	default:
+		return errs.NewErr(errs.InvalidCertificateProof, "unsupported public key")
+	}
+	if err = x509Cert.CheckSignature(algo, CertificateLoginMessage(user.Username, nonce), sig); err != nil {
+		return errs.NewErr(errs.InvalidCertificateProof, "%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package op
+
+import (
+	"crypto/x509"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+	"gorm.io/gorm"
+)
+
+const (
+	crlValidity  = 7 * 24 * time.Hour
+	ocspValidity = time.Hour
+)
+
+var crlCache struct {
+	sync.Mutex
+	der        []byte
+	thisUpdate time.Time
+}
+
+// GetCRL returns the current DER encoded CRL, regenerating it once half of its validity has passed.
+func GetCRL() ([]byte, error) {
+	crlCache.Lock()
+	defer crlCache.Unlock()
+	if crlCache.der == nil || time.Since(crlCache.thisUpdate) > crlValidity/2 {
+		if err := generateCRL(); err != nil {
+			return nil, err
+		}
+	}
+	return crlCache.der, nil
+}
+
+func refreshCRL() {
+	crlCache.Lock()
+	defer crlCache.Unlock()
+	if err := generateCRL(); err != nil {
+		// drop the stale list so the next request retries
+		crlCache.der = nil
+		utils.Log.Errorf("failed to regenerate CRL: %+v", err)
+	}
+}
+
+func generateCRL() error {
+	certs, err := db.GetRevokedCertificates()
+	if err != nil {
+		return err
+	}
+	entries := make([]x509.RevocationListEntry, 0, len(certs))
+	for _, cert := range certs {
+		serial, ok := new(big.Int).SetString(cert.SerialNumber, 16)
+		if !ok {
+			continue
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: revocationTime(&cert),
+			ReasonCode:     revocationReason(&cert),
+		})
+	}
+	now := time.Now()
+	der, err := ca.CreateCRL(entries, now, now.Add(crlValidity))
+	if err != nil {
+		return err
+	}
+	crlCache.der = der
+	crlCache.thisUpdate = now
+	return nil
+}
+
+// RespondOCSP answers a DER encoded OCSP request from the Certificate table.
+func RespondOCSP(data []byte) ([]byte, error) {
+	req, err := ca.ParseOCSPRequest(data)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := ocsp.Response{
+		SerialNumber: req.SerialNumber,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(ocspValidity),
+		Status:       ocsp.Unknown,
+	}
+	cert, err := db.GetCertificateBySerial(ca.SerialToHex(req.SerialNumber))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if cert != nil {
+		if isRevoked(cert) {
+			tmpl.Status = ocsp.Revoked
+			tmpl.RevokedAt = revocationTime(cert)
+			tmpl.RevocationReason = revocationReason(cert)
+		} else if cert.IsValid() || cert.Status == model.CertificateStatusExpired {
+			tmpl.Status = ocsp.Good
+		}
+	}
+	return ca.CreateOCSPResponse(tmpl)
+}
+
+func isRevoked(cert *model.Certificate) bool {
+	return cert.Status == model.CertificateStatusRevoked ||
+		cert.Status == model.CertificateStatusSuperseded ||
+		cert.DeletedAt.Valid
+}
+
+func revocationReason(cert *model.Certificate) int {
+	switch {
+	case cert.Status == model.CertificateStatusRevoked:
+		return ocsp.Unspecified
+	case cert.Status == model.CertificateStatusSuperseded:
+		return ocsp.Superseded
+	default:
+		return ocsp.CessationOfOperation
+	}
+}
+
+func revocationTime(cert *model.Certificate) time.Time {
+	if cert.RevokedAt != nil {
+		return *cert.RevokedAt
+	}
+	if cert.DeletedAt.Valid {
+		return cert.DeletedAt.Time
+	}
+	return cert.UpdatedAt
+}
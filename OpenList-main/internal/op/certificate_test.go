@@ -0,0 +1,350 @@
+package op_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"golang.org/x/crypto/ocsp"
+)
+
+var testAdmin = &model.User{Username: "admin", Role: model.ADMIN}
+
+func setupCertificate(t *testing.T, username string) (*model.Certificate, *x509.Certificate) {
+	cert, issued := issueCertificate(t, username)
+	return cert, issued.Cert
+}
+
+func issueCertificate(t *testing.T, username string) (*model.Certificate, *ca.Issued) {
+	if ca.Get() == nil {
+		a, _, err := ca.Generate("test CA")
+		if err != nil {
+			t.Fatalf("failed to generate CA: %+v", err)
+		}
+		ca.Set(a)
+	}
+	now := time.Now()
+	issued, err := ca.Issue(&ca.IssueReq{
+		Type:      model.CertificateTypeUser,
+		Username:  username,
+		NotBefore: now,
+		NotAfter:  now.AddDate(1, 0, 0),
+	})
+	if err != nil {
+		t.Fatalf("failed to issue certificate: %+v", err)
+	}
+	cert := &model.Certificate{
+		Name:           username,
+		Type:           model.CertificateTypeUser,
+		Status:         model.CertificateStatusValid,
+		Owner:          username,
+		SerialNumber:   issued.SerialHex(),
+		Content:        string(issued.CertPEM),
+		IssuedDate:     issued.Cert.NotBefore,
+		ExpirationDate: issued.Cert.NotAfter,
+	}
+	if err = op.CreateCertificate(cert, testAdmin, ""); err != nil {
+		t.Fatalf("failed to create certificate: %+v", err)
+	}
+	return cert, issued
+}
+
+func ocspStatus(t *testing.T, cert *x509.Certificate) int {
+	req, err := ocsp.CreateRequest(cert, ca.Get().Cert, nil)
+	if err != nil {
+		t.Fatalf("failed to create OCSP request: %+v", err)
+	}
+	raw, err := op.RespondOCSP(req)
+	if err != nil {
+		t.Fatalf("failed to respond OCSP request: %+v", err)
+	}
+	resp, err := ocsp.ParseResponseForCert(raw, cert, ca.Get().Cert)
+	if err != nil {
+		t.Fatalf("failed to parse OCSP response: %+v", err)
+	}
+	return resp.Status
+}
+
+func TestRevokeCertificate(t *testing.T) {
+	cert, x509Cert := setupCertificate(t, "revoke_test")
+	if status := ocspStatus(t, x509Cert); status != ocsp.Good {
+		t.Errorf("expect OCSP status good before revoke, got %d", status)
+	}
+	// a certificate is not the issuer of itself
+	foreign, err := ocsp.CreateRequest(x509Cert, x509Cert, nil)
+	if err != nil {
+		t.Fatalf("failed to create OCSP request: %+v", err)
+	}
+	if _, err = op.RespondOCSP(foreign); !errors.Is(err, ca.ErrForeignIssuer) {
+		t.Errorf("expect a request of another issuer to be unauthorized, got %+v", err)
+	}
+	if err = op.RevokeCertificate(cert.ID, testAdmin, "", "test"); err != nil {
+		t.Fatalf("failed to revoke certificate: %+v", err)
+	}
+	if status := ocspStatus(t, x509Cert); status != ocsp.Revoked {
+		t.Errorf("expect OCSP status revoked after revoke, got %d", status)
+	}
+	der, err := op.GetCRL()
+	if err != nil {
+		t.Fatalf("failed to get CRL: %+v", err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("failed to parse CRL: %+v", err)
+	}
+	if err = crl.CheckSignatureFrom(ca.Get().Cert); err != nil {
+		t.Errorf("CRL signature is invalid: %+v", err)
+	}
+	found := false
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(x509Cert.SerialNumber) == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("revoked certificate %s is missing from CRL", cert.SerialNumber)
+	}
+}
+
+func TestTakeCertificatePrivateKey(t *testing.T) {
+	cert, issued := issueCertificate(t, "take_key_test")
+	cert.PrivateKey = string(issued.KeyPEM)
+	if err := db.UpdateCertificate(cert); err != nil {
+		t.Fatalf("failed to update certificate: %+v", err)
+	}
+	// two downloads load the certificate before it is revoked
+	first, err := db.GetCertificateByID(cert.ID)
+	if err != nil {
+		t.Fatalf("failed to get certificate: %+v", err)
+	}
+	second := *first
+	if err = op.RevokeCertificate(cert.ID, testAdmin, "", "test"); err != nil {
+		t.Fatalf("failed to revoke certificate: %+v", err)
+	}
+	if key, err := op.TakeCertificatePrivateKey(first); err != nil || key != string(issued.KeyPEM) {
+		t.Errorf("expect the first download to take the key, got %q, %+v", key, err)
+	}
+	if key, err := op.TakeCertificatePrivateKey(&second); err != nil || key != "" {
+		t.Errorf("expect the second download to get no key, got %q, %+v", key, err)
+	}
+	stored, err := db.GetCertificateByID(cert.ID)
+	if err != nil {
+		t.Fatalf("failed to get certificate: %+v", err)
+	}
+	if stored.Status != model.CertificateStatusRevoked || stored.HasPrivateKey() {
+		t.Errorf("expect the certificate to stay revoked without a key, got %s", stored.Status)
+	}
+}
+
+func TestUpdateCertificateLifecycle(t *testing.T) {
+	soon, _ := setupCertificate(t, "lifecycle_soon")
+	past, _ := setupCertificate(t, "lifecycle_past")
+	soon.ExpirationDate = time.Now().AddDate(0, 0, 3)
+	past.ExpirationDate = time.Now().Add(-time.Hour)
+	for _, cert := range []*model.Certificate{soon, past} {
+		if err := db.UpdateCertificate(cert); err != nil {
+			t.Fatalf("failed to update certificate: %+v", err)
+		}
+	}
+	if _, _, err := op.UpdateCertificateLifecycle(7); err != nil {
+		t.Fatalf("failed to update lifecycle: %+v", err)
+	}
+	var tests = []struct {
+		id     uint
+		status model.CertificateStatus
+	}{
+		{id: soon.ID, status: model.CertificateStatusExpiring},
+		{id: past.ID, status: model.CertificateStatusExpired},
+	}
+	for _, tt := range tests {
+		cert, err := op.GetCertificateByID(tt.id)
+		if err != nil {
+			t.Fatalf("failed to get certificate: %+v", err)
+		}
+		if cert.Status != tt.status {
+			t.Errorf("expect certificate %d to be %s, got %s", tt.id, tt.status, cert.Status)
+		}
+	}
+}
+
+func TestRenewCertificate(t *testing.T) {
+	user := &model.User{Username: "renew_test", Role: model.TENANT}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	old, _ := setupCertificate(t, user.Username)
+	old.OwnerID = user.ID
+	if err := db.UpdateCertificate(old); err != nil {
+		t.Fatalf("failed to update certificate: %+v", err)
+	}
+	req, err := op.CreateTenantRenewalRequest(user, "", "renew", "")
+	if err != nil {
+		t.Fatalf("failed to create renewal request: %+v", err)
+	}
+	if req.RenewalOf != old.ID {
+		t.Errorf("expect renewal of %d, got %d", old.ID, req.RenewalOf)
+	}
+	cert, err := op.ApproveAndCreateCertificate(req.ID, testAdmin, "")
+	if err != nil {
+		t.Fatalf("failed to approve renewal: %+v", err)
+	}
+	if cert.PredecessorID != old.ID {
+		t.Errorf("expect predecessor %d, got %d", old.ID, cert.PredecessorID)
+	}
+	if cert.Content == old.Content {
+		t.Errorf("expect renewed certificate to be re-keyed")
+	}
+	old, err = op.GetCertificateByID(old.ID)
+	if err != nil {
+		t.Fatalf("failed to get old certificate: %+v", err)
+	}
+	if old.Status != model.CertificateStatusSuperseded {
+		t.Errorf("expect old certificate to be superseded, got %s", old.Status)
+	}
+}
+
+func TestCertificateHistory(t *testing.T) {
+	user := &model.User{Username: "history_test", Role: model.TENANT}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	req, err := op.CreateTenantCertificateRequest(user, "127.0.0.1", model.CertificateTypeUser, "history", "")
+	if err != nil {
+		t.Fatalf("failed to create request: %+v", err)
+	}
+	cert, err := op.ApproveAndCreateCertificate(req.ID, testAdmin, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to approve request: %+v", err)
+	}
+	if _, err = op.ApproveAndCreateCertificate(req.ID, testAdmin, "10.0.0.1"); !errors.Is(err, errs.CertificateRequestNotPending) {
+		t.Errorf("expect approving twice to fail with not pending, got %+v", err)
+	}
+	if _, err = op.UpdateCertificateDetails(cert.ID, "renamed", cert.ExpirationDate, testAdmin, "10.0.0.1"); err != nil {
+		t.Fatalf("failed to update certificate: %+v", err)
+	}
+	if err = op.RevokeCertificate(cert.ID, testAdmin, "10.0.0.2", "key compromise"); err != nil {
+		t.Fatalf("failed to revoke certificate: %+v", err)
+	}
+	events, err := op.GetCertificateHistory(cert.ID)
+	if err != nil {
+		t.Fatalf("failed to get history: %+v", err)
+	}
+	var tests = []model.CertificateEvent{
+		{Action: model.CertificateActionRequest, Actor: user.Username, IP: "127.0.0.1", NewStatus: model.CertificateStatusPending},
+		{Action: model.CertificateActionApprove, Actor: testAdmin.Username, IP: "10.0.0.1", OldStatus: model.CertificateStatusPending, NewStatus: model.CertificateStatusValid},
+		{Action: model.CertificateActionUpdate, Actor: testAdmin.Username, IP: "10.0.0.1", OldStatus: model.CertificateStatusValid, NewStatus: model.CertificateStatusValid},
+		{Action: model.CertificateActionRevoke, Actor: testAdmin.Username, IP: "10.0.0.2", OldStatus: model.CertificateStatusValid, NewStatus: model.CertificateStatusRevoked},
+	}
+	if len(events) != len(tests) {
+		t.Fatalf("expect %d events, got %d: %+v", len(tests), len(events), events)
+	}
+	for i, tt := range tests {
+		e := events[i]
+		if e.Action != tt.Action || e.Actor != tt.Actor || e.IP != tt.IP || e.OldStatus != tt.OldStatus || e.NewStatus != tt.NewStatus {
+			t.Errorf("event %d: expect %+v, got %+v", i, tt, e)
+		}
+	}
+}
+
+func TestTenantCertificateLogin(t *testing.T) {
+	tenant := setupTenant(t, "cert_login")
+	user := &model.User{Username: "cert_login_user", Role: model.TENANT, TenantID: tenant.ID}
+	user.SetPassword("password")
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	cert, issued := issueCertificate(t, user.Username)
+	cert.OwnerID = user.ID
+	if err := db.UpdateCertificate(cert); err != nil {
+		t.Fatalf("failed to update certificate: %+v", err)
+	}
+	key, err := ca.ParsePrivateKeyPEM(issued.KeyPEM)
+	if err != nil {
+		t.Fatalf("failed to parse key: %+v", err)
+	}
+	sign := func(nonce string) string {
+		digest := sha256.Sum256(op.CertificateLoginMessage(user.Username, nonce))
+		sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			t.Fatalf("failed to sign challenge: %+v", err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+
+	if err = op.CheckTenantCertificateLogin(user, "", "", ""); err != nil {
+		t.Errorf("expect password login when certificate login is disabled, got %+v", err)
+	}
+	tenant.CertLogin = model.TenantCertLoginRequired
+	if err = op.UpdateTenant(tenant); err != nil {
+		t.Fatalf("failed to update tenant: %+v", err)
+	}
+	if err = op.CheckTenantCertificateLogin(user, "", "", ""); !errors.Is(err, errs.CertificateProofRequired) {
+		t.Errorf("expect a certificate proof to be required, got %+v", err)
+	}
+	if err = op.CheckUserPassword(user, model.StaticHash("password")); !errors.Is(err, errs.CertificateProofRequired) {
+		t.Errorf("expect a password only sign-in to be refused, got %+v", err)
+	}
+	nonce, _ := op.NewCertificateLoginChallenge(user.Username)
+	if err = op.CheckTenantCertificateLogin(user, string(issued.CertPEM), nonce, sign("forged")); !errors.Is(err, errs.InvalidCertificateProof) {
+		t.Errorf("expect a signature over another nonce to be rejected, got %+v", err)
+	}
+	nonce, _ = op.NewCertificateLoginChallenge(user.Username)
+	signature := sign(nonce)
+	if err = op.CheckTenantCertificateLogin(user, string(issued.CertPEM), nonce, signature); err != nil {
+		t.Errorf("expect a valid proof to pass, got %+v", err)
+	}
+	if err = op.CheckTenantCertificateLogin(user, string(issued.CertPEM), nonce, signature); !errors.Is(err, errs.InvalidCertificateProof) {
+		t.Errorf("expect a challenge to be used only once, got %+v", err)
+	}
+	if err = op.RevokeCertificate(cert.ID, testAdmin, "", "test"); err != nil {
+		t.Fatalf("failed to revoke certificate: %+v", err)
+	}
+	nonce, _ = op.NewCertificateLoginChallenge(user.Username)
+	if err = op.CheckTenantCertificateLogin(user, string(issued.CertPEM), nonce, sign(nonce)); !errors.Is(err, errs.InvalidCertificateProof) {
+		t.Errorf("expect a revoked certificate to be rejected, got %+v", err)
+	}
+}
+
+func TestClientCertificateOwner(t *testing.T) {
+	tenant := setupTenant(t, "cert_owner")
+	user := &model.User{Username: "cert_owner_user", Role: model.TENANT, TenantID: tenant.ID}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	cert, x509Cert := setupCertificate(t, user.Username)
+	cert.OwnerID = user.ID
+	if err := db.UpdateCertificate(cert); err != nil {
+		t.Fatalf("failed to update certificate: %+v", err)
+	}
+	if owner, err := op.GetUserByClientCertificate(x509Cert); err != nil || owner.ID != user.ID {
+		t.Fatalf("expect the certificate to map to its owner, got %+v, %+v", owner, err)
+	}
+	tenant.Disabled = true
+	if err := op.UpdateTenant(tenant); err != nil {
+		t.Fatalf("failed to update tenant: %+v", err)
+	}
+	if _, err := op.GetUserByClientCertificate(x509Cert); !errors.Is(err, errs.TenantDisabled) {
+		t.Errorf("expect the user of a disabled tenant to be rejected, got %+v", err)
+	}
+	tenant.Disabled = false
+	if err := op.UpdateTenant(tenant); err != nil {
+		t.Fatalf("failed to update tenant: %+v", err)
+	}
+	user.Disabled = true
+	if err := op.UpdateUser(user); err != nil {
+		t.Fatalf("failed to update user: %+v", err)
+	}
+	if _, err := op.GetUserByClientCertificate(x509Cert); err == nil {
+		t.Errorf("expect a disabled user to be rejected")
+	}
+}
@@ -0,0 +1,134 @@
+package op
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// the changes the other instances sharing the database have to apply, see internal/cluster
+const (
+	ChangeStorage = "storage" // the key is the id of the storage
+	ChangeSetting = "setting"
+	ChangeList    = "list" // the key is the key of the listing cache
+	ChangeLink    = "link" // the key is the key of the link cache
+	// the changes of the objects cached from the database
+	ChangeUser     = "user"      // the key is the username, empty for all the users as after a change of the groups
+	ChangeApiToken = "api_token" // the key is the hash of the token
+	ChangeSession  = "session"   // the key is the id of the session
+	ChangePathACL  = "path_acl"
+	ChangeMeta     = "meta"   // the key is the path of the meta
+	ChangeTenant   = "tenant" // the key is the name of the tenant
+)
+
+// ChangeNotifier is told about the changes made by this instance
+type ChangeNotifier func(typ, key string)
+
+var changeNotifier ChangeNotifier
+
+// SetChangeNotifier should be called before the storages are loaded
+func SetChangeNotifier(n ChangeNotifier) {
+	changeNotifier = n
+}
+
+func notifyChange(typ, key string) {
+	if changeNotifier != nil {
+		changeNotifier(typ, key)
+	}
+}
+
+// ApplyChange applies a change made by another instance, it is not notified again
+func ApplyChange(ctx context.Context, typ, key string) error {
+	switch typ {
+	case ChangeStorage:
+		id, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid storage id %s", key)
+		}
+		return reloadStorage(ctx, uint(id))
+	case ChangeSetting:
+		return reloadSettings()
+	case ChangeList:
+		listCache.Del(key)
+	case ChangeLink:
+		linkCache.Del(key)
+	case ChangeUser, ChangeApiToken, ChangeSession, ChangePathACL, ChangeMeta, ChangeTenant:
+		delCache(typ, key)
+	default:
+		return errors.Errorf("unknown change type: %s", typ)
+	}
+	return nil
+}
+
+// dropCache drops an object cached from the database here and on the other instances
+func dropCache(typ, key string) {
+	delCache(typ, key)
+	notifyChange(typ, key)
+}
+
+func delCache(typ, key string) {
+	switch typ {
+	case ChangeUser:
+		if key == "" {
+			userCache.Clear()
+		} else {
+			userCache.Del(key)
+		}
+		// the admin and the guest may have been changed under another name
+		adminUser, guestUser = nil, nil
+	case ChangeApiToken:
+		apiTokenCache.Del(key)
+	case ChangeSession:
+		sessionCache.Del(key)
+	case ChangePathACL:
+		delPathACLsCache()
+	case ChangeMeta:
+		metaCache.Del(key)
+	case ChangeTenant:
+		tenantCache.Del(key)
+	}
+}
+
+// reloadStorage drops the storage and loads it again from the database
+func reloadStorage(ctx context.Context, id uint) error {
+	for _, storageDriver := range storagesMap.Values() {
+		if storageDriver.GetStorage().ID != id {
+			continue
+		}
+		if err := storageDriver.Drop(ctx); err != nil {
+			log.Warnf("failed drop storage %s: %+v", storageDriver.GetStorage().MountPath, err)
+		}
+		storagesMap.Delete(storageDriver.GetStorage().MountPath)
+		go callStorageHooks("del", storageDriver)
+	}
+	storage, err := db.GetStorageById(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if storage.Disabled {
+		return nil
+	}
+	return LoadStorage(ctx, *storage)
+}
+
+// reloadSettings runs the hooks of the settings again and drops the cached ones
+func reloadSettings() error {
+	items, err := db.GetSettingItems()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if _, err := HandleSettingItemHook(&items[i]); err != nil {
+			log.Warnf("failed to execute hook on %s: %+v", items[i].Key, err)
+		}
+	}
+	SettingCacheUpdate()
+	return nil
+}
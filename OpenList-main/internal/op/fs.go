@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -22,11 +23,18 @@ import (
 
 // In order to facilitate adding some other things before and after file op
 
-var listCache = cache.NewMemCache(cache.WithShards[[]model.Obj](64))
+var listCache = &storedCache[[]model.Obj]{
+	mem:    cache.NewMemCache(cache.WithShards[[]model.Obj](64)),
+	prefix: "list:",
+	encode: encodeObjs,
+	decode: decodeObjs,
+}
 var listG singleflight.Group[[]model.Obj]
 
 func updateCacheObj(storage driver.Driver, path string, oldObj model.Obj, newObj model.Obj) {
 	key := Key(storage, path)
+	// the other instances drop the listing rather than patching it
+	notifyChange(ChangeList, key)
 	objs, ok := listCache.Get(key)
 	if ok {
 		for i, obj := range objs {
@@ -41,12 +49,14 @@ func updateCacheObj(storage driver.Driver, path string, oldObj model.Obj, newObj
 				break
 			}
 		}
-		listCache.Set(key, objs, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+		listCache.Set(key, objs, time.Minute*time.Duration(storage.GetStorage().CacheExpiration))
 	}
 }
 
 func delCacheObj(storage driver.Driver, path string, obj model.Obj) {
 	key := Key(storage, path)
+	// the other instances drop the listing rather than patching it
+	notifyChange(ChangeList, key)
 	objs, ok := listCache.Get(key)
 	if ok {
 		for i, oldObj := range objs {
@@ -55,7 +65,7 @@ func delCacheObj(storage driver.Driver, path string, obj model.Obj) {
 				break
 			}
 		}
-		listCache.Set(key, objs, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+		listCache.Set(key, objs, time.Minute*time.Duration(storage.GetStorage().CacheExpiration))
 	}
 }
 
@@ -63,11 +73,17 @@ var addSortDebounceMap generic_sync.MapOf[string, func(func())]
 
 func addCacheObj(storage driver.Driver, path string, newObj model.Obj) {
 	key := Key(storage, path)
+	// the other instances drop the listing rather than patching it
+	notifyChange(ChangeList, key)
 	objs, ok := listCache.Get(key)
 	if ok {
 		for i, obj := range objs {
 			if obj.GetName() == newObj.GetName() {
 				objs[i] = newObj
+				// the objs are a copy when they come from the cache store
+				if cacheStore != nil {
+					listCache.Set(key, objs, time.Minute*time.Duration(storage.GetStorage().CacheExpiration))
+				}
 				return
 			}
 		}
@@ -79,7 +95,10 @@ func addCacheObj(storage driver.Driver, path string, newObj model.Obj) {
 			objs = append([]model.Obj{newObj}, objs...)
 		}
 
-		if storage.Config().LocalSort {
+		if storage.Config().LocalSort && cacheStore != nil {
+			// a later sort would only change the copy, so the stored objs are sorted now
+			model.SortFiles(objs, storage.GetStorage().OrderBy, storage.GetStorage().OrderDirection)
+		} else if storage.Config().LocalSort {
 			debounce, _ := addSortDebounceMap.LoadOrStore(key, utils.NewDebounce(time.Minute))
 			log.Debug("addCacheObj: wait start sort")
 			debounce(func() {
@@ -89,7 +108,7 @@ func addCacheObj(storage driver.Driver, path string, newObj model.Obj) {
 			})
 		}
 
-		listCache.Set(key, objs, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+		listCache.Set(key, objs, time.Minute*time.Duration(storage.GetStorage().CacheExpiration))
 	}
 }
 
@@ -103,10 +122,12 @@ func ClearCache(storage driver.Driver, path string) {
 		}
 	}
 	listCache.Del(Key(storage, path))
+	notifyChange(ChangeList, Key(storage, path))
 }
 
 func DeleteCache(storage driver.Driver, path string) {
 	listCache.Del(Key(storage, path))
+	notifyChange(ChangeList, Key(storage, path))
 }
 
 func Key(storage driver.Driver, path string) string {
@@ -162,7 +183,7 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 		if !storage.Config().NoCache {
 			if len(files) > 0 {
 				log.Debugf("set cache: %s => %+v", key, files)
-				listCache.Set(key, files, cache.WithEx[[]model.Obj](time.Minute*time.Duration(storage.GetStorage().CacheExpiration)))
+				listCache.Set(key, files, time.Minute*time.Duration(storage.GetStorage().CacheExpiration))
 			} else {
 				log.Debugf("del cache: %s", key)
 				listCache.Del(key)
@@ -249,7 +270,12 @@ func GetUnwrap(ctx context.Context, storage driver.Driver, path string) (model.O
 	return model.UnwrapObj(obj), err
 }
 
-var linkCache = cache.NewMemCache(cache.WithShards[*model.Link](16))
+var linkCache = &storedCache[*model.Link]{
+	mem:    cache.NewMemCache(cache.WithShards[*model.Link](16)),
+	prefix: "link:",
+	encode: encodeLink,
+	decode: decodeLink,
+}
 var linkG = singleflight.Group[*model.Link]{Remember: true}
 var errLinkMFileCache = stderrors.New("ErrLinkMFileCache")
 
@@ -305,7 +331,7 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, errLinkMFileCache
 		}
 		if link.Expiration != nil {
-			linkCache.Set(key, link, cache.WithEx[*model.Link](*link.Expiration))
+			linkCache.Set(key, link, *link.Expiration)
 		}
 		link.AddIfCloser(forget)
 		return link, nil
@@ -520,6 +546,24 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	if err != nil {
 		return errors.WithMessage(err, "failed to get dst dir")
 	}
+	// the copy is charged as far as the src is known from the cache and settled to the copied objects
+	charge, err := chargeQuota(ctx, storage, 0, 0)
+	if err != nil {
+		return err
+	}
+	if charge.accounted() {
+		bytes, objects := cachedUsage(storage, srcPath, srcObj)
+		if err = charge.add(bytes, objects, true); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				charge.refund()
+			} else {
+				charge.settleObjs(ctx, storage, dstDirPath, []model.Obj{srcObj})
+			}
+		}()
+	}
 
 	switch s := storage.(type) {
 	case driver.CopyResult:
@@ -538,7 +582,7 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 			DeleteCache(storage, dstDirPath)
 		}
 	default:
-		return errs.NotImplement
+		err = errs.NotImplement
 	}
 	return errors.WithStack(err)
 }
@@ -561,11 +605,18 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 		return errors.WithMessage(err, "failed to get object")
 	}
 	dirPath := stdpath.Dir(path)
+	charge, err := chargeQuota(ctx, storage, 0, 0)
+	if err != nil {
+		return err
+	}
+	// only what is known from the cache is released, the storage is not walked before the removal
+	bytes, objects := cachedUsage(storage, path, rawObj)
 
 	switch s := storage.(type) {
 	case driver.Remove:
 		err = s.Remove(ctx, model.UnwrapObj(rawObj))
 		if err == nil {
+			charge.settle(-bytes, -objects)
 			delCacheObj(storage, dirPath, rawObj)
 			// clear folder cache recursively
 			if rawObj.IsDir() {
@@ -600,16 +651,29 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	tempName := file.GetName() + ".openlist_to_delete"
 	tempPath := stdpath.Join(dstDirPath, tempName)
 	fi, err := GetUnwrap(ctx, storage, dstPath)
+	// the quota is charged before the old obj is touched, replacing it only charges the difference
+	// and the old obj is removed without releasing it again
+	oldBytes, objects := int64(0), int64(1)
+	if err == nil {
+		oldBytes, objects = max(fi.GetSize(), 0), 0
+	}
+	charge, chargeErr := chargeQuota(ctx, storage, max(file.GetSize(), 0)-oldBytes, objects)
+	if chargeErr != nil {
+		return chargeErr
+	}
+	noQuotaCtx := context.WithValue(ctx, conf.NoQuotaKey, struct{}{})
 	if err == nil {
 		if fi.GetSize() == 0 {
-			err = Remove(ctx, storage, dstPath)
+			err = Remove(noQuotaCtx, storage, dstPath)
 			if err != nil {
+				charge.refund()
 				return errors.WithMessagef(err, "while uploading, failed remove existing file which size = 0")
 			}
 		} else if storage.Config().NoOverwriteUpload {
 			// try to rename old obj
 			err = Rename(ctx, storage, dstPath, tempName)
 			if err != nil {
+				charge.refund()
 				return err
 			}
 		} else {
@@ -618,11 +682,13 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	}
 	err = MakeDir(ctx, storage, dstDirPath)
 	if err != nil {
+		charge.refund()
 		return errors.WithMessagef(err, "failed to make dir [%s]", dstDirPath)
 	}
 	parentDir, err := GetUnwrap(ctx, storage, dstDirPath)
 	// this should not happen
 	if err != nil {
+		charge.refund()
 		return errors.WithMessagef(err, "failed to get dir [%s]", dstDirPath)
 	}
 	// if up is nil, set a default to prevent panic
@@ -630,9 +696,9 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		up = func(p float64) {}
 	}
 
+	var newObj model.Obj
 	switch s := storage.(type) {
 	case driver.PutResult:
-		var newObj model.Obj
 		newObj, err = s.Put(ctx, parentDir, file, up)
 		if err == nil {
 			if newObj != nil {
@@ -647,8 +713,17 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 			DeleteCache(storage, dstDirPath)
 		}
 	default:
+		charge.refund()
 		return errs.NotImplement
 	}
+	if err != nil {
+		charge.refund()
+	} else if charge.accounted() {
+		// the size declared by the client is settled to the size actually stored
+		if size, ok := storedSize(ctx, storage, dstPath, newObj); ok {
+			charge.settle(size-oldBytes, objects)
+		}
+	}
 	log.Debugf("put file [%s] done", file.GetName())
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
@@ -659,12 +734,13 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 			}
 		} else {
 			// upload success, remove old obj
-			err := Remove(ctx, storage, tempPath)
+			err := Remove(noQuotaCtx, storage, tempPath)
 			if err != nil {
 				return err
 			} else {
 				key := Key(storage, stdpath.Join(dstDirPath, file.GetName()))
 				linkCache.Del(key)
+				notifyChange(ChangeLink, key)
 			}
 		}
 	}
@@ -688,9 +764,20 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	if err != nil {
 		return errors.WithMessagef(err, "failed to put url")
 	}
+	// the size is unknown before the url is fetched, only the object is charged
+	// and settled to the size stored once it is fetched
+	charge, err := chargeQuota(ctx, storage, 0, 1)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			charge.refund()
+		}
+	}()
+	var newObj model.Obj
 	switch s := storage.(type) {
 	case driver.PutURLResult:
-		var newObj model.Obj
 		newObj, err = s.PutURL(ctx, dstDir, dstName, url)
 		if err == nil {
 			if newObj != nil {
@@ -705,8 +792,14 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 			DeleteCache(storage, dstDirPath)
 		}
 	default:
+		charge.refund()
 		return errs.NotImplement
 	}
+	if err == nil && charge.accounted() {
+		if size, ok := storedSize(ctx, storage, stdpath.Join(dstDirPath, dstName), newObj); ok {
+			charge.settle(size, 1)
+		}
+	}
 	log.Debugf("put url [%s](%s) done", dstName, url)
 	return errors.WithStack(err)
 }
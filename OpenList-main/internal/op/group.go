@@ -0,0 +1,169 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func GetGroupById(id uint) (*model.Group, error) {
+	return db.GetGroupById(id)
+}
+
+func GetGroups(pageIndex, pageSize int) ([]model.Group, int64, error) {
+	return db.GetGroups(pageIndex, pageSize)
+}
+
+func GetGroupMembers(groupId uint, pageIndex, pageSize int) ([]model.User, int64, error) {
+	return db.GetGroupMembers(groupId, pageIndex, pageSize)
+}
+
+func CreateGroup(g *model.Group) error {
+	if g.Name == "" {
+		return errors.New("group name is required")
+	}
+	fixGroupPaths(g)
+	return db.CreateGroup(g)
+}
+
+func UpdateGroup(g *model.Group) error {
+	if _, err := db.GetGroupById(g.ID); err != nil {
+		return err
+	}
+	fixGroupPaths(g)
+	if err := db.UpdateGroup(g); err != nil {
+		return err
+	}
+	delGroupMembersCache()
+	return nil
+}
+
+func DeleteGroupById(id uint) error {
+	if err := db.DeleteGroupById(id); err != nil {
+		return err
+	}
+	if err := deletePathACLsBySubject(model.ACLSubjectGroup, id); err != nil {
+		return err
+	}
+	delGroupMembersCache()
+	return nil
+}
+
+func AddGroupMember(groupId, userId uint) error {
+	if _, err := db.GetGroupById(groupId); err != nil {
+		return err
+	}
+	if _, err := db.GetUserById(userId); err != nil {
+		return err
+	}
+	if err := db.AddUserToGroup(userId, groupId); err != nil {
+		return err
+	}
+	delGroupMembersCache()
+	return nil
+}
+
+func RemoveGroupMember(groupId, userId uint) error {
+	if err := db.RemoveUserFromGroup(userId, groupId); err != nil {
+		return err
+	}
+	delGroupMembersCache()
+	return nil
+}
+
+// SyncExternalGroups makes the user a member of exactly the groups its LDAP or SSO groups map to,
+// groups without external names are managed by hand and left untouched
+func SyncExternalGroups(user *model.User, externalNames []string) error {
+	groups, err := db.GetExternalGroups()
+	if err != nil {
+		return err
+	}
+	current, err := db.GetGroupsByUserId(user.ID)
+	if err != nil {
+		return err
+	}
+	member := make(map[uint]bool, len(current))
+	for _, g := range current {
+		member[g.ID] = true
+	}
+	changed := false
+	for _, g := range groups {
+		matched := false
+		for _, name := range externalNames {
+			if g.MatchExternal(name) {
+				matched = true
+				break
+			}
+		}
+		switch {
+		case matched && !member[g.ID]:
+			err = db.AddUserToGroup(user.ID, g.ID)
+		case !matched && member[g.ID]:
+			err = db.RemoveUserFromGroup(user.ID, g.ID)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+	if changed {
+		delGroupMembersCache()
+		return loadUserGroups(user)
+	}
+	return nil
+}
+
+// HasExternalGroup reports whether any of the external groups is mapped to a group
+func HasExternalGroup(externalNames []string) (bool, error) {
+	if len(externalNames) == 0 {
+		return false, nil
+	}
+	groups, err := db.GetExternalGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups {
+		for _, name := range externalNames {
+			if g.MatchExternal(name) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// loadUserGroups resolves the permission, base path and meta overrides the user gets from its groups
+func loadUserGroups(u *model.User) error {
+	groups, err := db.GetGroupsByUserId(u.ID)
+	if err != nil {
+		return err
+	}
+	u.GroupIDs = make([]uint, 0, len(groups))
+	u.GroupPermission, u.GroupBasePath, u.GroupMetas = 0, "", nil
+	for _, g := range groups {
+		u.GroupIDs = append(u.GroupIDs, g.ID)
+		u.GroupPermission |= g.Permission
+		if u.GroupBasePath == "" {
+			u.GroupBasePath = g.BasePath
+		}
+		u.GroupMetas = append(u.GroupMetas, g.Metas...)
+	}
+	return nil
+}
+
+func fixGroupPaths(g *model.Group) {
+	if g.BasePath != "" {
+		g.BasePath = utils.FixAndCleanPath(g.BasePath)
+	}
+	for i := range g.Metas {
+		g.Metas[i].Path = utils.FixAndCleanPath(g.Metas[i].Path)
+	}
+}
+
+// delGroupMembersCache drops all cached users since the groups are resolved when users are loaded
+func delGroupMembersCache() {
+	dropCache(ChangeUser, "")
+}
@@ -0,0 +1,56 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestGroupPermission(t *testing.T) {
+	dev := &model.Group{
+		Name:       "dev",
+		Permission: 1 << 3,
+		BasePath:   "/dev",
+		Metas:      []model.GroupMeta{{Path: "/dev/secret", NoPassword: true, Sub: true}},
+	}
+	ops := &model.Group{Name: "ops", ExternalNames: "ops\nsre"}
+	for _, g := range []*model.Group{dev, ops} {
+		if err := op.CreateGroup(g); err != nil {
+			t.Fatalf("failed to create group: %+v", err)
+		}
+	}
+	user := &model.User{Username: "grouped", GroupIDs: []uint{dev.ID}}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	user, err := op.GetUserByName("grouped")
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	if !user.CanWrite() || user.Permission != 0 {
+		t.Errorf("expect the group permission to be merged, got %d", user.EffectivePermission())
+	}
+	if user.EffectiveBasePath() != "/dev" {
+		t.Errorf("expect the group base path to be inherited, got %s", user.EffectiveBasePath())
+	}
+	if m := user.GroupMetaOf("/dev/secret/a"); m == nil || !m.NoPassword {
+		t.Errorf("expect the group meta to apply to sub paths, got %+v", m)
+	}
+	if err = op.SyncExternalGroups(user, []string{"cn=SRE,ou=groups,dc=example,dc=com"}); err != nil {
+		t.Fatalf("failed to sync external groups: %+v", err)
+	}
+	user, err = op.GetUserByName("grouped")
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	if len(user.GroupIDs) != 2 {
+		t.Errorf("expect the external group to be added and dev to be kept, got %v", user.GroupIDs)
+	}
+	if err = op.SyncExternalGroups(user, []string{}); err != nil {
+		t.Fatalf("failed to sync external groups: %+v", err)
+	}
+	if len(user.GroupIDs) != 1 || user.GroupIDs[0] != dev.ID {
+		t.Errorf("expect only the external group to be removed, got %v", user.GroupIDs)
+	}
+}
@@ -19,10 +19,17 @@ var metaCache = cache.NewMemCache(cache.WithShards[*model.Meta](2))
 // metaG maybe not needed
 var metaG singleflight.Group[*model.Meta]
 
+// GetNearestMeta get the meta of the path or its nearest parent,
+// metas outside a tenant namespace are never applied to the paths inside it
 func GetNearestMeta(path string) (*model.Meta, error) {
-	return getNearestMeta(utils.FixAndCleanPath(path))
+	path = utils.FixAndCleanPath(path)
+	root := "/"
+	if name, ok := model.TenantNameOfPath(path); ok {
+		root = model.TenantRootPath(name)
+	}
+	return getNearestMeta(path, root)
 }
-func getNearestMeta(path string) (*model.Meta, error) {
+func getNearestMeta(path, root string) (*model.Meta, error) {
 	meta, err := GetMetaByPath(path)
 	if err == nil {
 		return meta, nil
@@ -30,10 +37,10 @@ func getNearestMeta(path string) (*model.Meta, error) {
 	if errors.Cause(err) != errs.MetaNotFound {
 		return nil, err
 	}
-	if path == "/" {
+	if path == root || path == "/" {
 		return nil, errs.MetaNotFound
 	}
-	return getNearestMeta(stdpath.Dir(path))
+	return getNearestMeta(stdpath.Dir(path), root)
 }
 
 func GetMetaByPath(path string) (*model.Meta, error) {
@@ -67,23 +74,32 @@ func DeleteMetaById(id uint) error {
 	if err != nil {
 		return err
 	}
-	metaCache.Del(old.Path)
+	defer dropCache(ChangeMeta, old.Path)
 	return db.DeleteMetaById(id)
 }
 
 func UpdateMeta(u *model.Meta) error {
-	u.Path = utils.FixAndCleanPath(u.Path)
+	var err error
+	u.Path, err = resolveTenantPath(&u.TenantID, u.Path)
+	if err != nil {
+		return err
+	}
 	old, err := db.GetMetaById(u.ID)
 	if err != nil {
 		return err
 	}
-	metaCache.Del(old.Path)
+	defer dropCache(ChangeMeta, old.Path)
+	defer dropCache(ChangeMeta, u.Path)
 	return db.UpdateMeta(u)
 }
 
 func CreateMeta(u *model.Meta) error {
-	u.Path = utils.FixAndCleanPath(u.Path)
-	metaCache.Del(u.Path)
+	var err error
+	u.Path, err = resolveTenantPath(&u.TenantID, u.Path)
+	if err != nil {
+		return err
+	}
+	defer dropCache(ChangeMeta, u.Path)
 	return db.CreateMeta(u)
 }
 
@@ -91,6 +107,6 @@ func GetMetaById(id uint) (*model.Meta, error) {
 	return db.GetMetaById(id)
 }
 
-func GetMetas(pageIndex, pageSize int) (metas []model.Meta, count int64, err error) {
-	return db.GetMetas(pageIndex, pageSize)
+func GetMetas(pageIndex, pageSize int, tenantID *uint) (metas []model.Meta, count int64, err error) {
+	return db.GetMetas(pageIndex, pageSize, tenantID)
 }
@@ -0,0 +1,274 @@
+package op
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/idp"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+// OpenList as OIDC provider: the authorization code flow with PKCE,
+// codes are kept in the database so that any node of a cluster can redeem them,
+// and the tokens are JWTs signed by the idp key
+
+const (
+	OIDCCodeTTL  = time.Minute
+	OIDCTokenTTL = time.Hour
+)
+
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce,omitempty"`
+	AuthTime          int64  `json:"auth_time,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Scope             string `json:"scope,omitempty"`
+	// TokenUse tells the access tokens from the ID tokens, which must not be accepted by the userinfo endpoint
+	TokenUse string `json:"token_use"`
+}
+
+func randomOIDCString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashOIDCSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func GetOIDCClients(pageIndex, pageSize int) ([]model.OIDCClient, int64, error) {
+	return db.GetOIDCClients(pageIndex, pageSize)
+}
+
+func GetOIDCClientById(id uint) (*model.OIDCClient, error) {
+	return db.GetOIDCClientById(id)
+}
+
+// CreateOIDCClient registers a client and returns its plain secret, which is empty for public clients
+func CreateOIDCClient(c *model.OIDCClient) (string, error) {
+	clientID, err := randomOIDCString()
+	if err != nil {
+		return "", err
+	}
+	c.ID = 0
+	c.ClientID = clientID[:24]
+	c.SecretHash = ""
+	var secret string
+	if !c.Public {
+		if secret, err = randomOIDCString(); err != nil {
+			return "", err
+		}
+		c.SecretHash = hashOIDCSecret(secret)
+	}
+	return secret, db.CreateOIDCClient(c)
+}
+
+// UpdateOIDCClient updates a client, its id, secret and type can not be changed
+func UpdateOIDCClient(c *model.OIDCClient) error {
+	old, err := db.GetOIDCClientById(c.ID)
+	if err != nil {
+		return err
+	}
+	c.ClientID, c.SecretHash, c.Public, c.CreatedAt = old.ClientID, old.SecretHash, old.Public, old.CreatedAt
+	return db.UpdateOIDCClient(c)
+}
+
+// ResetOIDCClientSecret replaces the secret of a confidential client and returns the new one
+func ResetOIDCClientSecret(id uint) (string, error) {
+	c, err := db.GetOIDCClientById(id)
+	if err != nil {
+		return "", err
+	}
+	if c.Public {
+		return "", errors.New("public clients have no secret")
+	}
+	secret, err := randomOIDCString()
+	if err != nil {
+		return "", err
+	}
+	c.SecretHash = hashOIDCSecret(secret)
+	return secret, db.UpdateOIDCClient(c)
+}
+
+func DeleteOIDCClientById(id uint) error {
+	return db.DeleteOIDCClientById(id)
+}
+
+func getActiveOIDCClient(clientID string) (*model.OIDCClient, error) {
+	c, err := db.GetOIDCClientByClientID(clientID)
+	if err != nil || c.Disabled {
+		return nil, errs.NewErr(errs.OIDCInvalidClient, "unknown or disabled client")
+	}
+	return c, nil
+}
+
+// CheckOIDCAuthRequest validates an authorization request before the user approves it.
+// An errs.OIDCInvalidClient error must be shown to the user rather than redirected,
+// since the redirect uri is not known to belong to the client.
+func CheckOIDCAuthRequest(req *model.OIDCAuthRequest, responseType string) error {
+	c, err := getActiveOIDCClient(req.ClientID)
+	if err != nil {
+		return err
+	}
+	if !c.AllowRedirect(req.RedirectURI) {
+		return errs.NewErr(errs.OIDCInvalidClient, "redirect uri is not registered")
+	}
+	if responseType != "code" {
+		return errs.NewErr(errs.OIDCInvalidRequest, "only the code response type is supported")
+	}
+	if !slices.Contains(strings.Fields(req.Scope), "openid") {
+		return errs.NewErr(errs.OIDCInvalidRequest, "the openid scope is required")
+	}
+	// a challenge without a method is plain, which only confidential clients may use
+	if req.CodeChallenge != "" && req.CodeChallengeMethod == "" {
+		req.CodeChallengeMethod = "plain"
+	}
+	switch {
+	case c.Public && (req.CodeChallenge == "" || req.CodeChallengeMethod != "S256"):
+		return errs.NewErr(errs.OIDCInvalidRequest, "public clients must use PKCE with S256")
+	case req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" && req.CodeChallengeMethod != "plain":
+		return errs.NewErr(errs.OIDCInvalidRequest, "unsupported code challenge method")
+	}
+	return nil
+}
+
+// NewOIDCAuthCode records the approval of the request by the user and returns the code for the client
+func NewOIDCAuthCode(user *model.User, req *model.OIDCAuthRequest) (string, error) {
+	code, err := randomOIDCString()
+	if err != nil {
+		return "", err
+	}
+	if err = db.DeleteExpiredOIDCAuthCodes(); err != nil {
+		return "", err
+	}
+	c := &model.OIDCAuthCode{
+		CodeHash:        hashOIDCSecret(code),
+		OIDCAuthRequest: *req,
+		ExpiresAt:       time.Now().Add(OIDCCodeTTL),
+	}
+	c.UserID = user.ID
+	c.AuthTime = time.Now()
+	if err = db.CreateOIDCAuthCode(c); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeOIDCAuthCode authenticates the client and redeems the code with the PKCE verifier
+func ExchangeOIDCAuthCode(clientID, clientSecret, code, redirectURI, verifier string) (*model.OIDCAuthRequest, error) {
+	c, err := getActiveOIDCClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Public && subtle.ConstantTimeCompare([]byte(hashOIDCSecret(clientSecret)), []byte(c.SecretHash)) != 1 {
+		return nil, errs.NewErr(errs.OIDCInvalidClient, "wrong client secret")
+	}
+	authCode, err := db.TakeOIDCAuthCode(hashOIDCSecret(code))
+	if err != nil || authCode.ClientID != clientID || authCode.RedirectURI != redirectURI {
+		return nil, errs.NewErr(errs.OIDCInvalidGrant, "unknown or expired code")
+	}
+	req, ok := &authCode.OIDCAuthRequest, true
+	switch req.CodeChallengeMethod {
+	case "S256":
+		sum := sha256.Sum256([]byte(verifier))
+		ok = base64.RawURLEncoding.EncodeToString(sum[:]) == req.CodeChallenge
+	case "plain":
+		ok = verifier == req.CodeChallenge
+	}
+	if !ok {
+		return nil, errs.NewErr(errs.OIDCInvalidGrant, "wrong code verifier")
+	}
+	return req, nil
+}
+
+// IssueOIDCTokens issues the ID and access tokens of a redeemed request
+func IssueOIDCTokens(issuer string, req *model.OIDCAuthRequest) (*model.OIDCTokens, error) {
+	p := idp.Get()
+	if p == nil {
+		return nil, errors.New("oidc provider is not initialized")
+	}
+	user, err := GetUserById(req.UserID)
+	if err != nil || user.Disabled {
+		return nil, errs.NewErr(errs.OIDCInvalidGrant, "user is disabled or deleted")
+	}
+	now := time.Now()
+	claims := oidcClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{req.ClientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(OIDCTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Scope: req.Scope,
+	}
+	if slices.Contains(strings.Fields(req.Scope), "profile") {
+		claims.PreferredUsername, claims.Name = user.Username, user.Username
+	}
+	accessClaims := claims
+	accessClaims.TokenUse = "access"
+	accessToken, err := p.Sign(accessClaims)
+	if err != nil {
+		return nil, err
+	}
+	idClaims := claims
+	idClaims.TokenUse = "id"
+	idClaims.Nonce = req.Nonce
+	idClaims.AuthTime = req.AuthTime.Unix()
+	idClaims.Scope = ""
+	idToken, err := p.Sign(idClaims)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OIDCTokens{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(OIDCTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       req.Scope,
+	}, nil
+}
+
+// GetOIDCUserInfo returns the claims of the user an access token was issued for
+func GetOIDCUserInfo(issuer, accessToken string) (map[string]any, error) {
+	p := idp.Get()
+	if p == nil {
+		return nil, errors.New("oidc provider is not initialized")
+	}
+	var claims oidcClaims
+	if err := p.Parse(accessToken, &claims); err != nil {
+		return nil, errs.NewErr(errs.OIDCInvalidToken, "%v", err)
+	}
+	if claims.TokenUse != "access" || claims.Issuer != issuer {
+		return nil, errs.NewErr(errs.OIDCInvalidToken, "not an access token of this issuer")
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, errs.NewErr(errs.OIDCInvalidToken, "invalid subject")
+	}
+	user, err := GetUserById(uint(id))
+	if err != nil || user.Disabled {
+		return nil, errs.NewErr(errs.OIDCInvalidToken, "user is disabled or deleted")
+	}
+	info := map[string]any{"sub": claims.Subject}
+	if slices.Contains(strings.Fields(claims.Scope), "profile") {
+		info["preferred_username"] = user.Username
+		info["name"] = user.Username
+	}
+	return info, nil
+}
@@ -0,0 +1,92 @@
+package op_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/idp"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestOIDCProvider(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %+v", err)
+	}
+	idp.Set(idp.New(key))
+	const issuer = "https://openlist.example.com/oidc"
+	client := &model.OIDCClient{Name: "media", RedirectURIs: "https://media.example.com/callback", Public: true}
+	if secret, err := op.CreateOIDCClient(client); err != nil || secret != "" {
+		t.Fatalf("failed to create public client: %q %+v", secret, err)
+	}
+	user := &model.User{Username: "oidc", BasePath: "/"}
+	if err = op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	verifier := "a-long-enough-code-verifier-for-the-test-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	req := &model.OIDCAuthRequest{
+		ClientID:            client.ClientID,
+		RedirectURI:         "https://media.example.com/callback",
+		Scope:               "openid profile",
+		Nonce:               "n-0S6",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	}
+	if err = op.CheckOIDCAuthRequest(req, "code"); err != nil {
+		t.Fatalf("expect the request to be valid, got %+v", err)
+	}
+	bad := *req
+	bad.RedirectURI = "https://evil.example.com/callback"
+	if err = op.CheckOIDCAuthRequest(&bad, "code"); !errors.Is(err, errs.OIDCInvalidClient) {
+		t.Errorf("expect an unregistered redirect uri to be rejected, got %+v", err)
+	}
+	bad = *req
+	bad.CodeChallenge = ""
+	if err = op.CheckOIDCAuthRequest(&bad, "code"); !errors.Is(err, errs.OIDCInvalidRequest) {
+		t.Errorf("expect a public client without PKCE to be rejected, got %+v", err)
+	}
+	for _, method := range []string{"plain", ""} {
+		bad = *req
+		bad.CodeChallenge, bad.CodeChallengeMethod = verifier, method
+		if err = op.CheckOIDCAuthRequest(&bad, "code"); !errors.Is(err, errs.OIDCInvalidRequest) {
+			t.Errorf("expect a public client with the %q challenge method to be rejected, got %+v", method, err)
+		}
+	}
+
+	code, err := op.NewOIDCAuthCode(user, req)
+	if err != nil {
+		t.Fatalf("failed to issue code: %+v", err)
+	}
+	if _, err = op.ExchangeOIDCAuthCode(client.ClientID, "", code, req.RedirectURI, "wrong"); !errors.Is(err, errs.OIDCInvalidGrant) {
+		t.Errorf("expect a wrong verifier to be rejected, got %+v", err)
+	}
+	if _, err = op.ExchangeOIDCAuthCode(client.ClientID, "", code, req.RedirectURI, verifier); !errors.Is(err, errs.OIDCInvalidGrant) {
+		t.Errorf("expect a code to be usable only once, got %+v", err)
+	}
+	code, err = op.NewOIDCAuthCode(user, req)
+	if err != nil {
+		t.Fatalf("failed to issue code: %+v", err)
+	}
+	approved, err := op.ExchangeOIDCAuthCode(client.ClientID, "", code, req.RedirectURI, verifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code: %+v", err)
+	}
+	tokens, err := op.IssueOIDCTokens(issuer, approved)
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %+v", err)
+	}
+	info, err := op.GetOIDCUserInfo(issuer, tokens.AccessToken)
+	if err != nil || info["preferred_username"] != "oidc" {
+		t.Errorf("expect the user info of oidc, got %v %+v", info, err)
+	}
+	if _, err = op.GetOIDCUserInfo(issuer, tokens.IDToken); !errors.Is(err, errs.OIDCInvalidToken) {
+		t.Errorf("expect an id token not to be accepted as access token, got %+v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package op
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// CheckPasswordPolicy checks a new password of the user against the length, character class and reuse rules
+func CheckPasswordPolicy(u *model.User, pwd string) error {
+	if pwd == "" {
+		return errors.WithStack(errs.EmptyPassword)
+	}
+	if minLength := getSettingInt(conf.PasswordMinLength, 0); len([]rune(pwd)) < minLength {
+		return errs.NewErr(errs.WeakPassword, "it must be at least %d characters long", minLength)
+	}
+	if minClasses := getSettingInt(conf.PasswordMinClasses, 0); passwordClasses(pwd) < minClasses {
+		return errs.NewErr(errs.WeakPassword, "it must contain at least %d of lowercase letters, uppercase letters, digits and symbols", minClasses)
+	}
+	history := getSettingInt(conf.PasswordHistory, 0)
+	if history <= 0 {
+		return nil
+	}
+	for _, h := range passwordHistory(u, history) {
+		salt, hash, _ := strings.Cut(h, ":")
+		if model.TwoHashPwd(pwd, salt) == hash {
+			return errors.WithStack(errs.PasswordReused)
+		}
+	}
+	return nil
+}
+
+// SetUserPassword sets the password of the user if it follows the password policy,
+// the replaced password is kept in the history to prevent its reuse
+func SetUserPassword(u *model.User, pwd string) error {
+	if err := CheckPasswordPolicy(u, pwd); err != nil {
+		return err
+	}
+	if history := getSettingInt(conf.PasswordHistory, 0); history > 0 {
+		u.PwdHistory = strings.Join(passwordHistory(u, history), "\n")
+	}
+	u.SetPassword(pwd)
+	return nil
+}
+
+// passwordHistory returns the current and the previous passwords of the user, at most n of them
+func passwordHistory(u *model.User, n int) []string {
+	var history []string
+	if u.PwdHash != "" {
+		history = append(history, u.Salt+":"+u.PwdHash)
+	}
+	for _, h := range strings.Split(u.PwdHistory, "\n") {
+		if h != "" {
+			history = append(history, h)
+		}
+	}
+	return history[:min(len(history), n)]
+}
+
+func passwordClasses(pwd string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range pwd {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// IsPasswordExpired reports whether the password of the user is older than the maximum age
+func IsPasswordExpired(u *model.User) bool {
+	maxAge := getSettingInt(conf.PasswordMaxAge, 0)
+	if maxAge <= 0 || u.PwdTS == 0 || u.PwdHash == "" {
+		return false
+	}
+	return time.Since(time.Unix(u.PwdTS, 0)) > time.Duration(maxAge)*24*time.Hour
+}
+
+func accountLockDuration() time.Duration {
+	return time.Duration(getSettingInt(conf.AccountLockDuration, 0)) * time.Minute
+}
+
+// CheckUserPassword validates a password hashed by sha256 of a user who is not locked,
+// a wrong password counts as a failed sign-in attempt.
+// Users of tenants requiring certificate login can not sign in with a password only.
+func CheckUserPassword(u *model.User, pwdStaticHash string) error {
+	if err := checkUserPassword(u, pwdStaticHash); err != nil {
+		return err
+	}
+	return CheckCertificateLoginRequired(u)
+}
+
+// CheckProtocolUserPassword is CheckUserPassword for WebDAV, FTP and SFTP, which have no way to enroll 2FA,
+// so the users required to enroll it are refused until they have done it on the web
+func CheckProtocolUserPassword(u *model.User, pwdStaticHash string) error {
+	if err := CheckUserPassword(u, pwdStaticHash); err != nil {
+		return err
+	}
+	return Check2FAEnrolled(u)
+}
+
+// CheckTenantUserPassword validates the password and the certificate proof of a tenant user, see CheckTenantCertificateLogin
+func CheckTenantUserPassword(u *model.User, pwdStaticHash, certPEM, nonce, signature string) error {
+	if err := checkUserPassword(u, pwdStaticHash); err != nil {
+		return err
+	}
+	return CheckTenantCertificateLogin(u, certPEM, nonce, signature)
+}
+
+// CheckAccountLocked fails with errs.AccountLocked if the account of the user is locked,
+// it is the check of the sign-ins validating no password, such as LDAP and SSH keys
+func CheckAccountLocked(u *model.User) error {
+	if u.IsLocked(accountLockDuration()) {
+		return errors.WithStack(errs.AccountLocked)
+	}
+	return nil
+}
+
+func checkUserPassword(u *model.User, pwdStaticHash string) error {
+	if err := CheckAccountLocked(u); err != nil {
+		return err
+	}
+	if err := u.ValidatePwdStaticHash(pwdStaticHash); err != nil {
+		if lockErr := LoginFailed(u); lockErr != nil {
+			return lockErr
+		}
+		return err
+	}
+	return nil
+}
+
+// LoginFailed counts a failed sign-in attempt of the user and locks the account at the threshold
+func LoginFailed(u *model.User) error {
+	threshold := getSettingInt(conf.AccountLockThreshold, 0)
+	if threshold <= 0 {
+		return nil
+	}
+	failed, lockedAt := u.FailedLogins, u.LockedAt
+	if lockedAt != nil && !u.IsLocked(accountLockDuration()) {
+		// the lock has expired, start counting again
+		failed, lockedAt = 0, nil
+	}
+	failed++
+	if failed >= threshold && lockedAt == nil {
+		now := time.Now()
+		lockedAt = &now
+	}
+	return updateUserLockout(u, failed, lockedAt)
+}
+
+// LoginSucceeded resets the failed sign-in attempts of the user
+func LoginSucceeded(u *model.User) error {
+	if u.FailedLogins == 0 && u.LockedAt == nil {
+		return nil
+	}
+	return updateUserLockout(u, 0, nil)
+}
+
+// UnlockUser unlocks the account of the user and resets its failed sign-in attempts
+func UnlockUser(id uint) error {
+	u, err := db.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	return updateUserLockout(u, 0, nil)
+}
+
+func updateUserLockout(u *model.User, failed int, lockedAt *time.Time) error {
+	if err := db.UpdateUserLockout(u.ID, failed, lockedAt); err != nil {
+		return err
+	}
+	u.FailedLogins, u.LockedAt = failed, lockedAt
+	dropCache(ChangeUser, u.Username)
+	return nil
+}
+
+// getSettingInt is setting.GetInt for this package, which the setting package depends on
+func getSettingInt(key string, defaultVal int) int {
+	item, err := GetSettingItemByKey(key)
+	if err != nil {
+		return defaultVal
+	}
+	i, err := strconv.Atoi(item.Value)
+	if err != nil {
+		return defaultVal
+	}
+	return i
+}
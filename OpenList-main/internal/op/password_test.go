@@ -0,0 +1,72 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	err := op.SaveSettingItems([]model.SettingItem{
+		{Key: conf.PasswordMinLength, Value: "8"},
+		{Key: conf.PasswordMinClasses, Value: "3"},
+		{Key: conf.PasswordHistory, Value: "2"},
+		{Key: conf.AccountLockThreshold, Value: "3"},
+		{Key: conf.AccountLockDuration, Value: "0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to save settings: %+v", err)
+	}
+	defer func() {
+		// the other tests do not expect a password policy
+		_ = op.SaveSettingItems([]model.SettingItem{
+			{Key: conf.PasswordMinLength, Value: "0"},
+			{Key: conf.PasswordMinClasses, Value: "0"},
+			{Key: conf.PasswordHistory, Value: "0"},
+			{Key: conf.AccountLockThreshold, Value: "0"},
+		})
+	}()
+	user := &model.User{Username: "policy", BasePath: "/"}
+	var tests = []struct {
+		password string
+		err      error
+	}{
+		{"Ab1!", errs.WeakPassword},
+		{"abcdefgh1", errs.WeakPassword},
+		{"Abcdefgh1", nil},
+		{"Abcdefgh2", nil},
+		{"Abcdefgh1", errs.PasswordReused},
+		{"Abcdefgh3", nil},
+		{"Abcdefgh1", nil},
+	}
+	for _, tt := range tests {
+		if err = op.SetUserPassword(user, tt.password); !errors.Is(err, tt.err) {
+			t.Errorf("password %s: expect %v, got %+v", tt.password, tt.err, err)
+		}
+	}
+	if err = op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = op.CheckUserPassword(user, model.StaticHash("wrong")); !errors.Is(err, errs.WrongPassword) {
+			t.Errorf("expect a wrong password, got %+v", err)
+		}
+	}
+	if err = op.CheckUserPassword(user, model.StaticHash("Abcdefgh1")); !errors.Is(err, errs.AccountLocked) {
+		t.Errorf("expect the account to be locked, got %+v", err)
+	}
+	if err = op.UnlockUser(user.ID); err != nil {
+		t.Fatalf("failed to unlock user: %+v", err)
+	}
+	user, err = op.GetUserByName("policy")
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	if err = op.CheckUserPassword(user, model.StaticHash("Abcdefgh1")); err != nil {
+		t.Errorf("expect the unlocked account to sign in, got %+v", err)
+	}
+}
@@ -24,6 +24,13 @@ func GetStorageAndActualPath(rawPath string) (storage driver.Driver, actualPath
 		err = errs.NewErr(errs.StorageNotFound, "rawPath: %s", rawPath)
 		return
 	}
+	// a tenant namespace must only resolve to storages of the same tenant
+	tenantID, err := GetTenantIDByPath(rawPath)
+	if err != nil || storage.GetStorage().TenantID != tenantID {
+		storage = nil
+		err = errs.NewErr(errs.StorageNotFound, "rawPath: %s", rawPath)
+		return
+	}
 	log.Debugln("use storage: ", storage.GetStorage().MountPath)
 	mountPath := utils.GetActualMountPath(storage.GetStorage().MountPath)
 	actualPath = utils.FixAndCleanPath(strings.TrimPrefix(rawPath, mountPath))
@@ -0,0 +1,235 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/go-cache"
+	log "github.com/sirupsen/logrus"
+)
+
+var quotaCache = cache.NewMemCache(cache.WithShards[[]model.Quota](2))
+
+// GetUserQuotas returns the quota of the user followed by the quota of its tenant if any
+func GetUserQuotas(user *model.User) ([]model.Quota, error) {
+	key := user.Username
+	if quotas, ok := quotaCache.Get(key); ok {
+		return quotas, nil
+	}
+	limits, err := quotaLimits(user, 0)
+	if err != nil {
+		return nil, err
+	}
+	quotas := make([]model.Quota, 0, len(limits))
+	for _, q := range limits {
+		usage, err := db.GetQuotaUsage(q.Scope, q.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		q.UsedBytes, q.UsedObjects = usage.UsedBytes, usage.UsedObjects
+		quotas = append(quotas, q)
+	}
+	quotaCache.Set(key, quotas, cache.WithEx[[]model.Quota](time.Second*5))
+	return quotas, nil
+}
+
+// quotaLimits returns the quota of the user and of the tenant,
+// the tenant of the user is used if tenantID is 0
+func quotaLimits(user *model.User, tenantID uint) ([]model.Quota, error) {
+	var quotas []model.Quota
+	if user != nil && !user.IsGuest() {
+		quotas = append(quotas, model.Quota{
+			Scope:        model.QuotaScopeUser,
+			OwnerID:      user.ID,
+			LimitBytes:   user.QuotaBytes,
+			LimitObjects: user.QuotaObjects,
+		})
+		if tenantID == 0 {
+			tenantID = user.TenantID
+		}
+	}
+	if tenantID != 0 {
+		tenant, err := GetTenantById(tenantID)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, model.Quota{
+			Scope:        model.QuotaScopeTenant,
+			OwnerID:      tenant.ID,
+			LimitBytes:   tenant.QuotaBytes,
+			LimitObjects: tenant.QuotaObjects,
+		})
+	}
+	return quotas, nil
+}
+
+// quotaCharge is what a write operation has charged to the quotas
+type quotaCharge struct {
+	quotas  []model.Quota
+	bytes   int64
+	objects int64
+}
+
+// chargeQuota charges a change of the storage to the user in ctx and to the tenant owning the storage,
+// negative amounts are released. The usage is tracked whether a limit is set or not, so that it is
+// known when a limit is set later, and only the limits set are enforced. A removal is released from
+// the user removing, so a move across storages, a put followed by a remove, leaves the usage unchanged.
+// Nothing is charged for temporary objects, which are marked by conf.NoQuotaKey in ctx.
+func chargeQuota(ctx context.Context, storage driver.Driver, bytes, objects int64) (*quotaCharge, error) {
+	if ctx.Value(conf.NoQuotaKey) != nil {
+		return &quotaCharge{}, nil
+	}
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	quotas, err := quotaLimits(user, storage.GetStorage().TenantID)
+	if err != nil {
+		return nil, err
+	}
+	c := &quotaCharge{quotas: quotas}
+	if len(c.quotas) == 0 {
+		return c, nil
+	}
+	if err = c.add(bytes, objects, true); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// add changes the charge by the given amounts, the limits are only checked if enforce is set
+func (c *quotaCharge) add(bytes, objects int64, enforce bool) error {
+	if len(c.quotas) == 0 {
+		return nil
+	}
+	quotas := c.quotas
+	if !enforce {
+		quotas = make([]model.Quota, len(c.quotas))
+		for i, q := range c.quotas {
+			quotas[i] = model.Quota{Scope: q.Scope, OwnerID: q.OwnerID}
+		}
+	}
+	if bytes > 0 || objects > 0 {
+		if err := db.ReserveQuota(quotas, max(bytes, 0), max(objects, 0)); err != nil {
+			return err
+		}
+	}
+	if bytes < 0 || objects < 0 {
+		if err := db.ReleaseQuota(quotas, -min(bytes, 0), -min(objects, 0)); err != nil {
+			return err
+		}
+	}
+	c.bytes += bytes
+	c.objects += objects
+	// the cached quotas are keyed by username, which is not known here
+	quotaCache.Clear()
+	return nil
+}
+
+// settle replaces the charge with the actual usage once it is known
+func (c *quotaCharge) settle(bytes, objects int64) {
+	if err := c.add(bytes-c.bytes, objects-c.objects, false); err != nil {
+		log.Errorf("failed settle quota: %+v", err)
+	}
+}
+
+// refund gives back everything charged, used when the write failed
+func (c *quotaCharge) refund() {
+	c.settle(0, 0)
+}
+
+func (c *quotaCharge) accounted() bool {
+	return len(c.quotas) > 0
+}
+
+// settleObjs settles the charge to the usage of the objects written to the dir.
+// The files are settled at once, directories are walked in the background so that
+// a large tree is not listed before the write returns.
+func (c *quotaCharge) settleObjs(ctx context.Context, storage driver.Driver, dirPath string, objs []model.Obj) {
+	if !c.accounted() {
+		return
+	}
+	var bytes, objects int64
+	var dirs []model.Obj
+	for _, obj := range objs {
+		if obj.IsDir() {
+			dirs = append(dirs, obj)
+			continue
+		}
+		bytes += max(obj.GetSize(), 0)
+		objects++
+	}
+	if len(dirs) == 0 {
+		c.settle(bytes, objects)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		bytes, objects := bytes, objects
+		for _, dir := range dirs {
+			b, n, err := treeUsage(ctx, storage, stdpath.Join(dirPath, dir.GetName()))
+			if err != nil {
+				log.Warnf("failed to get usage of %s, quota will be partially charged: %+v", dir.GetName(), err)
+			}
+			bytes += b
+			objects += n
+		}
+		c.settle(bytes, objects)
+	}()
+}
+
+// storedSize returns the size of an object just written as reported by the storage,
+// newObj is the object returned by the driver if any
+func storedSize(ctx context.Context, storage driver.Driver, path string, newObj model.Obj) (int64, bool) {
+	if newObj == nil {
+		obj, err := GetUnwrap(ctx, storage, path)
+		if err != nil {
+			log.Warnf("failed to get size of %s, quota is charged with the declared size: %+v", path, err)
+			return 0, false
+		}
+		newObj = obj
+	}
+	return max(newObj.GetSize(), 0), true
+}
+
+// cachedUsage returns the bytes and files under the object as far as the listings are cached.
+// The storage is never listed, a directory with no cached listing counts as the size it reports.
+func cachedUsage(storage driver.Driver, path string, obj model.Obj) (bytes, objects int64) {
+	if !obj.IsDir() {
+		return max(obj.GetSize(), 0), 1
+	}
+	objs, ok := listCache.Get(Key(storage, path))
+	if !ok {
+		return max(obj.GetSize(), 0), 0
+	}
+	for _, o := range objs {
+		b, n := cachedUsage(storage, stdpath.Join(path, o.GetName()), o)
+		bytes += b
+		objects += n
+	}
+	return bytes, objects
+}
+
+// treeUsage returns the bytes and files under the directory, walking it recursively
+func treeUsage(ctx context.Context, storage driver.Driver, path string) (bytes, objects int64, err error) {
+	objs, err := List(ctx, storage, path, model.ListArgs{})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, o := range objs {
+		if !o.IsDir() {
+			bytes += max(o.GetSize(), 0)
+			objects++
+			continue
+		}
+		b, n, err := treeUsage(ctx, storage, stdpath.Join(path, o.GetName()))
+		if err != nil {
+			return bytes, objects, err
+		}
+		bytes += b
+		objects += n
+	}
+	return bytes, objects, nil
+}
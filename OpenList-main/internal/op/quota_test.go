@@ -0,0 +1,72 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+func TestUserQuota(t *testing.T) {
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver: "Local", MountPath: "/quota", Addition: `{"root_folder_path":"` + t.TempDir() + `"}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	driver, err := op.GetStorageByMountPath("/quota")
+	if err != nil {
+		t.Fatalf("failed to get storage: %+v", err)
+	}
+	user := &model.User{Username: "quota", BasePath: "/", QuotaBytes: 10, QuotaObjects: 2}
+	if err = op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	ctx := context.WithValue(context.Background(), conf.UserKey, user)
+	put := func(name, content string) error {
+		return op.Put(ctx, driver, "/", &stream.FileStream{
+			Obj:    &model.Object{Name: name, Size: int64(len(content))},
+			Reader: strings.NewReader(content),
+		}, nil)
+	}
+	var tests = []struct {
+		name    string
+		content string
+		isErr   bool
+	}{
+		{name: "a", content: "123456"},
+		{name: "b", content: "123456", isErr: true},
+		{name: "b", content: "1234"},
+		{name: "c", content: "", isErr: true},
+	}
+	for _, tt := range tests {
+		err := put(tt.name, tt.content)
+		if tt.isErr != errors.Is(err, errs.QuotaExceeded) {
+			t.Errorf("put %s with %d bytes: expect quota exceeded %v, got %+v", tt.name, len(tt.content), tt.isErr, err)
+		}
+	}
+	assertUsage := func(bytes, objects int64) {
+		quotas, err := op.GetUserQuotas(user)
+		if err != nil {
+			t.Fatalf("failed to get quotas: %+v", err)
+		}
+		if len(quotas) != 1 || quotas[0].UsedBytes != bytes || quotas[0].UsedObjects != objects {
+			t.Errorf("expect usage %d bytes %d objects, got %+v", bytes, objects, quotas)
+		}
+	}
+	assertUsage(10, 2)
+	if err = op.Remove(ctx, driver, "/a"); err != nil {
+		t.Fatalf("failed to remove: %+v", err)
+	}
+	assertUsage(4, 1)
+	if err = put("c", "123456"); err != nil {
+		t.Errorf("put c after the removal: %+v", err)
+	}
+	assertUsage(10, 2)
+}
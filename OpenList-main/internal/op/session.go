@@ -0,0 +1,136 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+// sessionCache caches sessions by id, revoked sessions are removed at once
+var sessionCache = cache.NewMemCache(cache.WithShards[*model.Session](2))
+
+const (
+	// sessions are reloaded after this interval so that revocations on other instances are picked up
+	sessionCacheExpiry = time.Minute
+	// the last seen time is written at most once per interval
+	sessionTouchInterval = time.Minute
+)
+
+// CreateSession records a login of the user which is valid until expiresAt
+func CreateSession(user *model.User, ip, userAgent string, expiresAt time.Time) (*model.Session, error) {
+	if err := db.DeleteExpiredSessions(user.ID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &model.Session{
+		ID:        random.String(32),
+		UserID:    user.ID,
+		Device:    deviceOf(userAgent),
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: expiresAt,
+	}
+	if err := db.CreateSession(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CheckSession returns the session if it belongs to the user and is neither expired nor revoked
+func CheckSession(id string, userId uint) (*model.Session, error) {
+	if id == "" {
+		return nil, errors.WithStack(errs.SessionRevoked)
+	}
+	s, ok := sessionCache.Get(id)
+	if !ok {
+		var err error
+		s, err = db.GetSessionById(id)
+		if err != nil {
+			return nil, errors.WithStack(errs.SessionRevoked)
+		}
+		sessionCache.Set(id, s, cache.WithEx[*model.Session](sessionCacheExpiry))
+	}
+	if s.UserID != userId || s.IsExpired() {
+		return nil, errors.WithStack(errs.SessionRevoked)
+	}
+	if time.Since(s.LastSeen) > sessionTouchInterval {
+		now := time.Now()
+		if err := db.UpdateSessionLastSeen(s.ID, now); err != nil {
+			return nil, err
+		}
+		touched := *s
+		touched.LastSeen = now
+		sessionCache.Set(id, &touched, cache.WithEx[*model.Session](sessionCacheExpiry))
+		s = &touched
+	}
+	return s, nil
+}
+
+func GetSessionsByUserId(userId uint) ([]model.Session, error) {
+	return db.GetSessionsByUserId(userId)
+}
+
+// RevokeSession revokes a session of the user, its token stops working immediately
+func RevokeSession(userId uint, id string) error {
+	s, err := db.GetSessionById(id)
+	if err != nil {
+		return err
+	}
+	if s.UserID != userId {
+		return errors.WithStack(errs.PermissionDenied)
+	}
+	defer dropCache(ChangeSession, id)
+	return db.DeleteSessionById(id)
+}
+
+// RevokeUserSessions revokes all sessions of the user, which logs it out everywhere
+func RevokeUserSessions(userId uint) error {
+	sessions, err := db.GetSessionsByUserId(userId)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		defer dropCache(ChangeSession, s.ID)
+	}
+	return db.DeleteSessionsByUserId(userId)
+}
+
+// deviceOf describes the client by the browser and operating system in the user agent
+func deviceOf(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	pick := func(names [][2]string) string {
+		for _, n := range names {
+			if strings.Contains(ua, n[0]) {
+				return n[1]
+			}
+		}
+		return ""
+	}
+	// the order matters, e.g. the user agent of Edge also contains Chrome and Safari
+	browser := pick([][2]string{
+		{"edg/", "Edge"}, {"opr/", "Opera"}, {"firefox/", "Firefox"}, {"chrome/", "Chrome"},
+		{"safari/", "Safari"}, {"curl/", "curl"}, {"okhttp", "OkHttp"},
+	})
+	os := pick([][2]string{
+		{"android", "Android"}, {"iphone", "iOS"}, {"ipad", "iPadOS"}, {"windows", "Windows"},
+		{"mac os", "macOS"}, {"cros", "ChromeOS"}, {"linux", "Linux"},
+	})
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown"
+	}
+}
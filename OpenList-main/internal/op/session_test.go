@@ -0,0 +1,49 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestSession(t *testing.T) {
+	user := &model.User{Username: "session", BasePath: "/"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	ua := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Edg/120.0"
+	s, err := op.CreateSession(user, "127.0.0.1", ua, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create session: %+v", err)
+	}
+	if s.Device != "Edge on Windows" {
+		t.Errorf("expect the device to be Edge on Windows, got %s", s.Device)
+	}
+	other, err := op.CreateSession(user, "127.0.0.1", "curl/8.0", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create session: %+v", err)
+	}
+	if _, err = op.CheckSession(s.ID, user.ID); err != nil {
+		t.Errorf("expect the session to be valid, got %+v", err)
+	}
+	if _, err = op.CheckSession(s.ID, user.ID+1); !errors.Is(err, errs.SessionRevoked) {
+		t.Errorf("expect the session of another user to be rejected, got %+v", err)
+	}
+	if err = op.RevokeSession(user.ID, s.ID); err != nil {
+		t.Fatalf("failed to revoke session: %+v", err)
+	}
+	if _, err = op.CheckSession(s.ID, user.ID); !errors.Is(err, errs.SessionRevoked) {
+		t.Errorf("expect the revoked session to be rejected, got %+v", err)
+	}
+	user.Disabled = true
+	if err = op.UpdateUser(user); err != nil {
+		t.Fatalf("failed to disable user: %+v", err)
+	}
+	if _, err = op.CheckSession(other.ID, user.ID); !errors.Is(err, errs.SessionRevoked) {
+		t.Errorf("expect the sessions of a disabled user to be revoked, got %+v", err)
+	}
+}
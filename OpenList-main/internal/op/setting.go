@@ -172,6 +172,7 @@ func SaveSettingItems(items []model.SettingItem) error {
 		return fmt.Errorf("failed save setting: %+v", err)
 	}
 		SettingCacheUpdate()
+	notifyChange(ChangeSetting, "")
 	return nil
 }
 
@@ -189,6 +190,7 @@ func SaveSettingItem(item *model.SettingItem) (err error) {
 		return fmt.Errorf("failed save setting on %s: %+v", item.Key, err)
 	}
 	SettingCacheUpdate()
+	notifyChange(ChangeSetting, item.Key)
 	return nil
 }
 
@@ -201,6 +203,7 @@ func DeleteSettingItemByKey(key string) error {
 		return errors.Errorf("setting [%s] is not deprecated", key)
 	}
 	SettingCacheUpdate()
+	defer notifyChange(ChangeSetting, key)
 	return db.DeleteSettingItemByKey(key)
 }
 
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -62,6 +63,13 @@ func GetSharingById(id string, refresh ...bool) (*model.Sharing, error) {
 		if err = utils.Json.UnmarshalFromString(s.FilesRaw, &files); err != nil {
 			files = make([]string, 0)
 		}
+		// never serve a sharing whose files or creator have left its tenant namespace
+		if !creator.IsAdmin() && creator.TenantID != s.TenantID {
+			return nil, errors.WithMessagef(errs.PathNotInTenant, "sharing [%s]", id)
+		}
+		if _, err = getSharingTenantID(files); err != nil {
+			return nil, errors.WithMessagef(err, "sharing [%s]", id)
+		}
 		return &model.Sharing{
 			SharingDB: s,
 			Files:     files,
@@ -71,8 +79,8 @@ func GetSharingById(id string, refresh ...bool) (*model.Sharing, error) {
 	return sharing, err
 }
 
-func GetSharings(pageIndex, pageSize int) ([]model.Sharing, int64, error) {
-	s, cnt, err := db.GetSharings(pageIndex, pageSize)
+func GetSharings(pageIndex, pageSize int, tenantID *uint) ([]model.Sharing, int64, error) {
+	s, cnt, err := db.GetSharings(pageIndex, pageSize, tenantID)
 	if err != nil {
 		return nil, 0, errors.WithStack(err)
 	}
@@ -112,8 +120,39 @@ func GetSharingUnwrapPath(sharing *model.Sharing, path string) (unwrapPath strin
 	return stdpath.Join(mapPath, rest), nil
 }
 
+// getSharingTenantID get the tenant namespace of the shared files, which must all be in the same one
+func getSharingTenantID(files []string) (uint, error) {
+	var tenantID uint
+	for i, f := range files {
+		id, err := GetTenantIDByPath(f)
+		if err != nil {
+			return 0, err
+		}
+		if i > 0 && id != tenantID {
+			return 0, errors.WithStack(errs.PathNotInTenant)
+		}
+		tenantID = id
+	}
+	return tenantID, nil
+}
+
+// setSharingTenant scopes the sharing to the tenant namespace of its files
+func setSharingTenant(sharing *model.Sharing) (err error) {
+	sharing.TenantID, err = getSharingTenantID(sharing.Files)
+	if err != nil {
+		return err
+	}
+	if !sharing.Creator.IsAdmin() && sharing.TenantID != sharing.Creator.TenantID {
+		return errors.WithStack(errs.PathNotInTenant)
+	}
+	return nil
+}
+
 func CreateSharing(sharing *model.Sharing) (id string, err error) {
 	sharing.CreatorId = sharing.Creator.ID
+	if err = setSharingTenant(sharing); err != nil {
+		return "", err
+	}
 	sharing.FilesRaw, err = utils.Json.MarshalToString(utils.MustSliceConvert(sharing.Files, utils.FixAndCleanPath))
 	if err != nil {
 		return "", errors.WithStack(err)
@@ -124,6 +163,9 @@ func CreateSharing(sharing *model.Sharing) (id string, err error) {
 func UpdateSharing(sharing *model.Sharing, skipMarshal ...bool) (err error) {
 	if !utils.IsBool(skipMarshal...) {
 		sharing.CreatorId = sharing.Creator.ID
+		if err = setSharingTenant(sharing); err != nil {
+			return err
+		}
 		sharing.FilesRaw, err = utils.Json.MarshalToString(utils.MustSliceConvert(sharing.Files, utils.FixAndCleanPath))
 		if err != nil {
 			return errors.WithStack(err)
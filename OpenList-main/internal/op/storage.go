@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,8 +47,11 @@ func GetStorageByMountPath(mountPath string) (driver.Driver, error) {
 // then instantiate corresponding driver and save it in memory
 func CreateStorage(ctx context.Context, storage model.Storage) (uint, error) {
 	storage.Modified = time.Now()
-	storage.MountPath = utils.FixAndCleanPath(storage.MountPath)
 	var err error
+	storage.MountPath, err = resolveTenantPath(&storage.TenantID, storage.MountPath)
+	if err != nil {
+		return 0, errors.WithMessage(err, "invalid mount path")
+	}
 	// check driver first
 	driverName := storage.Driver
 	driverNew, err := GetDriver(driverName)
@@ -60,6 +64,7 @@ func CreateStorage(ctx context.Context, storage model.Storage) (uint, error) {
 	if err != nil {
 		return storage.ID, errors.WithMessage(err, "failed create storage in database")
 	}
+	notifyStorageChange(storage.ID)
 	// already has an id
 	err = initStorage(ctx, storage, storageDriver)
 	go callStorageHooks("add", storageDriver)
@@ -180,6 +185,7 @@ func EnableStorage(ctx context.Context, id uint) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed update storage in db")
 	}
+	notifyStorageChange(storage.ID)
 	err = LoadStorage(ctx, *storage)
 	if err != nil {
 		return errors.WithMessage(err, "failed load storage")
@@ -210,6 +216,7 @@ func DisableStorage(ctx context.Context, id uint) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed update storage in db")
 	}
+	notifyStorageChange(storage.ID)
 	storagesMap.Delete(storage.MountPath)
 	go callStorageHooks("del", storageDriver)
 	return nil
@@ -227,11 +234,15 @@ func UpdateStorage(ctx context.Context, storage model.Storage) error {
 		return errors.Errorf("driver cannot be changed")
 	}
 	storage.Modified = time.Now()
-	storage.MountPath = utils.FixAndCleanPath(storage.MountPath)
+	storage.MountPath, err = resolveTenantPath(&storage.TenantID, storage.MountPath)
+	if err != nil {
+		return errors.WithMessage(err, "invalid mount path")
+	}
 	err = db.UpdateStorage(&storage)
 	if err != nil {
 		return errors.WithMessage(err, "failed update storage in database")
 	}
+	notifyStorageChange(storage.ID)
 	if storage.Disabled {
 		return nil
 	}
@@ -276,10 +287,16 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 	if err := db.DeleteStorageById(id); err != nil {
 		return errors.WithMessage(err, "failed delete storage in database")
 	}
+	notifyStorageChange(id)
 	return nil
 }
 
+func notifyStorageChange(id uint) {
+	notifyChange(ChangeStorage, strconv.FormatUint(uint64(id), 10))
+}
+
 // MustSaveDriverStorage call from specific driver
+// the other instances are not told, as they would init the storage and save it again
 func MustSaveDriverStorage(driver driver.Driver) {
 	err := saveDriverStorage(driver)
 	if err != nil {
@@ -385,3 +402,15 @@ func GetBalancedStorage(path string) driver.Driver {
 		return storages[i]
 	}
 }
+
+// GetStorageDetails get the space of the storage, errs.NotImplement if the driver can't tell
+func GetStorageDetails(ctx context.Context, storage driver.Driver) (*model.StorageDetails, error) {
+	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
+		return nil, errors.Errorf("storage not init: %s", storage.GetStorage().Status)
+	}
+	s, ok := storage.(driver.WithDetails)
+	if !ok {
+		return nil, errs.NotImplement
+	}
+	return s.GetDetails(ctx)
+}
@@ -0,0 +1,198 @@
+package op
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/generic_sync"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// healthHistoryLen is the number of checks kept for each storage
+	healthHistoryLen = 20
+	// healthCheckTimeout bounds a probe together with the re-init after it
+	healthCheckTimeout = time.Minute
+	// healthRetryDelay is the delay after the first failed check, it doubles after each failure
+	healthRetryDelay = 30 * time.Second
+	// healthReinitFailures is the number of failed probes in a row after which a working storage is initialized again
+	healthReinitFailures = 3
+)
+
+type storageHealth struct {
+	sync.Mutex
+	model.StorageHealth
+	checking bool
+}
+
+var storageHealthMap generic_sync.MapOf[uint, *storageHealth]
+
+func getStorageHealth(id uint) *storageHealth {
+	h, _ := storageHealthMap.LoadOrStore(id, &storageHealth{StorageHealth: model.StorageHealth{ID: id}})
+	return h
+}
+
+// GetStorageHealths returns the health of the loaded storages, ordered by mount path
+func GetStorageHealths() []model.StorageHealth {
+	storages := GetAllStorages()
+	res := make([]model.StorageHealth, 0, len(storages))
+	for _, storage := range storages {
+		s := storage.GetStorage()
+		item := model.StorageHealth{ID: s.ID}
+		if h, ok := storageHealthMap.Load(s.ID); ok {
+			h.Lock()
+			item = h.StorageHealth
+			item.History = slices.Clone(h.History)
+			h.Unlock()
+		}
+		item.MountPath, item.Driver, item.Status = s.MountPath, s.Driver, s.Status
+		res = append(res, item)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].MountPath < res[j].MountPath })
+	return res
+}
+
+// CheckStoragesHealth starts the checks of the storages which are due and returns at once
+func CheckStoragesHealth(ctx context.Context) {
+	interval := time.Duration(getSettingInt(conf.StorageHealthCheckInterval, 300)) * time.Second
+	if interval <= 0 {
+		return
+	}
+	now := time.Now()
+	loaded := make(map[uint]struct{})
+	for _, storage := range GetAllStorages() {
+		id := storage.GetStorage().ID
+		loaded[id] = struct{}{}
+		h := getStorageHealth(id)
+		h.Lock()
+		due := !h.checking && !now.Before(h.NextCheck)
+		h.checking = h.checking || due
+		h.Unlock()
+		if due {
+			go checkStorageHealth(ctx, storage, h, interval)
+		}
+	}
+	// forget the storages which are gone
+	storageHealthMap.Range(func(id uint, _ *storageHealth) bool {
+		if _, ok := loaded[id]; !ok {
+			storageHealthMap.Delete(id)
+		}
+		return true
+	})
+}
+
+// CheckStorageHealth checks the storage at once, out of its schedule
+func CheckStorageHealth(ctx context.Context, storage driver.Driver) (model.HealthCheck, error) {
+	h := getStorageHealth(storage.GetStorage().ID)
+	h.Lock()
+	if h.checking {
+		h.Unlock()
+		return model.HealthCheck{}, errors.New("the storage is being checked")
+	}
+	h.checking = true
+	h.Unlock()
+	interval := time.Duration(getSettingInt(conf.StorageHealthCheckInterval, 300)) * time.Second
+	return checkStorageHealth(ctx, storage, h, interval), nil
+}
+
+// checkStorageHealth probes a working storage and initializes it again if the probes keep failing,
+// a broken storage is initialized again directly and the other instances are told to load it again.
+// The storage hooks are called with "status" when the storage starts or stops working.
+func checkStorageHealth(ctx context.Context, storage driver.Driver, h *storageHealth, interval time.Duration) model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	s := storage.GetStorage()
+	oldStatus := s.Status
+	check := model.HealthCheck{Time: time.Now()}
+	var err error
+	if oldStatus == WORK {
+		err = probeStorage(ctx, storage)
+	}
+	h.Lock()
+	failures := h.Failures
+	h.Unlock()
+	if err != nil && failures+1 < healthReinitFailures {
+		log.Warnf("health probe of storage [%s] failed: %+v", s.MountPath, err)
+	} else if oldStatus != WORK || err != nil {
+		if err != nil {
+			log.Warnf("health probe of storage [%s] failed %d times in a row, init it again: %+v", s.MountPath, failures+1, err)
+		}
+		check.Reinit = true
+		err = reinitStorage(ctx, storage)
+		if err == nil {
+			// only the leader checks the storages, the others load the repaired one again
+			notifyStorageChange(s.ID)
+		}
+	}
+	check.Latency = time.Since(check.Time).Milliseconds()
+	check.Healthy = err == nil
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	h.Lock()
+	h.History = append(h.History, check)
+	if len(h.History) > healthHistoryLen {
+		h.History = slices.Clone(h.History[len(h.History)-healthHistoryLen:])
+	}
+	if check.Healthy {
+		h.Failures = 0
+		h.NextCheck = time.Now().Add(interval)
+	} else {
+		h.Failures++
+		h.NextCheck = time.Now().Add(healthBackoff(h.Failures))
+	}
+	h.checking = false
+	h.Unlock()
+
+	if newStatus := storage.GetStorage().Status; (oldStatus == WORK) != (newStatus == WORK) {
+		if newStatus == WORK {
+			log.Infof("storage [%s] works again", s.MountPath)
+		} else {
+			log.Errorf("storage [%s] stopped working: %s", s.MountPath, newStatus)
+		}
+		go callStorageHooks("status", storage)
+	}
+	return check
+}
+
+// healthBackoff is the delay before the next check after the failures in a row
+func healthBackoff(failures int) time.Duration {
+	maxDelay := time.Duration(getSettingInt(conf.StorageHealthMaxBackoff, 3600)) * time.Second
+	delay := healthRetryDelay
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, max(maxDelay, healthRetryDelay))
+}
+
+// probeStorage asks the driver for the root, or lists the root if it can't get it alone.
+// The root is listed by the driver itself, bypassing the cache and the status of the storage,
+// so that a backend which stopped working, such as with an expired token, is noticed at once.
+func probeStorage(ctx context.Context, storage driver.Driver) error {
+	if r, ok := storage.(driver.GetRooter); ok {
+		_, err := r.GetRoot(ctx)
+		return err
+	}
+	root, err := GetUnwrap(ctx, storage, "/")
+	if err != nil {
+		return err
+	}
+	_, err = storage.List(ctx, root, model.ListArgs{Refresh: true})
+	return err
+}
+
+// reinitStorage drops the storage and initializes it again with the same settings
+func reinitStorage(ctx context.Context, storage driver.Driver) error {
+	if err := storage.Drop(ctx); err != nil {
+		log.Warnf("failed drop storage [%s]: %+v", storage.GetStorage().MountPath, err)
+	}
+	return initStorage(ctx, *storage.GetStorage(), storage)
+}
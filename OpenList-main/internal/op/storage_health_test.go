@@ -0,0 +1,108 @@
+package op_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestStorageHealth(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/health",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	defer op.DeleteStorageById(ctx, id)
+	storage, err := op.GetStorageByMountPath("/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transitions := make(chan string, 4)
+	op.RegisterStorageHook(func(typ string, s driver.Driver) {
+		if typ == "status" && s.GetStorage().MountPath == "/health" {
+			transitions <- s.GetStorage().Status
+		}
+	})
+
+	check, err := op.CheckStorageHealth(ctx, storage)
+	if err != nil || !check.Healthy || check.Reinit {
+		t.Fatalf("expect a healthy probe, got %+v, %v", check, err)
+	}
+
+	// the storage is only initialized again after the third failed probe in a row, which fails too
+	if err = os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		check, _ = op.CheckStorageHealth(ctx, storage)
+		if check.Healthy || check.Reinit || storage.GetStorage().Status != op.WORK {
+			t.Fatalf("expect a failed probe without re-init, got %+v, status %s", check, storage.GetStorage().Status)
+		}
+	}
+	check, _ = op.CheckStorageHealth(ctx, storage)
+	if check.Healthy || !check.Reinit {
+		t.Fatalf("expect a failed re-init, got %+v", check)
+	}
+	if storage.GetStorage().Status == op.WORK {
+		t.Errorf("expect the status to tell the error")
+	}
+	select {
+	case status := <-transitions:
+		if status == op.WORK {
+			t.Errorf("expect the hook to see the broken status")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expect the status hook to be called")
+	}
+	// the delay before the next check doubles after each failure
+	op.CheckStorageHealth(ctx, storage)
+	health := getHealth(t, id)
+	if health.Failures != 4 {
+		t.Errorf("expect 4 failures in a row, got %d", health.Failures)
+	}
+	if delay := time.Until(health.NextCheck); delay < 230*time.Second || delay > 4*time.Minute {
+		t.Errorf("expect the next check in about 4 minutes, got %s", delay)
+	}
+
+	// the storage works again once re-initialized
+	if err = os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	check, _ = op.CheckStorageHealth(ctx, storage)
+	if !check.Healthy || !check.Reinit || storage.GetStorage().Status != op.WORK {
+		t.Fatalf("expect a successful re-init, got %+v, status %s", check, storage.GetStorage().Status)
+	}
+	select {
+	case status := <-transitions:
+		if status != op.WORK {
+			t.Errorf("expect the hook to see the storage working, got %s", status)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expect the status hook to be called")
+	}
+	health = getHealth(t, id)
+	if health.Failures != 0 || len(health.History) != 6 {
+		t.Errorf("expect the failures to be reset and 6 checks in the history, got %+v", health)
+	}
+}
+
+func getHealth(t *testing.T, id uint) model.StorageHealth {
+	t.Helper()
+	for _, h := range op.GetStorageHealths() {
+		if h.ID == id {
+			return h
+		}
+	}
+	t.Fatalf("no health of storage %d", id)
+	return model.StorageHealth{}
+}
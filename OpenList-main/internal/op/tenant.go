@@ -0,0 +1,166 @@
+package op
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var tenantCache = cache.NewMemCache(cache.WithShards[*model.Tenant](2))
+var tenantG singleflight.Group[*model.Tenant]
+
+// 租户名用作命名空间目录名，只允许小写字母、数字、下划线和连字符
+var tenantNameReg = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func GetTenantByName(name string) (*model.Tenant, error) {
+	if tenant, ok := tenantCache.Get(name); ok {
+		if tenant == nil {
+			return nil, errs.TenantNotFound
+		}
+		return tenant, nil
+	}
+	tenant, err, _ := tenantG.Do(name, func() (*model.Tenant, error) {
+		_tenant, err := db.GetTenantByName(name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				tenantCache.Set(name, nil)
+				return nil, errs.TenantNotFound
+			}
+			return nil, err
+		}
+		tenantCache.Set(name, _tenant, cache.WithEx[*model.Tenant](time.Hour))
+		return _tenant, nil
+	})
+	return tenant, err
+}
+
+func GetTenantById(id uint) (*model.Tenant, error) {
+	tenant, err := db.GetTenantById(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.WithStack(errs.TenantNotFound)
+	}
+	return tenant, err
+}
+
+func GetTenants(pageIndex, pageSize int) ([]model.Tenant, int64, error) {
+	return db.GetTenants(pageIndex, pageSize)
+}
+
+func CreateTenant(t *model.Tenant) error {
+	if !tenantNameReg.MatchString(t.Name) {
+		return errors.Errorf("invalid tenant name: %s", t.Name)
+	}
+	if err := checkTenantCertLogin(t); err != nil {
+		return err
+	}
+	defer dropCache(ChangeTenant, t.Name)
+	return db.CreateTenant(t)
+}
+
+// UpdateTenant 更新租户信息，租户名决定了命名空间路径，不允许修改
+func UpdateTenant(t *model.Tenant) error {
+	old, err := GetTenantById(t.ID)
+	if err != nil {
+		return err
+	}
+	if old.Name != t.Name {
+		return errors.New("tenant name can not be changed")
+	}
+	if err = checkTenantCertLogin(t); err != nil {
+		return err
+	}
+	t.CreatedAt = old.CreatedAt
+	defer dropCache(ChangeTenant, old.Name)
+	return db.UpdateTenant(t)
+}
+
+func checkTenantCertLogin(t *model.Tenant) error {
+	switch t.CertLoginMode() {
+	case model.TenantCertLoginDisabled, model.TenantCertLoginOptional, model.TenantCertLoginRequired:
+		return nil
+	default:
+		return errors.Errorf("invalid certificate login mode: %s", t.CertLogin)
+	}
+}
+
+// DeleteTenantById 删除租户，命名空间中仍有用户、存储、元信息或分享时不允许删除
+func DeleteTenantById(id uint) error {
+	old, err := GetTenantById(id)
+	if err != nil {
+		return err
+	}
+	count, err := db.CountTenantResources(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.WithStack(errs.TenantNotEmpty)
+	}
+	defer dropCache(ChangeTenant, old.Name)
+	if err = db.DeleteTenantById(id); err != nil {
+		return err
+	}
+	return db.DeleteQuotaUsage(model.QuotaScopeTenant, id)
+}
+
+// GetTenantIDByPath 获取路径所属租户的ID，不属于任何租户命名空间时返回0
+// 路径位于不存在或已禁用的租户命名空间中时返回错误
+func GetTenantIDByPath(path string) (uint, error) {
+	name, ok := model.TenantNameOfPath(path)
+	if !ok {
+		if model.IsTenantPath(path) {
+			// 命名空间的父目录本身不属于任何租户
+			return 0, errors.WithStack(errs.PathNotInTenant)
+		}
+		return 0, nil
+	}
+	tenant, err := GetTenantByName(name)
+	if err != nil {
+		return 0, err
+	}
+	if tenant.Disabled {
+		return 0, errors.WithStack(errs.TenantDisabled)
+	}
+	return tenant.ID, nil
+}
+
+// resolveTenantPath 将路径限定在租户命名空间中
+// tenantID 不为0时，不在该租户命名空间中的路径被视为相对于命名空间根目录；
+// tenantID 为0时，位于某个租户命名空间中的路径会被归属到该租户
+func resolveTenantPath(tenantID *uint, path string) (string, error) {
+	path = utils.FixAndCleanPath(path)
+	if *tenantID == 0 {
+		if !model.IsTenantPath(path) {
+			return path, nil
+		}
+		name, ok := model.TenantNameOfPath(path)
+		if !ok {
+			return "", errors.WithStack(errs.PathNotInTenant)
+		}
+		tenant, err := GetTenantByName(name)
+		if err != nil {
+			return "", err
+		}
+		*tenantID = tenant.ID
+		return path, nil
+	}
+	tenant, err := GetTenantById(*tenantID)
+	if err != nil {
+		return "", err
+	}
+	if utils.IsSubPath(tenant.RootPath(), path) {
+		return path, nil
+	}
+	if model.IsTenantPath(path) {
+		return "", errors.WithStack(errs.PathNotInTenant)
+	}
+	return utils.FixAndCleanPath(tenant.RootPath() + path), nil
+}
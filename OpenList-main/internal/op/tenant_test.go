@@ -0,0 +1,152 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func setupTenant(t *testing.T, name string) *model.Tenant {
+	tenant := &model.Tenant{Name: name}
+	if err := op.CreateTenant(tenant); err != nil {
+		t.Fatalf("failed to create tenant: %+v", err)
+	}
+	return tenant
+}
+
+func TestTenantStorageIsolation(t *testing.T) {
+	acme := setupTenant(t, "acme")
+	setupTenant(t, "globex")
+	storages := []model.Storage{
+		{Driver: "Local", MountPath: "/", Addition: `{"root_folder_path":"."}`},
+		{Driver: "Local", MountPath: "/docs", Tenancy: model.Tenancy{TenantID: acme.ID}, Addition: `{"root_folder_path":"."}`},
+	}
+	for _, storage := range storages {
+		if _, err := op.CreateStorage(context.Background(), storage); err != nil {
+			t.Fatalf("failed to create storage: %+v", err)
+		}
+	}
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver: "Local", MountPath: "/@tenants/initech/docs", Addition: `{"root_folder_path":"."}`,
+	}); err == nil {
+		t.Errorf("expect mounting in a nonexistent tenant namespace to fail")
+	}
+	var tests = []struct {
+		path      string
+		mountPath string
+	}{
+		{path: "/@tenants/acme/docs/a", mountPath: "/@tenants/acme/docs"},
+		{path: "/@tenants/acme/other", mountPath: ""},
+		{path: "/@tenants/globex/docs", mountPath: ""},
+		{path: "/@tenants", mountPath: ""},
+		{path: "/public", mountPath: "/"},
+	}
+	for _, tt := range tests {
+		storage, _, err := op.GetStorageAndActualPath(tt.path)
+		if tt.mountPath == "" {
+			if err == nil {
+				t.Errorf("expect %s to resolve no storage, got %s", tt.path, storage.GetStorage().MountPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed to get storage of %s: %+v", tt.path, err)
+		} else if storage.GetStorage().MountPath != tt.mountPath {
+			t.Errorf("expect %s to resolve %s, got %s", tt.path, tt.mountPath, storage.GetStorage().MountPath)
+		}
+	}
+	if err := op.DeleteTenantById(acme.ID); !errors.Is(err, errs.TenantNotEmpty) {
+		t.Errorf("expect deleting a tenant with storages to fail, got %+v", err)
+	}
+}
+
+func TestTenantMetaIsolation(t *testing.T) {
+	tenant := setupTenant(t, "meta_tenant")
+	if err := op.CreateMeta(&model.Meta{Path: "/", Password: "global"}); err != nil {
+		t.Fatalf("failed to create meta: %+v", err)
+	}
+	meta := &model.Meta{Path: "/docs", Password: "tenant", TenantID: tenant.ID}
+	if err := op.CreateMeta(meta); err != nil {
+		t.Fatalf("failed to create meta: %+v", err)
+	}
+	if meta.Path != "/@tenants/meta_tenant/docs" {
+		t.Errorf("expect meta to be created in the tenant namespace, got %s", meta.Path)
+	}
+	if m, err := op.GetNearestMeta("/@tenants/meta_tenant/docs/a"); err != nil || m.Password != "tenant" {
+		t.Errorf("expect the tenant meta, got %+v, %+v", m, err)
+	}
+	if m, err := op.GetNearestMeta("/@tenants/meta_tenant/other"); !errors.Is(err, errs.MetaNotFound) {
+		t.Errorf("expect the global meta not to apply in the tenant namespace, got %+v", m)
+	}
+}
+
+func TestTenantUserNamespace(t *testing.T) {
+	tenant := setupTenant(t, "user_tenant")
+	tenantUser := &model.User{Username: "tenant_user", BasePath: "/", TenantID: tenant.ID}
+	if err := op.CreateUser(tenantUser); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	generalUser := &model.User{Username: "general_user", BasePath: "/"}
+	var tests = []struct {
+		user    *model.User
+		reqPath string
+		path    string
+	}{
+		{user: tenantUser, reqPath: "/a", path: "/@tenants/user_tenant/a"},
+		{user: tenantUser, reqPath: "/../../a", path: ""},
+		{user: generalUser, reqPath: "/a", path: "/a"},
+		{user: generalUser, reqPath: "/@tenants/user_tenant/a", path: ""},
+	}
+	for _, tt := range tests {
+		path, err := tt.user.JoinPath(tt.reqPath)
+		if tt.path == "" {
+			if err == nil {
+				t.Errorf("expect %s not to access %s", tt.user.Username, path)
+			}
+			continue
+		}
+		if err != nil || path != tt.path {
+			t.Errorf("expect %s to join %s as %s, got %s, %+v", tt.user.Username, tt.reqPath, tt.path, path, err)
+		}
+	}
+}
+
+func TestTenantSubUser(t *testing.T) {
+	tenant := setupTenant(t, "sub_tenant")
+	other := setupTenant(t, "sub_other")
+	admin := &model.User{Username: "sub_admin", Role: model.TENANT, TenantID: tenant.ID, Permission: 0b1001}
+	if err := op.CreateUser(admin); err != nil {
+		t.Fatalf("failed to create tenant admin: %+v", err)
+	}
+	user := &model.User{Username: "sub_user", Password: "pwd", BasePath: "/home", Permission: 0b1000}
+	if err := op.CreateTenantUser(admin, user); err != nil {
+		t.Fatalf("failed to create sub user: %+v", err)
+	}
+	if user.TenantID != tenant.ID || user.BasePath != "/@tenants/sub_tenant/home" {
+		t.Errorf("expect the sub user to be created in the tenant namespace, got %d %s", user.TenantID, user.BasePath)
+	}
+	if err := op.CreateTenantUser(admin, &model.User{Username: "sub_exceed", Password: "pwd", Permission: 0b10}); !errors.Is(err, errs.ExceedPermission) {
+		t.Errorf("expect a permission beyond the tenant admin to be rejected, got %+v", err)
+	}
+	if err := op.CreateTenantUser(admin, &model.User{Username: "sub_tenant_admin", Password: "pwd", Role: model.TENANT}); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("expect creating another tenant admin to be rejected, got %+v", err)
+	}
+	if err := op.CreateTenantUser(admin, &model.User{Username: "sub_escape", Password: "pwd", BasePath: "/@tenants/sub_other"}); !errors.Is(err, errs.PathNotInTenant) {
+		t.Errorf("expect a base path outside the tenant to be rejected, got %+v", err)
+	}
+	user.Disabled = true
+	if err := op.UpdateTenantUser(admin, user); err != nil {
+		t.Errorf("failed to disable sub user: %+v", err)
+	}
+	otherAdmin := &model.User{Username: "sub_other_admin", Role: model.TENANT, TenantID: other.ID, Permission: 0b1001}
+	if err := op.DeleteTenantUser(otherAdmin, user.ID); !errors.Is(err, errs.UserNotInTenant) {
+		t.Errorf("expect users of another tenant to be invisible, got %+v", err)
+	}
+	if err := op.DeleteTenantUser(admin, admin.ID); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("expect a tenant admin not to delete itself, got %+v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+// 租户管理员自助管理本租户内的子用户
+// 子用户只能是普通用户，基础路径限定在租户命名空间中，权限不能超出租户管理员自身的权限
+
+// GetTenantUsers 列出租户管理员所在租户的全部用户
+func GetTenantUsers(admin *model.User, pageIndex, pageSize int) ([]model.User, int64, error) {
+	return db.GetUsers(pageIndex, pageSize, &admin.TenantID)
+}
+
+// GetTenantUser 获取本租户内的用户，其他租户的用户视为不存在
+func GetTenantUser(admin *model.User, id uint) (*model.User, error) {
+	user, err := GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.TenantID != admin.TenantID {
+		return nil, errors.WithStack(errs.UserNotInTenant)
+	}
+	return user, nil
+}
+
+// CreateTenantUser 在租户管理员所在租户中创建子用户
+func CreateTenantUser(admin *model.User, u *model.User) error {
+	if err := checkTenantUser(admin, u); err != nil {
+		return err
+	}
+	if err := SetUserPassword(u, u.Password); err != nil {
+		return err
+	}
+	u.Password = ""
+	u.OtpSecret, u.SsoID, u.Authn = "", "", ""
+	return CreateUser(u)
+}
+
+// UpdateTenantUser 更新子用户，密码为空时保留原密码，两步验证等凭据、配额和目录来源不允许修改
+func UpdateTenantUser(admin *model.User, u *model.User) error {
+	old, err := getManagedTenantUser(admin, u.ID)
+	if err != nil {
+		return err
+	}
+	if err = checkTenantUser(admin, u); err != nil {
+		return err
+	}
+	u.PwdHash, u.Salt, u.PwdTS, u.PwdHistory = old.PwdHash, old.Salt, old.PwdTS, old.PwdHistory
+	if u.Password != "" {
+		if err = SetUserPassword(u, u.Password); err != nil {
+			return err
+		}
+		u.Password = ""
+	}
+	u.OtpSecret, u.SsoID, u.Authn, u.RecoveryCodes = old.OtpSecret, old.SsoID, old.Authn, old.RecoveryCodes
+	u.FailedLogins, u.LockedAt = old.FailedLogins, old.LockedAt
+	u.QuotaBytes, u.QuotaObjects = old.QuotaBytes, old.QuotaObjects
+	u.LdapDN, u.ExternalID = old.LdapDN, old.ExternalID
+	return UpdateUser(u)
+}
+
+// DeleteTenantUser 删除子用户
+func DeleteTenantUser(admin *model.User, id uint) error {
+	if _, err := getManagedTenantUser(admin, id); err != nil {
+		return err
+	}
+	return DeleteUserById(id)
+}
+
+// getManagedTenantUser 获取租户管理员可以管理的子用户，租户管理员之间不能互相修改
+func getManagedTenantUser(admin *model.User, id uint) (*model.User, error) {
+	user, err := GetTenantUser(admin, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != model.GENERAL {
+		return nil, errors.WithStack(errs.PermissionDenied)
+	}
+	return user, nil
+}
+
+func checkTenantUser(admin *model.User, u *model.User) error {
+	if u.Role != model.GENERAL {
+		return errs.NewErr(errs.PermissionDenied, "tenant users can only be general users")
+	}
+	if u.Permission&^admin.EffectivePermission() != 0 {
+		return errors.WithStack(errs.ExceedPermission)
+	}
+	u.TenantID = admin.TenantID
+	// 用户组是全局的，只能由管理员分配
+	u.GroupIDs = nil
+	return nil
+}
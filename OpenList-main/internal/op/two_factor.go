@@ -0,0 +1,110 @@
+package op
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	recoveryCodeCount = 10
+	// without look-alike characters such as 0/o and 1/l
+	recoveryCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+)
+
+// Has2FA reports whether the user has enrolled TOTP or WebAuthn
+func Has2FA(u *model.User) bool {
+	return u.OtpSecret != "" || len(u.WebAuthnCredentials()) > 0
+}
+
+// Requires2FA reports whether the user must enroll 2FA before using the API
+func Requires2FA(u *model.User) bool {
+	if u.Role != model.ADMIN && u.Role != model.TENANT {
+		return false
+	}
+	item, err := GetSettingItemByKey(conf.Force2FA)
+	return err == nil && (item.Value == "true" || item.Value == "1")
+}
+
+// Check2FAEnrolled refuses the user who must enroll 2FA and has not yet
+func Check2FAEnrolled(u *model.User) error {
+	if Requires2FA(u) && !Has2FA(u) {
+		return errors.WithStack(errs.TwoFactorRequired)
+	}
+	return nil
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of the user and returns the new ones,
+// only their hashes are stored
+func GenerateRecoveryCodes(u *model.User) ([]string, error) {
+	if !Has2FA(u) {
+		return nil, errors.WithStack(errs.TwoFactorDisabled)
+	}
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	alphabetLen := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := range codes {
+		b := make([]byte, 10)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, alphabetLen)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			b[j] = recoveryCodeAlphabet[n.Int64()]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := updateRecoveryCodes(u, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode consumes a recovery code of the user, it reports whether the code was valid
+func UseRecoveryCode(u *model.User, code string) (bool, error) {
+	if code == "" || u.RecoveryCodes == "" {
+		return false, nil
+	}
+	hash := hashRecoveryCode(code)
+	hashes := strings.Split(u.RecoveryCodes, "\n")
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			return true, updateRecoveryCodes(u, append(hashes[:i:i], hashes[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// RecoveryCodesLeft returns the number of unused recovery codes of the user
+func RecoveryCodesLeft(u *model.User) int {
+	if u.RecoveryCodes == "" {
+		return 0
+	}
+	return len(strings.Split(u.RecoveryCodes, "\n"))
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func updateRecoveryCodes(u *model.User, hashes []string) error {
+	codes := strings.Join(hashes, "\n")
+	if err := db.UpdateUserRecoveryCodes(u.ID, codes); err != nil {
+		return err
+	}
+	u.RecoveryCodes = codes
+	dropCache(ChangeUser, u.Username)
+	return nil
+}
@@ -0,0 +1,40 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	user := &model.User{Username: "recovery", BasePath: "/"}
+	if err := op.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if _, err := op.GenerateRecoveryCodes(user); !errors.Is(err, errs.TwoFactorDisabled) {
+		t.Errorf("expect no recovery codes without 2FA, got %+v", err)
+	}
+	user.OtpSecret = "secret"
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		t.Fatalf("failed to generate recovery codes: %+v", err)
+	}
+	if len(codes) != 10 || op.RecoveryCodesLeft(user) != 10 {
+		t.Fatalf("expect 10 recovery codes, got %d", len(codes))
+	}
+	if ok, err := op.UseRecoveryCode(user, "not-a-code"); ok || err != nil {
+		t.Errorf("expect an unknown code to be rejected, got %v %+v", ok, err)
+	}
+	if ok, err := op.UseRecoveryCode(user, codes[3]); !ok || err != nil {
+		t.Errorf("expect the code to be accepted, got %v %+v", ok, err)
+	}
+	if ok, _ := op.UseRecoveryCode(user, codes[3]); ok {
+		t.Errorf("expect a code to be usable only once")
+	}
+	if op.RecoveryCodesLeft(user) != 9 {
+		t.Errorf("expect 9 recovery codes left, got %d", op.RecoveryCodesLeft(user))
+	}
+}
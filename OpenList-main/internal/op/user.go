@@ -18,7 +18,7 @@ var adminUser *model.User
 
 func GetAdmin() (*model.User, error) {
 	if adminUser == nil {
-		user, err := db.GetUserByRole(model.ADMIN)
+		user, err := GetUserByRole(model.ADMIN)
 		if err != nil {
 			return nil, err
 		}
@@ -29,7 +29,7 @@ func GetAdmin() (*model.User, error) {
 
 func GetGuest() (*model.User, error) {
 	if guestUser == nil {
-		user, err := db.GetUserByRole(model.GUEST)
+		user, err := GetUserByRole(model.GUEST)
 		if err != nil {
 			return nil, err
 		}
@@ -39,7 +39,11 @@ func GetGuest() (*model.User, error) {
 }
 
 func GetUserByRole(role int) (*model.User, error) {
-	return db.GetUserByRole(role)
+	user, err := db.GetUserByRole(role)
+	if err != nil {
+		return nil, err
+	}
+	return user, loadUserGroups(user)
 }
 
 func GetUserByName(username string) (*model.User, error) {
@@ -54,6 +58,9 @@ func GetUserByName(username string) (*model.User, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err = loadUserGroups(_user); err != nil {
+			return nil, err
+		}
 		userCache.Set(username, _user, cache.WithEx[*model.User](time.Hour))
 		return _user, nil
 	})
@@ -61,16 +68,29 @@ func GetUserByName(username string) (*model.User, error) {
 }
 
 func GetUserById(id uint) (*model.User, error) {
-	return db.GetUserById(id)
+	user, err := db.GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	return user, loadUserGroups(user)
 }
 
-func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err error) {
-	return db.GetUsers(pageIndex, pageSize)
+func GetUsers(pageIndex, pageSize int, tenantID *uint) (users []model.User, count int64, err error) {
+	return db.GetUsers(pageIndex, pageSize, tenantID)
+}
+
+func GetLdapUsers() ([]model.User, error) {
+	return db.GetLdapUsers()
 }
 
 func CreateUser(u *model.User) error {
-	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.CreateUser(u)
+	if err := resolveUserBasePath(u); err != nil {
+		return err
+	}
+	if err := db.CreateUser(u); err != nil {
+		return err
+	}
+	return setUserGroups(u)
 }
 
 func DeleteUserById(id uint) error {
@@ -81,8 +101,23 @@ func DeleteUserById(id uint) error {
 	if old.IsAdmin() || old.IsGuest() {
 		return errs.DeleteAdminOrGuest
 	}
-	userCache.Del(old.Username)
-	return db.DeleteUserById(id)
+	defer dropCache(ChangeUser, old.Username)
+	if err = db.DeleteUserById(id); err != nil {
+		return err
+	}
+	if err = db.DeleteApiTokensByUserId(id); err != nil {
+		return err
+	}
+	if err = RevokeUserSessions(id); err != nil {
+		return err
+	}
+	if err = db.DeleteUserGroupsByUserId(id); err != nil {
+		return err
+	}
+	if err = deletePathACLsBySubject(model.ACLSubjectUser, id); err != nil {
+		return err
+	}
+	return db.DeleteQuotaUsage(model.QuotaScopeUser, id)
 }
 
 func UpdateUser(u *model.User) error {
@@ -96,13 +131,53 @@ func UpdateUser(u *model.User) error {
 	if u.IsGuest() {
 		guestUser = nil
 	}
-	userCache.Del(old.Username)
-	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.UpdateUser(u)
+	defer dropCache(ChangeUser, old.Username)
+	if err = resolveUserBasePath(u); err != nil {
+		return err
+	}
+	if err = db.UpdateUser(u); err != nil {
+		return err
+	}
+	// a disabled user is logged out everywhere
+	if u.Disabled && !old.Disabled {
+		if err = RevokeUserSessions(u.ID); err != nil {
+			return err
+		}
+	}
+	return setUserGroups(u)
+}
+
+// resolveUserBasePath confines the base path of tenant users to the tenant namespace,
+// other users may leave it empty to inherit the base path of their groups
+func resolveUserBasePath(u *model.User) (err error) {
+	if u.IsAdmin() || u.IsGuest() {
+		u.TenantID = 0
+		u.BasePath = utils.FixAndCleanPath(u.BasePath)
+		return nil
+	}
+	if u.BasePath == "" && u.TenantID == 0 {
+		return nil
+	}
+	u.BasePath, err = resolveTenantPath(&u.TenantID, u.BasePath)
+	return err
+}
+
+// setUserGroups replaces the groups of the user if they are given
+func setUserGroups(u *model.User) error {
+	if u.GroupIDs == nil {
+		return nil
+	}
+	if err := db.SetUserGroups(u.ID, u.GroupIDs); err != nil {
+		return err
+	}
+	return loadUserGroups(u)
 }
 
 func Cancel2FAByUser(u *model.User) error {
 	u.OtpSecret = ""
+	if len(u.WebAuthnCredentials()) == 0 {
+		u.RecoveryCodes = ""
+	}
 	return UpdateUser(u)
 }
 
@@ -125,6 +200,6 @@ func DelUserCache(username string) error {
 	if user.IsGuest() {
 		guestUser = nil
 	}
-	userCache.Del(username)
+	dropCache(ChangeUser, username)
 	return nil
 }
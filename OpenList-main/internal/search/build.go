@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
@@ -206,6 +207,10 @@ func Update(parent string, objs []model.Obj) {
 	if isIgnorePath(parent) {
 		return
 	}
+	// the nodes of a cluster list the same storages, so only the leader updates an index they share
+	if !cluster.IsLeader() && sharedIndex() {
+		return
+	}
 	ctx := context.Background()
 	// only update when index have built
 	progress, err := Progress()
@@ -267,6 +272,13 @@ func Update(parent string, objs []model.Obj) {
 	}
 }
 
+// sharedIndex reports whether the index is kept in the database or in meilisearch and so shared by the nodes of a cluster.
+// A bleve index is a file of each node, which every node keeps up to date itself.
+// The builds started from the API run on the node receiving the request, guarded by the cluster lock of the index.
+func sharedIndex() bool {
+	return instance.Config().Name != "bleve"
+}
+
 func init() {
 	op.RegisterObjsUpdateHook(Update)
 }
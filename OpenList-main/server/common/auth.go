@@ -5,7 +5,9 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/go-cache"
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/pkg/errors"
 )
@@ -15,17 +17,39 @@ var SecretKey []byte
 type UserClaims struct {
 	Username string `json:"username"`
 	PwdTS    int64  `json:"pwd_ts"`
+	// SessionID is the id of the session the token belongs to, revoking the session invalidates the token
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 var validTokenCache = cache.NewMemCache[bool]()
 
-func GenerateToken(user *model.User) (tokenString string, err error) {
+// GenerateToken starts a session of the user for the client of the request and returns its token.
+// Users of tenants requiring certificate login are refused, they sign in with GenerateCertificateToken.
+func GenerateToken(c *gin.Context, user *model.User) (tokenString string, err error) {
+	if err = op.CheckCertificateLoginRequired(user); err != nil {
+		return "", err
+	}
+	return generateToken(c, user)
+}
+
+// GenerateCertificateToken is GenerateToken for a user who has proved holding its certificate with op.CheckTenantCertificateLogin
+func GenerateCertificateToken(c *gin.Context, user *model.User) (tokenString string, err error) {
+	return generateToken(c, user)
+}
+
+func generateToken(c *gin.Context, user *model.User) (tokenString string, err error) {
+	expiresAt := time.Now().Add(time.Duration(conf.Conf.TokenExpiresIn) * time.Hour)
+	session, err := op.CreateSession(user, c.ClientIP(), c.Request.UserAgent(), expiresAt)
+	if err != nil {
+		return "", err
+	}
 	claim := UserClaims{
-		Username: user.Username,
-		PwdTS:    user.PwdTS,
+		Username:  user.Username,
+		PwdTS:     user.PwdTS,
+		SessionID: session.ID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(conf.Conf.TokenExpiresIn) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		}}
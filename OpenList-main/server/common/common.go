@@ -84,6 +84,7 @@ func ErrorWithDataResp(c *gin.Context, err error, code int, data interface{}, l
 			log.Errorf("%v", err)
 		}
 	}
+	c.Set(respErrorKey, hidePrivacy(err.Error()))
 	c.JSON(200, Resp[interface{}]{
 		Code:    code,
 		Message: hidePrivacy(err.Error()),
@@ -96,6 +97,7 @@ func ErrorStrResp(c *gin.Context, str string, code int, l ...bool) {
 	if len(l) != 0 && l[0] {
 		log.Error(str)
 	}
+	c.Set(respErrorKey, hidePrivacy(str))
 	c.JSON(200, Resp[interface{}]{
 		Code:    code,
 		Message: hidePrivacy(str),
@@ -104,6 +106,14 @@ func ErrorStrResp(c *gin.Context, str string, code int, l ...bool) {
 	c.Abort()
 }
 
+// respErrorKey keeps the message of the error response for the middlewares running after the handler
+const respErrorKey = "resp_error"
+
+// RespError returns the message of the error response written by the handler, if any
+func RespError(c *gin.Context) (string, bool) {
+	return c.GetString(respErrorKey), c.GetString(respErrorKey) != ""
+}
+
 func SuccessResp(c *gin.Context, data ...interface{}) {
 	SuccessWithMsgResp(c, "success", data...)
 }
@@ -0,0 +1,14 @@
+package common
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// ClientCertificate returns the verified TLS client certificate of the request, if any
+func ClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
@@ -119,11 +119,14 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 		}
 	} else {
 		userObj, err = op.GetUserByName(user)
+		if err == nil {
+			err = op.CheckProtocolUserPassword(userObj, model.StaticHash(pass))
+		}
+		op.RecordLogin(model.ProtocolFTP, cc.RemoteAddr().String(), user, "password", err)
 		if err != nil {
 			return nil, err
 		}
-		passHash := model.StaticHash(pass)
-		if err = userObj.ValidatePwdStaticHash(passHash); err != nil {
+		if err = op.LoginSucceeded(userObj); err != nil {
 			return nil, err
 		}
 	}
@@ -139,6 +142,7 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 		ctx = context.WithValue(ctx, conf.MetaPassKey, "")
 	}
 	ctx = context.WithValue(ctx, conf.ClientIPKey, cc.RemoteAddr().String())
+	ctx = context.WithValue(ctx, conf.ProtocolKey, model.ProtocolFTP)
 	ctx = context.WithValue(ctx, conf.ProxyHeaderKey, d.proxyHeader)
 	return ftp.NewAferoAdapter(ctx), nil
 }
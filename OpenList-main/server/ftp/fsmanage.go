@@ -10,7 +10,6 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
-	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/pkg/errors"
 )
 
@@ -20,29 +19,36 @@ func Mkdir(ctx context.Context, path string) error {
 	if err != nil {
 		return err
 	}
-	if !user.CanWrite() || !user.CanFTPManage() {
-		meta, err := op.GetNearestMeta(stdpath.Dir(reqPath))
-		if err != nil {
-			if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
-				return err
-			}
-		}
-		if !common.CanWrite(meta, reqPath) {
-			return errs.PermissionDenied
+	meta, err := op.GetNearestMeta(stdpath.Dir(reqPath))
+	if err != nil {
+		if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			return err
 		}
 	}
+	if !op.CanPath(pathUser(user), meta, reqPath, model.PathWrite, "") {
+		return errs.PermissionDenied
+	}
 	return fs.MakeDir(ctx, reqPath)
 }
 
+// pathUser is the user whose permissions apply to the FTP writes,
+// without FTP management only the metas and ACLs of the paths can allow them
+func pathUser(user *model.User) *model.User {
+	if user.CanFTPManage() {
+		return user
+	}
+	return user.WithoutWrite()
+}
+
 func Remove(ctx context.Context, path string) error {
 	user := ctx.Value(conf.UserKey).(*model.User)
-	if !user.CanRemove() || !user.CanFTPManage() {
-		return errs.PermissionDenied
-	}
 	reqPath, err := user.JoinPath(path)
 	if err != nil {
 		return err
 	}
+	if !user.CanFTPManage() || !op.CanPath(user, nil, reqPath, model.PathDelete, "") {
+		return errs.PermissionDenied
+	}
 	return fs.Remove(ctx, reqPath)
 }
 
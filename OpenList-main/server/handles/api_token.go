@@ -0,0 +1,92 @@
+package handles
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type ApiTokenCreateReq struct {
+	Name       string     `json:"name" binding:"required"`
+	PathPrefix string     `json:"path_prefix"`
+	Permission int32      `json:"permission"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type ApiTokenCreateResp struct {
+	model.ApiToken
+	Token string `json:"token"`
+}
+
+func ListMyApiTokens(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	tokens, total, err := op.GetApiTokensByUserId(user.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: tokens,
+		Total:   total,
+	})
+}
+
+// CreateMyApiToken creates a token, the plain token is only returned in this response
+func CreateMyApiToken(c *gin.Context) {
+	var req ApiTokenCreateReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	apiToken := model.ApiToken{
+		Name:       req.Name,
+		PathPrefix: req.PathPrefix,
+		Permission: req.Permission,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	token, err := op.CreateApiToken(user, &apiToken)
+	if err != nil {
+		if errors.Is(err, errs.ExceedPermission) {
+			common.ErrorResp(c, err, 403)
+		} else {
+			common.ErrorResp(c, err, 400)
+		}
+		return
+	}
+	common.SuccessResp(c, ApiTokenCreateResp{ApiToken: apiToken, Token: token})
+}
+
+func RevokeMyApiToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err = op.RevokeApiToken(user.ID, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, errs.ApiTokenNotFound):
+			common.ErrorResp(c, err, 404)
+		case errors.Is(err, errs.PermissionDenied):
+			common.ErrorResp(c, err, 403)
+		default:
+			common.ErrorResp(c, err, 500, true)
+		}
+		return
+	}
+	common.SuccessResp(c)
+}
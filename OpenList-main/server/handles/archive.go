@@ -109,7 +109,7 @@ func FsArchiveMeta(c *gin.Context, req *ArchiveMetaReq, user *model.User) {
 		}
 	}
 	common.GinWithValue(c, conf.MetaKey, meta)
-	if !common.CanAccess(user, meta, reqPath, req.Password) {
+	if !op.CanPath(user, meta, reqPath, model.PathRead, req.Password) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
@@ -194,7 +194,7 @@ func FsArchiveList(c *gin.Context, req *ArchiveListReq, user *model.User) {
 		}
 	}
 	common.GinWithValue(c, conf.MetaKey, meta)
-	if !common.CanAccess(user, meta, reqPath, req.Password) {
+	if !op.CanPath(user, meta, reqPath, model.PathRead, req.Password) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
@@ -0,0 +1,106 @@
+package handles
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultAuditPerPage is used when no page size is given, the audit log is usually too large to list at once
+const defaultAuditPerPage = 100
+
+func ListAuditEvents(c *gin.Context) {
+	var req struct {
+		model.PageReq
+		model.AuditFilter
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.PerPage < 1 {
+		req.PerPage = defaultAuditPerPage
+	}
+	req.Validate()
+	events, total, err := op.GetAuditEvents(req.AuditFilter, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: events,
+		Total:   total,
+	})
+}
+
+var auditCSVHeader = []string{"id", "created_at", "user_id", "username", "ip", "protocol", "action", "target", "detail", "success", "error"}
+
+// csvCell quotes a value a spreadsheet would run as a formula, usernames and paths are chosen by the users
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ExportAuditEvents streams the matched events as csv or as json lines, oldest first
+func ExportAuditEvents(c *gin.Context) {
+	var filter model.AuditFilter
+	if err := c.ShouldBind(&filter); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	var write func([]model.AuditEvent) error
+	switch format := c.DefaultQuery("format", "csv"); format {
+	case "csv":
+		w := csv.NewWriter(c.Writer)
+		write = func(events []model.AuditEvent) error {
+			for _, e := range events {
+				if err := w.Write([]string{
+					strconv.FormatUint(uint64(e.ID), 10), e.CreatedAt.Format(time.RFC3339),
+					strconv.FormatUint(uint64(e.UserID), 10), csvCell(e.Username), e.IP, e.Protocol,
+					e.Action, csvCell(e.Target), csvCell(e.Detail), strconv.FormatBool(e.Success), csvCell(e.Error),
+				}); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+			return w.Error()
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename=audit.csv")
+		c.Status(200)
+		if err := w.Write(auditCSVHeader); err != nil {
+			return
+		}
+		w.Flush()
+	case "jsonl":
+		enc := json.NewEncoder(c.Writer)
+		write = func(events []model.AuditEvent) error {
+			for _, e := range events {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		c.Header("Content-Type", "application/jsonl; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename=audit.jsonl")
+		c.Status(200)
+	default:
+		common.ErrorStrResp(c, "unsupported format: "+format, 400)
+		return
+	}
+	// the response has started, so a failure can only be logged
+	if err := op.WalkAuditEvents(filter, write); err != nil {
+		log.Errorf("failed export audit events: %+v", err)
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"encoding/hex"
 	"net/url"
 	"fmt"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"github.com/pquerna/otp/totp"
 	log "github.com/sirupsen/logrus"
 )
@@ -24,7 +28,11 @@ type LoginReq struct {
 
 type UserResp struct {
 	model.User
-	Otp bool `json:"otp"`
+	Otp    bool          `json:"otp"`
+	Quotas []model.Quota `json:"quotas"`
+	// the user must enroll 2FA before it can use the API
+	Require2FA        bool `json:"require_2fa"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
 }
 
 // CurrentUser get current user by token
@@ -35,9 +43,20 @@ func CurrentUser(c *gin.Context) {
 		User: *user,
 	}
 	userResp.Password = ""
+	// report what the user gets together with its groups
+	userResp.Permission = user.EffectivePermission()
+	userResp.BasePath = user.EffectiveBasePath()
 	if userResp.OtpSecret != "" {
 		userResp.Otp = true
 	}
+	userResp.Require2FA = op.Requires2FA(user) && !op.Has2FA(user)
+	userResp.RecoveryCodesLeft = op.RecoveryCodesLeft(user)
+	quotas, err := op.GetUserQuotas(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	userResp.Quotas = quotas
 	common.SuccessResp(c, userResp)
 }
 
@@ -51,9 +70,13 @@ func UpdateCurrent(c *gin.Context) {
 	req.ID = user.ID
 	req.Username = user.Username
 	req.Role = user.Role
-	// can only update these fields
-	user.OtpSecret = req.OtpSecret
-	user.Password = req.Password
+	// can only update these fields, 2FA is managed by its own routes
+	if req.Password != "" {
+		if err := op.SetUserPassword(user, req.Password); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
 	user.SsoID = req.SsoID
 	if err := op.UpdateUser(user); err != nil {
 		common.ErrorResp(c, err, 500)
@@ -106,8 +129,8 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		}
 		return
 	}
-	if err := user.ValidatePwdStaticHash(req.Password); err != nil {
-		common.ErrorResp(c, err, 400)
+	if err := op.CheckUserPassword(user, req.Password); err != nil {
+		common.ErrorResp(c, err, loginErrCode(err))
 		if count, ok := model.LoginCache.Get(ip); ok {
 			model.LoginCache.Set(ip, count+1)
 		} else {
@@ -116,8 +139,11 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		return
 	}
 	if user.OtpSecret != "" {
-		if !totp.Validate(req.OtpCode, user.OtpSecret) {
+		if !totp.Validate(req.OtpCode, user.OtpSecret) && !useRecoveryCode(user, req.OtpCode) {
 			common.ErrorStrResp(c, "Invalid 2FA code", 402)
+			if err := op.LoginFailed(user); err != nil {
+				utils.Log.Errorf("failed to record the failed login of %s: %+v", user.Username, err)
+			}
 			if count, ok := model.LoginCache.Get(ip); ok {
 				model.LoginCache.Set(ip, count+1)
 			} else {
@@ -127,15 +153,20 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		}
 	}
 	
-	token, err := common.GenerateToken(user)
+	if err := op.LoginSucceeded(user); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
 	}
 	
 	common.SuccessResp(c, gin.H{
-		"token": token,
-		"role":  user.Role, // 添加角色信息到响应
+		"token":            token,
+		"role":             user.Role, // 添加角色信息到响应
+		"password_expired": op.IsPasswordExpired(user),
 	})
 	model.LoginCache.Del(ip)
 }
@@ -204,14 +235,74 @@ func Verify2FA(c *gin.Context) {
 	user.OtpSecret = req.Secret
 	if err := op.UpdateUser(user); err != nil {
 		common.ErrorResp(c, err, 500)
-	} else {
-		common.SuccessResp(c)
+		return
+	}
+	// the recovery codes are only shown once, they replace the codes issued before
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"recovery_codes": codes})
+}
+
+// GenerateRecoveryCodes issues new recovery codes, the unused codes stop working
+func GenerateRecoveryCodes(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		if errors.Is(err, errs.TwoFactorDisabled) {
+			common.ErrorResp(c, err, 400)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
+		return
+	}
+	common.SuccessResp(c, gin.H{"recovery_codes": codes})
+}
+
+// useRecoveryCode accepts a recovery code in place of a TOTP code, each code works once
+func useRecoveryCode(user *model.User, code string) bool {
+	ok, err := op.UseRecoveryCode(user, code)
+	if err != nil {
+		utils.Log.Errorf("failed to use the recovery code of %s: %+v", user.Username, err)
+		return false
+	}
+	return ok
+}
+
+// TenantLoginReq 租户登录请求，租户启用证书登录时需附带证书及其私钥对登录挑战的签名
+type TenantLoginReq struct {
+	LoginReq
+	Certificate string `json:"certificate"` // PEM格式的租户证书
+	Nonce       string `json:"nonce"`       // TenantLoginChallenge 签发的挑战
+	Signature   string `json:"signature"`   // 对 op.CertificateLoginMessage 的签名，base64编码
+}
+
+// TenantLoginChallenge 签发证书登录挑战
+func TenantLoginChallenge(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	nonce, err := op.NewCertificateLoginChallenge(req.Username)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
 	}
+	common.SuccessResp(c, gin.H{
+		"nonce":      nonce,
+		"message":    string(op.CertificateLoginMessage(req.Username, nonce)),
+		"expires_at": time.Now().Add(op.CertLoginChallengeTTL),
+	})
 }
 
 // TenantLogin 租户登录接口
 func TenantLogin(c *gin.Context) {
-	var req LoginReq
+	var req TenantLoginReq
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
@@ -251,8 +342,9 @@ func TenantLogin(c *gin.Context) {
 		return
 	}
 	
-	if err := user.ValidatePwdStaticHash(req.Password); err != nil {
-		common.ErrorResp(c, err, 400)
+	// 按租户配置检查密码和证书持有证明
+	if err := op.CheckTenantUserPassword(user, req.Password, req.Certificate, req.Nonce, req.Signature); err != nil {
+		common.ErrorResp(c, err, loginErrCode(err))
 		if count, ok := model.LoginCache.Get(ip); ok {
 			model.LoginCache.Set(ip, count+1)
 		} else {
@@ -262,8 +354,11 @@ func TenantLogin(c *gin.Context) {
 	}
 	
 	if user.OtpSecret != "" {
-		if !totp.Validate(req.OtpCode, user.OtpSecret) {
+		if !totp.Validate(req.OtpCode, user.OtpSecret) && !useRecoveryCode(user, req.OtpCode) {
 			common.ErrorStrResp(c, "Invalid 2FA code", 402)
+			if err := op.LoginFailed(user); err != nil {
+				utils.Log.Errorf("failed to record the failed login of %s: %+v", user.Username, err)
+			}
 			if count, ok := model.LoginCache.Get(ip); ok {
 				model.LoginCache.Set(ip, count+1)
 			} else {
@@ -273,7 +368,11 @@ func TenantLogin(c *gin.Context) {
 		}
 	}
 	
-	token, err := common.GenerateToken(user)
+	if err := op.LoginSucceeded(user); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	token, err := common.GenerateCertificateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
@@ -286,6 +385,17 @@ func TenantLogin(c *gin.Context) {
 	model.LoginCache.Del(ip)
 }
 
+// loginErrCode is 423 for a locked account, so that it is not mistaken for a wrong password
+func loginErrCode(err error) int {
+	if errors.Is(err, errs.AccountLocked) {
+		return 423
+	}
+	if errors.Is(err, errs.CertificateProofRequired) || errors.Is(err, errs.InvalidCertificateProof) {
+		return 401
+	}
+	return 400
+}
+
 // LogOut logout
 func LogOut(c *gin.Context) {
 	token := c.GetHeader("Authorization")
@@ -294,5 +404,13 @@ func LogOut(c *gin.Context) {
 	}
 	// 将token加入黑名单
 	common.InvalidateToken(token)
+	if sid, ok := c.Request.Context().Value(conf.SessionKey).(string); ok {
+		user := c.Request.Context().Value(conf.UserKey).(*model.User)
+		if err := op.RevokeSession(user.ID, sid); err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+	}
+	op.RecordAudit(c.Request.Context(), model.AuditLogout, "", "", nil)
 	common.SuccessResp(c)
 }
\ No newline at end of file
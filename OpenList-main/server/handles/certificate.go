@@ -1,13 +1,19 @@
 package handles
 
 import (
+	"encoding/base64"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ocsp"
 	"gorm.io/gorm"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -17,15 +23,20 @@ import (
 
 // --- Admin Handlers ---
 
+type CertificateListReq struct {
+	model.PageReq
+	ExpiringWithin int `json:"expiring_within" form:"expiring_within"` // 只列出该天数内到期的有效证书
+}
+
 // CertificateList 获取证书列表，增加了分页功能，与ListUsers风格统一
 func CertificateList(c *gin.Context) {
-	var req model.PageReq
+	var req CertificateListReq
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
 	req.Validate()
-	certs, total, err := op.GetCertificates(req.Page, req.PerPage)
+	certs, total, err := op.GetCertificates(req.Page, req.PerPage, req.ExpiringWithin)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -64,7 +75,8 @@ func CreateCertificate(c *gin.Context) {
 	}
 	
 	// 调用服务层创建证书
-	err := op.CreateCertificate(cert)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	err := op.CreateCertificate(cert, user, c.ClientIP())
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -79,6 +91,7 @@ func CreateCertificateRequest(c *gin.Context) {
 		UserID   uint                  `json:"user_id"`
 		Type     model.CertificateType `json:"type" binding:"required"`
 		Reason   string                `json:"reason" binding:"required"`
+		CSR      string                `json:"csr"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.ErrorResp(c, err, 400)
@@ -90,13 +103,19 @@ func CreateCertificateRequest(c *gin.Context) {
 		UserID:   req.UserID,
 		Type:     req.Type,
 		Reason:   req.Reason,
+		CSR:      req.CSR,
 		Status:   model.CertificateStatusPending,
 	}
 
 	// 调用服务层创建证书申请
-	err := op.CreateCertificateRequest(request)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	err := op.CreateCertificateRequest(request, user, c.ClientIP())
 	if err != nil {
-		common.ErrorResp(c, err, 500)
+		if errors.Is(err, errs.InvalidCSR) || errors.Is(err, errs.CertificateCSRRequired) {
+			common.ErrorResp(c, err, 400)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
 		return
 	}
 	common.SuccessResp(c, request)
@@ -143,7 +162,8 @@ func UpdateCertificate(c *gin.Context) {
 	}
 
 	// 调用服务层进行更新，控制器不关心实现
-	cert, err := op.UpdateCertificateDetails(id, req.Name, expDate)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	cert, err := op.UpdateCertificateDetails(id, req.Name, expDate, user, c.ClientIP())
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -158,7 +178,8 @@ func DeleteCertificate(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	if err := op.DeleteCertificate(id); err != nil {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.DeleteCertificate(id, user, c.ClientIP()); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
@@ -182,9 +203,14 @@ func ApproveCertificateRequest(c *gin.Context) {
 	adminUser := _user.(*model.User)
 	
 	// 单一调用，封装了所有批准和创建的逻辑
-	cert, err := op.ApproveAndCreateCertificate(id, adminUser)
+	cert, err := op.ApproveAndCreateCertificate(id, adminUser, c.ClientIP())
 	if err != nil {
-		common.ErrorResp(c, err, 500)
+		if errors.Is(err, errs.CertificateRequestNotPending) || errors.Is(err, errs.CertificateNotRenewable) ||
+			errors.Is(err, errs.InvalidCSR) {
+			common.ErrorResp(c, err, 400)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
 		return
 	}
 	common.SuccessResp(c, cert)
@@ -213,9 +239,13 @@ func RejectCertificateRequest(c *gin.Context) {
 	}
 	adminUser := _user.(*model.User)
 	
-	request, err := op.RejectCertificateRequest(id, adminUser, req.Reason)
+	request, err := op.RejectCertificateRequest(id, adminUser, c.ClientIP(), req.Reason)
 	if err != nil {
-		common.ErrorResp(c, err, 500)
+		if errors.Is(err, errs.CertificateRequestNotPending) {
+			common.ErrorResp(c, err, 400)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
 		return
 	}
 	common.SuccessResp(c, request)
@@ -228,13 +258,47 @@ func RevokeCertificate(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	if err := op.RevokeCertificate(id); err != nil {
-		common.ErrorResp(c, err, 500)
+	// 吊销理由可选
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.RevokeCertificate(id, user, c.ClientIP(), req.Reason); err != nil {
+		if errors.Is(err, errs.CertificateAlreadyRevoked) {
+			common.ErrorResp(c, err, 400)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
 		return
 	}
 	common.SuccessResp(c)
 }
 
+// CertificateHistory 获取证书的审计记录：谁在何时从哪里申请、签发、吊销了该证书
+func CertificateHistory(c *gin.Context) {
+	id, err := getIDFromParam(c)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	events, err := op.GetCertificateHistory(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ErrorResp(c, err, 404)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
+		return
+	}
+	common.SuccessResp(c, events)
+}
+
 // --- Tenant Handlers ---
 
 // GetTenantCertificate 获取租户自己的证书
@@ -293,6 +357,7 @@ func CreateTenantCertificateRequest(c *gin.Context) {
 	var req struct {
 		Type   model.CertificateType `json:"type" binding:"required"`
 		Reason string                `json:"reason"`
+		CSR    string                `json:"csr"` // 可选，PEM格式的PKCS#10证书签名请求
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.ErrorResp(c, err, 400)
@@ -300,10 +365,12 @@ func CreateTenantCertificateRequest(c *gin.Context) {
 	}
 
 	// 3. 使用op包中的业务逻辑函数处理申请
-	request, err := op.CreateTenantCertificateRequest(user, req.Type, req.Reason)
+	request, err := op.CreateTenantCertificateRequest(user, c.ClientIP(), req.Type, req.Reason, req.CSR)
 	if err != nil {
 		if errors.Is(err, errs.CertificateAlreadyExists) || errors.Is(err, errs.CertificateRequestPending) {
 			common.ErrorResp(c, err, 409)
+		} else if errors.Is(err, errs.InvalidCSR) || errors.Is(err, errs.CertificateCSRRequired) {
+			common.ErrorResp(c, err, 400)
 		} else {
 			common.ErrorResp(c, err, 500)
 		}
@@ -313,9 +380,36 @@ func CreateTenantCertificateRequest(c *gin.Context) {
 	common.SuccessResp(c, request)
 }
 
+// RenewTenantCertificate 租户申请续期自己的证书
+func RenewTenantCertificate(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	var req struct {
+		Reason string `json:"reason"`
+		CSR    string `json:"csr"` // 可选，续期时必须使用新的密钥
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	request, err := op.CreateTenantRenewalRequest(user, c.ClientIP(), req.Reason, req.CSR)
+	if err != nil {
+		if errors.Is(err, errs.CertificateRequestPending) {
+			common.ErrorResp(c, err, 409)
+		} else if errors.Is(err, errs.CertificateNotRenewable) || errors.Is(err, errs.InvalidCSR) ||
+			errors.Is(err, errs.CertificateCSRRequired) {
+			common.ErrorResp(c, err, 400)
+		} else {
+			common.ErrorResp(c, err, 500)
+		}
+		return
+	}
+	common.SuccessResp(c, request)
+}
+
 // DownloadCertificate 租户下载自己的证书
+// 私钥仅在首次下载时随证书一起返回，之后只返回证书
 func DownloadCertificate(c *gin.Context) {
-	user := c.MustGet(string(conf.UserKey)).(*model.User)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	cert, err := op.GetCertificateForTenant(user.ID)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
@@ -336,6 +430,67 @@ func DownloadCertificate(c *gin.Context) {
 		return
 	}
 
+	key, err := op.TakeCertificatePrivateKey(cert)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if key != "" {
+		c.Header("Content-Disposition", "attachment; filename="+cert.Name+".pem")
+		c.Data(http.StatusOK, "application/x-pem-file", []byte(cert.Content+key))
+		return
+	}
 	c.Header("Content-Disposition", "attachment; filename="+cert.Name+".crt")
 	c.Data(http.StatusOK, "application/x-x509-ca-cert", []byte(cert.Content))
 }
+
+// DownloadCACertificate 下载用于校验租户证书的CA证书
+func DownloadCACertificate(c *gin.Context) {
+	authority := ca.Get()
+	if authority == nil {
+		common.ErrorStrResp(c, "certificate authority is not initialized", 500)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=openlist-ca.crt")
+	c.Data(http.StatusOK, "application/x-x509-ca-cert", authority.CertPEM)
+}
+
+// CertificateCRL 获取CA签名的证书吊销列表(DER格式)
+func CertificateCRL(c *gin.Context) {
+	crl, err := op.GetCRL()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=openlist-ca.crl")
+	c.Data(http.StatusOK, "application/pkix-crl", crl)
+}
+
+// CertificateOCSP 最小化的OCSP响应器，支持RFC 6960中的GET和POST两种请求方式
+func CertificateOCSP(c *gin.Context) {
+	var (
+		data []byte
+		err  error
+	)
+	if c.Request.Method == http.MethodGet {
+		data, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(c.Param("request"), "/"))
+	} else {
+		data, err = io.ReadAll(io.LimitReader(c.Request.Body, 64*1024))
+	}
+	if err != nil {
+		c.Data(http.StatusOK, "application/ocsp-response", ocsp.MalformedRequestErrorResponse)
+		return
+	}
+	resp, err := op.RespondOCSP(data)
+	if errors.Is(err, ca.ErrForeignIssuer) {
+		c.Data(http.StatusOK, "application/ocsp-response", ocsp.UnauthorizedErrorResponse)
+		return
+	}
+	if err != nil {
+		log.Debugf("failed to respond OCSP request: %+v", err)
+		c.Data(http.StatusOK, "application/ocsp-response", ocsp.MalformedRequestErrorResponse)
+		return
+	}
+	c.Header("Cache-Control", "max-age=0, no-cache")
+	c.Data(http.StatusOK, "application/ocsp-response", resp)
+}
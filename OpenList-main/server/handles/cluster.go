@@ -0,0 +1,20 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ListClusterNodes(c *gin.Context) {
+	if !cluster.Enabled() {
+		common.ErrorStrResp(c, "cluster mode is not enabled", 400)
+		return
+	}
+	nodes, err := cluster.Nodes()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, nodes)
+}
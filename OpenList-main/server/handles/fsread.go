@@ -90,11 +90,11 @@ func FsList(c *gin.Context, req *ListReq, user *model.User) {
 		}
 	}
 	common.GinWithValue(c, conf.MetaKey, meta)
-	if !common.CanAccess(user, meta, reqPath, req.Password) {
+	if !op.CanPath(user, meta, reqPath, model.PathList, req.Password) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
-	if !user.CanWrite() && !common.CanWrite(meta, reqPath) && req.Refresh {
+	if !op.CanPath(user, meta, reqPath, model.PathWrite, "") && req.Refresh {
 		common.ErrorStrResp(c, "Refresh without permission", 403)
 		return
 	}
@@ -114,7 +114,7 @@ func FsList(c *gin.Context, req *ListReq, user *model.User) {
 		Total:    int64(total),
 		Readme:   getReadme(meta, reqPath),
 		Header:   getHeader(meta, reqPath),
-		Write:    user.CanWrite() || common.CanWrite(meta, reqPath),
+		Write:    op.CanPath(user, meta, reqPath, model.PathWrite, ""),
 		Provider: provider,
 	})
 }
@@ -148,7 +148,7 @@ func FsDirs(c *gin.Context) {
 		}
 	}
 	common.GinWithValue(c, conf.MetaKey, meta)
-	if !common.CanAccess(user, meta, reqPath, req.Password) {
+	if !op.CanPath(user, meta, reqPath, model.PathList, req.Password) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
@@ -289,7 +289,7 @@ func FsGet(c *gin.Context, req *FsGetReq, user *model.User) {
 		}
 	}
 	common.GinWithValue(c, conf.MetaKey, meta)
-	if !common.CanAccess(user, meta, reqPath, req.Password) {
+	if !op.CanPath(user, meta, reqPath, model.PathRead, req.Password) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
@@ -413,7 +413,7 @@ func FsOther(c *gin.Context) {
 		}
 	}
 	common.GinWithValue(c, conf.MetaKey, meta)
-	if !common.CanAccess(user, meta, req.Path, req.Password) {
+	if !op.CanPath(user, meta, req.Path, model.PathRead, req.Password) {
 		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
 		return
 	}
@@ -3,12 +3,14 @@ package handles
 import (
 	"context"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/cluster"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/search"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -23,7 +25,13 @@ func BuildIndex(c *gin.Context) {
 		common.ErrorStrResp(c, "index is running", 400)
 		return
 	}
+	release, err := cluster.Lock("index")
+	if err != nil {
+		common.ErrorResp(c, errors.WithMessage(err, "index is running"), 400)
+		return
+	}
 	go func() {
+		defer release()
 		ctx := context.Background()
 		err := search.Clear(ctx)
 		if err != nil {
@@ -53,7 +61,13 @@ func UpdateIndex(c *gin.Context) {
 		common.ErrorStrResp(c, "update is not supported for current index", 400)
 		return
 	}
+	release, err := cluster.Lock("index")
+	if err != nil {
+		common.ErrorResp(c, errors.WithMessage(err, "index is running"), 400)
+		return
+	}
 	go func() {
+		defer release()
 		ctx := context.Background()
 		for _, path := range req.Paths {
 			err := search.Del(ctx, path)
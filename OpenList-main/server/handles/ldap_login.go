@@ -1,13 +1,11 @@
 package handles
 
 import (
-	"crypto/tls"
 	"errors"
-	"fmt"
-	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/ldap"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -15,7 +13,6 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
-	"gopkg.in/ldap.v3"
 )
 
 func LoginLdap(c *gin.Context) {
@@ -43,76 +40,82 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 		return
 	}
 
-	// Auth start
-	ldapServer := setting.GetStr(conf.LdapServer)
-	ldapManagerDN := setting.GetStr(conf.LdapManagerDN)
-	ldapManagerPassword := setting.GetStr(conf.LdapManagerPassword)
-	ldapUserSearchBase := setting.GetStr(conf.LdapUserSearchBase)
-	ldapUserSearchFilter := setting.GetStr(conf.LdapUserSearchFilter) // (uid=%s)
-
-	// Connect to LdapServer
-	l, err := dial(ldapServer)
-	if err != nil {
-		utils.Log.Errorf("failed to connect to LDAP: %v", err)
-		common.ErrorResp(c, err, 500)
-		return
-	}
-
-	// First bind with a read only user
-	if ldapManagerDN != "" && ldapManagerPassword != "" {
-		err = l.Bind(ldapManagerDN, ldapManagerPassword)
-		if err != nil {
-			utils.Log.Errorf("Failed to bind to LDAP: %v", err)
-			common.ErrorResp(c, err, 500)
+	// the account of a user who has signed in before is locked out like with the password login
+	user, userErr := op.GetUserByName(req.Username)
+	if userErr == nil {
+		if err := op.CheckAccountLocked(user); err != nil {
+			common.ErrorResp(c, err, loginErrCode(err))
 			return
 		}
 	}
 
-	// Search for the given username
-	searchRequest := ldap.NewSearchRequest(
-		ldapUserSearchBase,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		fmt.Sprintf(ldapUserSearchFilter, req.Username),
-		[]string{"dn"},
-		nil,
-	)
-	sr, err := l.Search(searchRequest)
+	// Auth start
+	l, err := ldap.Connect()
 	if err != nil {
-		utils.Log.Errorf("LDAP search failed: %v", err)
+		utils.Log.Errorf("%v", err)
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	if len(sr.Entries) != 1 {
-		utils.Log.Errorf("User does not exist or too many entries returned")
+	defer l.Close()
+
+	// Search for the given username
+	entry, err := ldap.SearchUser(l, req.Username)
+	if err != nil {
+		utils.Log.Errorf("%v", err)
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	userDN := sr.Entries[0].DN
 
 	// Bind as the user to verify their password
-	err = l.Bind(userDN, req.Password)
+	err = l.Bind(entry.DN, req.Password)
 	if err != nil {
 		utils.Log.Errorf("Failed to auth. %v", err)
 		common.ErrorResp(c, err, 400)
 		model.LoginCache.Set(ip, count+1)
+		if userErr == nil {
+			if err := op.LoginFailed(user); err != nil {
+				utils.Log.Errorf("failed to record the failed login of %s: %+v", user.Username, err)
+			}
+		}
 		return
 	} else {
 		utils.Log.Infof("Auth successful username:%s", req.Username)
 	}
+	if ldap.Locked(entry) {
+		common.ErrorStrResp(c, "the account is locked in the directory", 403)
+		return
+	}
 	// Auth finished
 
-	user, err := op.GetUserByName(req.Username)
-	if err != nil {
-		user, err = ladpRegister(req.Username)
+	groups := ldap.Groups(entry)
+	if userErr != nil {
+		user, err = ladpRegister(req.Username, entry.DN, groups)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
 			model.LoginCache.Set(ip, count+1)
 			return
 		}
+	} else if user.LdapDN != entry.DN {
+		user.LdapDN = entry.DN
+		if err = op.UpdateUser(user); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	if user.Disabled {
+		common.ErrorStrResp(c, "Current user is disabled", 401)
+		return
+	}
+	if groups != nil {
+		syncExternalGroups(user, groups)
+	}
+	if err = op.LoginSucceeded(user); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
 	}
 
 	// generate token
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
@@ -121,7 +124,9 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 	model.LoginCache.Del(ip)
 }
 
-func ladpRegister(username string) (*model.User, error) {
+// ladpRegister creates the user on its first login, it gets the permission and base path of the
+// groups its LDAP groups are mapped to, or the LDAP defaults if they are mapped to none
+func ladpRegister(username, dn string, groups []string) (*model.User, error) {
 	if username == "" {
 		return nil, errors.New("cannot get username from ldap provider")
 	}
@@ -133,25 +138,18 @@ func ladpRegister(username string) (*model.User, error) {
 		BasePath:   setting.GetStr(conf.LdapDefaultDir),
 		Role:       0,
 		Disabled:   false,
+		LdapDN:     dn,
 	}
-	if err := db.CreateUser(user); err != nil {
+	mapped, err := op.HasExternalGroup(groups)
+	if err != nil {
 		return nil, err
 	}
-	return user, nil
-}
-
-func dial(ldapServer string) (*ldap.Conn, error) {
-	var tlsEnabled bool = false
-	if strings.HasPrefix(ldapServer, "ldaps://") {
-		tlsEnabled = true
-		ldapServer = strings.TrimPrefix(ldapServer, "ldaps://")
-	} else if strings.HasPrefix(ldapServer, "ldap://") {
-		ldapServer = strings.TrimPrefix(ldapServer, "ldap://")
+	if mapped {
+		user.Permission = 0
+		user.BasePath = ""
 	}
-
-	if tlsEnabled {
-		return ldap.DialTLS("tcp", ldapServer, &tls.Config{InsecureSkipVerify: true})
-	} else {
-		return ldap.Dial("tcp", ldapServer)
+	if err := db.CreateUser(user); err != nil {
+		return nil, err
 	}
+	return user, nil
 }
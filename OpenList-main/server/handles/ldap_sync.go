@@ -0,0 +1,23 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/ldap"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLdapSyncReport returns the report of the last reconciliation of the LDAP users
+func GetLdapSyncReport(c *gin.Context) {
+	common.SuccessResp(c, ldap.LastSyncReport())
+}
+
+// SyncLdap reconciles the LDAP users now and returns what it changed
+func SyncLdap(c *gin.Context) {
+	report, err := ldap.Sync()
+	report.Log()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, report)
+}
@@ -14,14 +14,17 @@ import (
 )
 
 func ListMetas(c *gin.Context) {
-	var req model.PageReq
+	var req struct {
+		model.PageReq
+		model.TenantReq
+	}
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
 	req.Validate()
 	log.Debugf("%+v", req)
-	metas, total, err := op.GetMetas(req.Page, req.PerPage)
+	metas, total, err := op.GetMetas(req.Page, req.PerPage, req.TenantID)
 	if err != nil {
 		common.ErrorResp(c, err, 500, true)
 		return
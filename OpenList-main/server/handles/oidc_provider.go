@@ -0,0 +1,306 @@
+package handles
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/idp"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCAuthorizeReq struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+func (r *OIDCAuthorizeReq) authRequest() *model.OIDCAuthRequest {
+	return &model.OIDCAuthRequest{
+		ClientID:            r.ClientID,
+		RedirectURI:         r.RedirectURI,
+		Scope:               r.Scope,
+		Nonce:               r.Nonce,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+	}
+}
+
+// redirect returns the redirect uri of the client with the given response parameters
+func (r *OIDCAuthorizeReq) redirect(params url.Values) string {
+	if r.State != "" {
+		params.Set("state", r.State)
+	}
+	sep := "?"
+	if strings.Contains(r.RedirectURI, "?") {
+		sep = "&"
+	}
+	return r.RedirectURI + sep + params.Encode()
+}
+
+func oidcIssuer(c *gin.Context) string {
+	return common.GetApiUrl(c) + "/oidc"
+}
+
+// oidcError writes an error response of RFC 6749
+func oidcError(c *gin.Context, err error) {
+	status, code := 500, "server_error"
+	for _, e := range []error{errs.OIDCInvalidRequest, errs.OIDCInvalidClient, errs.OIDCInvalidGrant, errs.OIDCInvalidToken} {
+		if errors.Is(err, e) {
+			status, code = 400, e.Error()
+			break
+		}
+	}
+	if code == errs.OIDCInvalidClient.Error() || code == errs.OIDCInvalidToken.Error() {
+		status = 401
+	}
+	if code == errs.OIDCInvalidToken.Error() {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": code, "error_description": err.Error()})
+}
+
+func OIDCDiscovery(c *gin.Context) {
+	issuer := oidcIssuer(c)
+	c.JSON(200, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "name"},
+	})
+}
+
+func OIDCJWKS(c *gin.Context) {
+	p := idp.Get()
+	if p == nil {
+		common.ErrorStrResp(c, "oidc provider is not initialized", 500)
+		return
+	}
+	c.JSON(200, p.JWKS())
+}
+
+// OIDCAuthorize serves a page which approves the request with the token of the user logged in to the frontend,
+// users who are not logged in are sent to the login page first, with their 2FA or WebAuthn if enabled.
+// The clients are registered by the admin, so no consent is asked.
+func OIDCAuthorize(c *gin.Context) {
+	var req OIDCAuthorizeReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.CheckOIDCAuthRequest(req.authRequest(), req.ResponseType); err != nil {
+		if errors.Is(err, errs.OIDCInvalidClient) {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		c.Redirect(302, req.redirect(url.Values{"error": {errors.Unwrap(err).Error()}, "error_description": {err.Error()}}))
+		return
+	}
+	api, _ := utils.Json.MarshalToString(common.GetApiUrl(c))
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<head><title>%s</title></head>
+<body>
+<p>Signing in...</p>
+<script>
+const api = %s
+const login = () => window.location.replace(api + "/@login?redirect=" + encodeURIComponent(window.location.pathname + window.location.search))
+const token = localStorage.getItem("token")
+if (!token) {
+	login()
+} else {
+	fetch(api + "/api/auth/oidc/authorize" + window.location.search, {method: "POST", headers: {Authorization: token}})
+		.then(resp => resp.json())
+		.then(resp => {
+			if (resp.code === 200) window.location.replace(resp.data.redirect)
+			else if (resp.code === 401) login()
+			else document.body.innerText = resp.message
+		})
+}
+</script>
+</body>`, html.EscapeString(setting.GetStr(conf.SiteTitle)), api)
+	c.Data(200, "text/html; charset=utf-8", []byte(page))
+}
+
+// OIDCApprove issues the code of an authorization request for the current user
+func OIDCApprove(c *gin.Context) {
+	var req OIDCAuthorizeReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	authReq := req.authRequest()
+	if err := op.CheckOIDCAuthRequest(authReq, req.ResponseType); err != nil {
+		if errors.Is(err, errs.OIDCInvalidClient) {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		common.SuccessResp(c, gin.H{
+			"redirect": req.redirect(url.Values{"error": {errors.Unwrap(err).Error()}, "error_description": {err.Error()}}),
+		})
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	code, err := op.NewOIDCAuthCode(user, authReq)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{"redirect": req.redirect(url.Values{"code": {code}})})
+}
+
+func OIDCToken(c *gin.Context) {
+	if c.PostForm("grant_type") != "authorization_code" {
+		oidcError(c, errs.NewErr(errs.OIDCInvalidRequest, "only the authorization_code grant type is supported"))
+		return
+	}
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if ok {
+		// the credentials of client_secret_basic are form encoded
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	req, err := op.ExchangeOIDCAuthCode(clientID, clientSecret, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	tokens, err := op.IssueOIDCTokens(oidcIssuer(c), req)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, tokens)
+}
+
+func OIDCUserInfo(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		oidcError(c, errs.NewErr(errs.OIDCInvalidToken, "a bearer token is required"))
+		return
+	}
+	info, err := op.GetOIDCUserInfo(oidcIssuer(c), token)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	c.JSON(200, info)
+}
+
+func ListOIDCProviderClients(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	clients, total, err := op.GetOIDCClients(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: clients,
+		Total:   total,
+	})
+}
+
+func GetOIDCProviderClient(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	client, err := op.GetOIDCClientById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, client)
+}
+
+type OIDCClientResp struct {
+	*model.OIDCClient
+	ClientSecret string `json:"client_secret,omitempty"` // only returned once, when it is generated
+}
+
+func CreateOIDCProviderClient(c *gin.Context) {
+	var req model.OIDCClient
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	secret, err := op.CreateOIDCClient(&req)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, OIDCClientResp{OIDCClient: &req, ClientSecret: secret})
+}
+
+func UpdateOIDCProviderClient(c *gin.Context) {
+	var req model.OIDCClient
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateOIDCClient(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func ResetOIDCProviderClientSecret(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	secret, err := op.ResetOIDCClientSecret(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, gin.H{"client_secret": secret})
+}
+
+func DeleteOIDCProviderClient(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.DeleteOIDCClientById(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -51,14 +51,14 @@ func Search(c *gin.Context) {
 	}
 	var filteredNodes []model.SearchNode
 	for _, node := range nodes {
-		if !strings.HasPrefix(node.Parent, user.BasePath) {
+		if !strings.HasPrefix(node.Parent, user.EffectiveBasePath()) || !user.InNamespace(node.Parent) {
 			continue
 		}
 		meta, err := op.GetNearestMeta(node.Parent)
 		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
 			continue
 		}
-		if !common.CanAccess(user, meta, path.Join(node.Parent, node.Name), req.Password) {
+		if !op.CanPath(user, meta, path.Join(node.Parent, node.Name), model.PathRead, req.Password) {
 			continue
 		}
 		filteredNodes = append(filteredNodes, node)
@@ -0,0 +1,83 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionResp struct {
+	model.Session
+	Current bool `json:"current"`
+}
+
+func ListMySessions(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	current, _ := c.Request.Context().Value(conf.SessionKey).(string)
+	sessions, err := op.GetSessionsByUserId(user.ID)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	resp := make([]SessionResp, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, SessionResp{Session: s, Current: s.ID == current})
+	}
+	common.SuccessResp(c, resp)
+}
+
+func RevokeMySession(c *gin.Context) {
+	id := c.Query("id")
+	if id == "" {
+		common.ErrorStrResp(c, "id is required", 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.RevokeSession(user.ID, id); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// RevokeMySessions revokes all sessions of the current user, including the current one
+func RevokeMySessions(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.RevokeUserSessions(user.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func ListUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	sessions, err := op.GetSessionsByUserId(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, sessions)
+}
+
+// ForceLogout revokes all sessions of the user, its personal API tokens are kept
+func ForceLogout(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	if err = op.RevokeUserSessions(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
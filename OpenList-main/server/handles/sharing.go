@@ -33,7 +33,7 @@ func SharingGet(c *gin.Context, req *FsGetReq) {
 	if dealError(c, err) {
 		return
 	}
-	_ = countAccess(c.ClientIP(), s)
+	_ = countAccess(c, s)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	url := ""
 	if !obj.IsDir() {
@@ -79,7 +79,7 @@ func SharingList(c *gin.Context, req *ListReq) {
 	if dealError(c, err) {
 		return
 	}
-	_ = countAccess(c.ClientIP(), s)
+	_ = countAccess(c, s)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	total, objs := pagination(objs, &req.PageReq)
 	common.SuccessResp(c, FsListResp{
@@ -135,7 +135,7 @@ func SharingArchiveMeta(c *gin.Context, req *ArchiveMetaReq) {
 	if dealError(c, err) {
 		return
 	}
-	_ = countAccess(c.ClientIP(), s)
+	_ = countAccess(c, s)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	url := fmt.Sprintf("%s/sad%s", common.GetApiUrl(c), utils.EncodePath(fakePath, true))
 	if s.Pwd != "" {
@@ -181,7 +181,7 @@ func SharingArchiveList(c *gin.Context, req *ArchiveListReq) {
 	if dealError(c, err) {
 		return
 	}
-	_ = countAccess(c.ClientIP(), s)
+	_ = countAccess(c, s)
 	total, objs := pagination(objs, &req.PageReq)
 	ret, _ := utils.SliceConvert(objs, func(src model.Obj) (ObjResp, error) {
 		return toObjsRespWithoutSignAndThumb(src), nil
@@ -223,7 +223,7 @@ func SharingDown(c *gin.Context) {
 		if _, ok := c.GetQuery("d"); !ok {
 			if url := common.GenerateDownProxyURL(storage.GetStorage(), unwrapPath); url != "" {
 				c.Redirect(302, url)
-				_ = countAccess(c.ClientIP(), s)
+				_ = countAccess(c, s)
 				return
 			}
 		}
@@ -235,7 +235,7 @@ func SharingDown(c *gin.Context) {
 			common.ErrorPage(c, errors.WithMessage(err, "failed get sharing link"), 500)
 			return
 		}
-		_ = countAccess(c.ClientIP(), s)
+		_ = countAccess(c, s)
 		proxy(c, link, obj, storage.GetStorage().ProxyRange)
 	} else {
 		link, _, err := op.Link(c.Request.Context(), storage, actualPath, model.LinkArgs{
@@ -248,7 +248,7 @@ func SharingDown(c *gin.Context) {
 			common.ErrorPage(c, errors.WithMessage(err, "failed get sharing link"), 500)
 			return
 		}
-		_ = countAccess(c.ClientIP(), s)
+		_ = countAccess(c, s)
 		redirect(c, link)
 	}
 }
@@ -377,7 +377,10 @@ func GetSharing(c *gin.Context) {
 }
 
 func ListSharings(c *gin.Context) {
-	var req model.PageReq
+	var req struct {
+		model.PageReq
+		model.TenantReq
+	}
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
@@ -388,7 +391,7 @@ func ListSharings(c *gin.Context) {
 	var total int64
 	var err error
 	if user.IsAdmin() {
-		sharings, total, err = op.GetSharings(req.Page, req.PerPage)
+		sharings, total, err = op.GetSharings(req.Page, req.PerPage, req.TenantID)
 	} else {
 		sharings, total, err = op.GetSharingsByCreatorId(user.ID, req.Page, req.PerPage)
 	}
@@ -437,17 +440,17 @@ func UpdateSharing(c *gin.Context) {
 		return
 	}
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
-	if !user.CanShare() {
-		common.ErrorStrResp(c, "permission denied", 403)
-		return
-	}
 	for i, s := range req.Files {
 		s = utils.FixAndCleanPath(s)
 		req.Files[i] = s
-		if !user.IsAdmin() && !strings.HasPrefix(s, user.BasePath) {
+		if !user.IsAdmin() && !strings.HasPrefix(s, user.EffectiveBasePath()) {
 			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 500)
 			return
 		}
+		if !op.CanPath(user, nil, s, model.PathShare, "") {
+			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 403)
+			return
+		}
 	}
 	s, err := op.GetSharingById(req.ID)
 	if err != nil || (!user.IsAdmin() && s.CreatorId != user.ID) {
@@ -487,17 +490,17 @@ func CreateSharing(c *gin.Context) {
 		return
 	}
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
-	if !user.CanShare() {
-		common.ErrorStrResp(c, "permission denied", 403)
-		return
-	}
 	for i, s := range req.Files {
 		s = utils.FixAndCleanPath(s)
 		req.Files[i] = s
-		if !user.IsAdmin() && !strings.HasPrefix(s, user.BasePath) {
+		if !user.IsAdmin() && !strings.HasPrefix(s, user.EffectiveBasePath()) {
 			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 500)
 			return
 		}
+		if !op.CanPath(user, nil, s, model.PathShare, "") {
+			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 403)
+			return
+		}
 	}
 	s := &model.Sharing{
 		SharingDB: &model.SharingDB{
@@ -565,12 +568,20 @@ var (
 	AccessCountDelay = 30 * time.Minute
 )
 
-func countAccess(ip string, s *model.Sharing) error {
-	key := fmt.Sprintf("%s:%s", s.ID, ip)
+// countAccess counts an access of the sharing and records it in the audit log,
+// the accesses from the same ip within AccessCountDelay are counted once
+func countAccess(c *gin.Context, s *model.Sharing) error {
+	key := fmt.Sprintf("%s:%s", s.ID, c.ClientIP())
 	_, ok := AccessCache.Get(key)
 	if !ok {
 		AccessCache.Set(key, struct{}{}, cache.WithEx[interface{}](AccessCountDelay))
 		s.Accessed += 1
+		// the download routes of the sharings are outside the api, so the event is filled here
+		e := &model.AuditEvent{IP: c.ClientIP(), Protocol: model.ProtocolWeb, Action: model.AuditShareAccess, Target: s.ID}
+		if user, ok := c.Request.Context().Value(conf.UserKey).(*model.User); ok {
+			e.UserID, e.Username = user.ID, user.Username
+		}
+		op.RecordAuditEvent(e)
 		return op.UpdateSharing(s, true)
 	}
 	return nil
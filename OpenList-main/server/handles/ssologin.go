@@ -15,6 +15,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
@@ -171,6 +172,28 @@ func autoRegister(username, userID string, err error) (*model.User, error) {
 	return user, nil
 }
 
+// ssoGroups returns the groups claim of the SSO user, nil if the claim is absent
+func ssoGroups(body []byte) []string {
+	claim := setting.GetStr(conf.SSOGroupsClaim)
+	if claim == "" {
+		return nil
+	}
+	var groups []string
+	utils.Json.Get(body, claim).ToVal(&groups)
+	return groups
+}
+
+// syncExternalGroups maps the LDAP or SSO groups of the user to its groups,
+// a failure is logged and does not block the login
+func syncExternalGroups(user *model.User, names []string) {
+	if names == nil {
+		names = []string{}
+	}
+	if err := op.SyncExternalGroups(user, names); err != nil {
+		utils.Log.Errorf("failed to sync external groups of %s: %+v", user.Username, err)
+	}
+}
+
 func parseJWT(p string) ([]byte, error) {
 	parts := strings.Split(p, ".")
 	if len(parts) < 2 {
@@ -258,9 +281,13 @@ func OIDCLoginCallback(c *gin.Context) {
 				common.ErrorResp(c, err, 400)
 			}
 		}
-		token, err := common.GenerateToken(user)
+		if groups := ssoGroups(payload); err == nil && groups != nil {
+			syncExternalGroups(user, groups)
+		}
+		token, err := common.GenerateToken(c, user)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
+			return
 		}
 		if useCompatibility {
 			c.Redirect(302, common.GetApiUrl(c)+"/@login?token="+token)
@@ -424,9 +451,13 @@ func SSOLoginCallback(c *gin.Context) {
 			return
 		}
 	}
-	token, err := common.GenerateToken(user)
+	if groups := ssoGroups(resp.Body()); groups != nil {
+		syncExternalGroups(user, groups)
+	}
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
+		return
 	}
 	if usecompatibility {
 		c.Redirect(302, common.GetApiUrl(c)+"/@login?token="+token)
@@ -14,14 +14,17 @@ import (
 )
 
 func ListStorages(c *gin.Context) {
-	var req model.PageReq
+	var req struct {
+		model.PageReq
+		model.TenantReq
+	}
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
 	req.Validate()
 	log.Debugf("%+v", req)
-	storages, total, err := db.GetStorages(req.Page, req.PerPage)
+	storages, total, err := db.GetStorages(req.Page, req.PerPage, req.TenantID)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -150,3 +153,49 @@ func LoadAllStorages(c *gin.Context) {
 	}(storages)
 	common.SuccessResp(c)
 }
+
+// StorageHealth returns the health history of the loaded storages, or of the one of the id
+func StorageHealth(c *gin.Context) {
+	healths := op.GetStorageHealths()
+	if idStr := c.Query("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		for _, h := range healths {
+			if h.ID == uint(id) {
+				common.SuccessResp(c, h)
+				return
+			}
+		}
+		common.ErrorStrResp(c, "storage not loaded", 404)
+		return
+	}
+	common.SuccessResp(c, healths)
+}
+
+// CheckStorageHealth checks the storage of the id at once
+func CheckStorageHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	storage, err := db.GetStorageById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	storageDriver, err := op.GetStorageByMountPath(storage.MountPath)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	check, err := op.CheckStorageHealth(c.Request.Context(), storageDriver)
+	if err != nil {
+		common.ErrorResp(c, err, 409)
+		return
+	}
+	common.SuccessResp(c, check)
+}
@@ -21,6 +21,7 @@ type TaskInfo struct {
 	Name        string      `json:"name"`
 	Creator     string      `json:"creator"`
 	CreatorRole int         `json:"creator_role"`
+	TenantID    uint        `json:"tenant_id"`
 	State       tache.State `json:"state"`
 	Status      string      `json:"status"`
 	Progress    float64     `json:"progress"`
@@ -42,15 +43,18 @@ func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
 	}
 	creatorName := ""
 	creatorRole := -1
+	var tenantID uint
 	if task.GetCreator() != nil {
 		creatorName = task.GetCreator().Username
 		creatorRole = task.GetCreator().Role
+		tenantID = task.GetCreator().TenantID
 	}
 	return TaskInfo{
 		ID:          task.GetID(),
 		Name:        task.GetName(),
 		Creator:     creatorName,
 		CreatorRole: creatorRole,
+		TenantID:    tenantID,
 		State:       task.GetState(),
 		Status:      task.GetStatus(),
 		Progress:    progress,
@@ -77,6 +81,18 @@ func getUserInfo(c *gin.Context) (bool, uint, bool) {
 	}
 }
 
+// getTenantFilter returns whether a task created by the user is in the tenant selected by the admin,
+// all tasks pass if no tenant is selected
+func getTenantFilter(c *gin.Context) (func(creator *model.User) bool, error) {
+	var req model.TenantReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		return nil, err
+	}
+	return func(creator *model.User) bool {
+		return req.TenantID == nil || (creator != nil && creator.TenantID == *req.TenantID)
+	}, nil
+}
+
 func getTargetedHandler[T task.TaskExtensionInfo](manager task.Manager[T], callback func(c *gin.Context, task T)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		isAdmin, uid, ok := getUserInfo(c)
@@ -132,9 +148,14 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			common.ErrorStrResp(c, "user invalid", 401)
 			return
 		}
+		inTenant, err := getTenantFilter(c)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
 		common.SuccessResp(c, getTaskInfos(manager.GetByCondition(func(task T) bool {
 			// avoid directly passing the user object into the function to reduce closure size
-			return (isAdmin || uid == task.GetCreator().ID) &&
+			return (isAdmin || uid == task.GetCreator().ID) && inTenant(task.GetCreator()) &&
 				argsContains(task.GetState(), tache.StatePending, tache.StateRunning, tache.StateCanceling,
 					tache.StateErrored, tache.StateFailing, tache.StateWaitingRetry, tache.StateBeforeRetry)
 		})))
@@ -146,8 +167,13 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			common.ErrorStrResp(c, "user invalid", 401)
 			return
 		}
+		inTenant, err := getTenantFilter(c)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
 		common.SuccessResp(c, getTaskInfos(manager.GetByCondition(func(task T) bool {
-			return (isAdmin || uid == task.GetCreator().ID) &&
+			return (isAdmin || uid == task.GetCreator().ID) && inTenant(task.GetCreator()) &&
 				argsContains(task.GetState(), tache.StateCanceled, tache.StateFailed, tache.StateSucceeded)
 		})))
 	})
@@ -225,4 +251,5 @@ func SetupTaskRoute(g *gin.RouterGroup) {
 	taskRoute(g.Group("/offline_download_transfer"), tool.TransferTaskManager)
 	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager)
 	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager)
+	taskRoute(g.Group("/mirror_check"), fs.MirrorCheckTaskManager)
 }
@@ -0,0 +1,99 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// ListTenants 列出租户，管理员可据此在各租户的命名空间之间切换
+func ListTenants(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	tenants, total, err := op.GetTenants(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: tenants,
+		Total:   total,
+	})
+}
+
+// GetTenant 获取租户信息
+func GetTenant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	tenant, err := op.GetTenantById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, tenantErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c, tenant)
+}
+
+// CreateTenant 创建租户
+func CreateTenant(c *gin.Context) {
+	var req model.Tenant
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.CreateTenant(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, req)
+}
+
+// UpdateTenant 更新租户
+func UpdateTenant(c *gin.Context) {
+	var req model.Tenant
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.UpdateTenant(&req); err != nil {
+		common.ErrorResp(c, err, tenantErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteTenant 删除租户，租户命名空间必须为空
+func DeleteTenant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := op.DeleteTenantById(uint(id)); err != nil {
+		common.ErrorResp(c, err, tenantErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func tenantErrCode(err error) int {
+	switch {
+	case errors.Is(err, errs.TenantNotFound):
+		return 404
+	case errors.Is(err, errs.TenantNotEmpty):
+		return 409
+	default:
+		return 500
+	}
+}
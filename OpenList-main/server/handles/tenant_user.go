@@ -0,0 +1,110 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ListTenantUsers 列出当前租户管理员所在租户的用户
+func ListTenantUsers(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	admin := c.Request.Context().Value(conf.UserKey).(*model.User)
+	users, total, err := op.GetTenantUsers(admin, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: users,
+		Total:   total,
+	})
+}
+
+// GetTenantUser 获取本租户用户信息
+func GetTenantUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	admin := c.Request.Context().Value(conf.UserKey).(*model.User)
+	user, err := op.GetTenantUser(admin, uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, tenantUserErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c, user)
+}
+
+// CreateTenantUser 在本租户中创建子用户
+func CreateTenantUser(c *gin.Context) {
+	var req model.User
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	admin := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.CreateTenantUser(admin, &req); err != nil {
+		common.ErrorResp(c, err, tenantUserErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// UpdateTenantUser 更新本租户的子用户，可用于禁用用户和调整权限
+func UpdateTenantUser(c *gin.Context) {
+	var req model.User
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	admin := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.UpdateTenantUser(admin, &req); err != nil {
+		common.ErrorResp(c, err, tenantUserErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// DeleteTenantUser 删除本租户的子用户
+func DeleteTenantUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	admin := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.DeleteTenantUser(admin, uint(id)); err != nil {
+		common.ErrorResp(c, err, tenantUserErrCode(err), true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func tenantUserErrCode(err error) int {
+	switch {
+	case errors.Is(err, errs.UserNotInTenant), errors.Is(err, gorm.ErrRecordNotFound):
+		return 404
+	case errors.Is(err, errs.PermissionDenied), errors.Is(err, errs.ExceedPermission),
+		errors.Is(err, errs.PathNotInTenant):
+		return 403
+	case errors.Is(err, errs.EmptyPassword), errors.Is(err, errs.WeakPassword),
+		errors.Is(err, errs.PasswordReused):
+		return 400
+	default:
+		return 500
+	}
+}
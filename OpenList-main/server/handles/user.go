@@ -15,15 +15,18 @@ import (
 
 // --- User Management ---
 
-// ListUsers 列出用户
+// ListUsers 列出用户，可按租户筛选
 func ListUsers(c *gin.Context) {
-	var req model.PageReq
+	var req struct {
+		model.PageReq
+		model.TenantReq
+	}
 	if err := c.ShouldBind(&req); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
 	req.Validate()
-	users, total, err := op.GetUsers(req.Page, req.PerPage)
+	users, total, err := op.GetUsers(req.Page, req.PerPage, req.TenantID)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -61,6 +64,13 @@ func CreateUser(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
+	if req.Password != "" {
+		if err := op.SetUserPassword(&req, req.Password); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		req.Password = ""
+	}
 	if err := op.CreateUser(&req); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -81,6 +91,25 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 	req.ID = id
+	old, err := op.GetUserById(id)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	// the credentials are not sent to the client, keep them unless a new password is given,
+	// the lockout is only reset by unlocking the user
+	req.PwdHash, req.Salt, req.PwdTS, req.PwdHistory = old.PwdHash, old.Salt, old.PwdTS, old.PwdHistory
+	req.OtpSecret, req.Authn, req.RecoveryCodes = old.OtpSecret, old.Authn, old.RecoveryCodes
+	req.FailedLogins, req.LockedAt = old.FailedLogins, old.LockedAt
+	// enabling a user deprovisioned through SCIM hands it back to the directory
+	req.Deprovisioned = old.Deprovisioned && req.Disabled
+	if req.Password != "" {
+		if err = op.SetUserPassword(&req, req.Password); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		req.Password = ""
+	}
 	if err := op.UpdateUser(&req); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -102,6 +131,20 @@ func Cancel2FAById(c *gin.Context) {
 	common.SuccessResp(c, "2FA canceled successfully")
 }
 
+// UnlockUser unlocks a user locked after too many failed sign-in attempts
+func UnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	if err = op.UnlockUser(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, "User unlocked successfully")
+}
+
 // DeleteUser 删除用户
 func DeleteUser(c *gin.Context) {
 	id, err := getIDFromParam(c)
@@ -184,7 +227,8 @@ func UpdateAdminCertificate(c *gin.Context) {
 	}
 
 	// 调用服务层进行更新，控制器不关心实现
-	cert, err := op.UpdateCertificateDetails(id, req.Name, expDate)
+	adminUser := c.Request.Context().Value(conf.UserKey).(*model.User)
+	cert, err := op.UpdateCertificateDetails(id, req.Name, expDate, adminUser, c.ClientIP())
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -199,7 +243,8 @@ func DeleteAdminCertificate(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	if err := op.DeleteCertificate(id); err != nil {
+	adminUser := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.DeleteCertificate(id, adminUser, c.ClientIP()); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
@@ -213,9 +258,9 @@ func ApproveAdminCertificateRequest(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	adminUser := c.MustGet(string(conf.UserKey)).(*model.User)
+	adminUser := c.Request.Context().Value(conf.UserKey).(*model.User)
 	// 单一调用，封装了所有批准和创建的逻辑
-	cert, err := op.ApproveAndCreateCertificate(id, adminUser)
+	cert, err := op.ApproveAndCreateCertificate(id, adminUser, c.ClientIP())
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -237,8 +282,8 @@ func RejectAdminCertificateRequest(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	adminUser := c.MustGet(string(conf.UserKey)).(*model.User)
-	request, err := op.RejectCertificateRequest(id, adminUser, req.Reason)
+	adminUser := c.Request.Context().Value(conf.UserKey).(*model.User)
+	request, err := op.RejectCertificateRequest(id, adminUser, c.ClientIP(), req.Reason)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
 		return
@@ -253,7 +298,8 @@ func AdminRevokeCertificate(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	if err := op.RevokeCertificate(id); err != nil {
+	adminUser := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := op.RevokeCertificate(id, adminUser, c.ClientIP(), ""); err != nil {
 		common.ErrorResp(c, err, 500)
 		return
 	}
@@ -264,7 +310,7 @@ func AdminRevokeCertificate(c *gin.Context) {
 
 // GetTenantUserCertificate 获取租户自己的证书，逻辑清晰
 func GetTenantUserCertificate(c *gin.Context) {
-	user := c.MustGet(string(conf.UserKey)).(*model.User)
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	cert, err := op.GetCertificateForTenant(user.ID)
 	if err != nil {
 		common.ErrorResp(c, err, 500)
@@ -9,6 +9,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/authn"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -111,7 +112,7 @@ func FinishAuthnLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := common.GenerateToken(user)
+	token, err := common.GenerateToken(c, user)
 	if err != nil {
 		common.ErrorResp(c, err, 400, true)
 		return
@@ -206,6 +207,10 @@ func DeleteAuthnLogin(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
+	if op.Requires2FA(user) && user.OtpSecret == "" && len(user.WebAuthnCredentials()) <= 1 {
+		common.ErrorResp(c, errs.TwoFactorRequired, 400)
+		return
+	}
 	err = db.RemoveAuthn(user, req.ID)
 	if err != nil {
 		common.ErrorResp(c, err, 400)
@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Protocol tags the request context with the protocol and the client ip,
+// which the audit log records for the actions done in the request
+func Protocol(protocol string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		common.GinWithValue(c, conf.ProtocolKey, protocol, conf.ClientIPKey, c.ClientIP())
+		c.Next()
+	}
+}
+
+// Audit records the requests changing something, the action is named after the route,
+// e.g. admin.user.delete for /api/admin/user/delete
+func Audit(c *gin.Context) {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		c.Next()
+		return
+	}
+	target := auditTarget(c)
+	c.Next()
+	var err error
+	if msg, ok := common.RespError(c); ok {
+		err = errors.New(msg)
+	} else if c.Writer.Status() >= http.StatusBadRequest {
+		err = errors.New(http.StatusText(c.Writer.Status()))
+	}
+	op.RecordAudit(c.Request.Context(), auditAction(c.FullPath()), target, "", err)
+}
+
+// AuditLogin records the sign-in attempts, the way of signing in is kept as the detail
+func AuditLogin(c *gin.Context) {
+	username := auditTarget(c)
+	c.Next()
+	e := &model.AuditEvent{
+		Username: username,
+		IP:       c.ClientIP(),
+		Protocol: model.ProtocolWeb,
+		Action:   model.AuditLogin,
+		Detail:   auditAction(c.FullPath()),
+	}
+	if msg, ok := common.RespError(c); ok {
+		e.Error = msg
+	} else if user, err := op.GetUserByName(username); err == nil {
+		e.UserID = user.ID
+	}
+	op.RecordAuditEvent(e)
+}
+
+func auditAction(route string) string {
+	var names []string
+	for _, name := range strings.Split(strings.TrimPrefix(route, "/api/"), "/") {
+		if name != "" && name[0] != ':' && name[0] != '*' {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+// the target of a request without an id in its path or query is taken from these fields of its body
+var auditTargetFields = []string{"id", "username", "mount_path", "name", "key"}
+
+const auditBodyLimit = 64 * 1024
+
+func auditTarget(c *gin.Context) string {
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	if id := c.Query("id"); id != "" {
+		return id
+	}
+	if id := c.Query("key"); id != "" {
+		return id
+	}
+	if c.ContentType() != gin.MIMEJSON {
+		return ""
+	}
+	// the body is put back for the handler, only its start is read
+	body, _ := io.ReadAll(io.LimitReader(c.Request.Body, auditBodyLimit))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	var fields map[string]any
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	for _, key := range auditTargetFields {
+		if v, ok := fields[key]; ok && v != nil && v != "" {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
@@ -6,8 +6,10 @@ import (
 	"crypto/subtle"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -38,7 +40,12 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 			return
 		}
 
-		// 2. Guest Check (No Token)
+		// 2. Client Certificate Check (No Token, verified TLS client certificate)
+		if token == "" && authByClientCert(c) {
+			return
+		}
+
+		// 3. Guest Check (No Token)
 		if token == "" {
 			log.Infof("[Auth Middleware] No token provided. Treating as GUEST.")
 			guest, err := op.GetGuest()
@@ -60,7 +67,13 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 			return
 		}
 
-		// 3. Parse User Token
+		// 4. Personal API Token
+		if strings.HasPrefix(token, model.ApiTokenPrefix) {
+			authByApiToken(c, token)
+			return
+		}
+
+		// 5. Parse User Token
 		log.Infof("[Auth Middleware] Token found, attempting to parse...")
 		userClaims, err := common.ParseToken(token)
 		if err != nil {
@@ -71,7 +84,7 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 		}
 		log.Infof("[Auth Middleware] Token parsed successfully for user: '%s'", userClaims.Username)
 
-		// 4. Get User from Database
+		// 6. Get User from Database
 		log.Infof("[Auth Middleware] Attempting to retrieve user '%s' from database...", userClaims.Username)
 		user, err := op.GetUserByName(userClaims.Username)
 		if err != nil {
@@ -82,7 +95,7 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 		}
 		log.Infof("[Auth Middleware] Successfully retrieved user object from DB: %+v", user)
 
-		// 5. Validate Password Timestamp
+		// 7. Validate Password Timestamp
 		if userClaims.PwdTS != user.PwdTS {
 			log.Warnf("[Auth Middleware] FAILED: Password timestamp mismatch for user '%s'. Aborting.", user.Username)
 			common.ErrorStrResp(c, "Password has been changed, login please", 401)
@@ -91,8 +104,16 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 		}
 		log.Infof("[Auth Middleware] Password timestamp check passed for user '%s'.", user.Username)
 
+		// 7.1. Validate Session
+		if _, err = op.CheckSession(userClaims.SessionID, user.ID); err != nil {
+			log.Debugf("[Auth Middleware] FAILED: Session of user '%s' is revoked. Aborting.", user.Username)
+			common.ErrorResp(c, err, 401)
+			c.Abort()
+			return
+		}
 
-		// 6. Check if User is Disabled
+
+		// 8. Check if User is Disabled
 		if user.Disabled {
 			log.Warnf("[Auth Middleware] FAILED: User '%s' is disabled. Aborting.", user.Username)
 			common.ErrorStrResp(c, "Current user is disabled, replace please", 401)
@@ -101,7 +122,12 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 		}
 		log.Infof("[Auth Middleware] Disabled check passed for user '%s'.", user.Username)
 
-		// 7. Role Validation Check
+		// 8.1. Check the Password Expiry and the 2FA Enrolment
+		if !checkUserPolicies(c, user) {
+			return
+		}
+
+		// 9. Role Validation Check
 		isValidRole := user.Role == model.ADMIN || user.Role == model.GENERAL || user.Role == model.TENANT
 		if !isValidRole || user.Role == model.GUEST {
 			log.Warnf("[Auth Middleware] FAILED: User '%s' blocked due to invalid role: %d. Aborting.", user.Username, user.Role)
@@ -111,9 +137,9 @@ func Auth(allowDisabledGuest bool) func(c *gin.Context) {
 		}
 		log.Infof("[Auth Middleware] Role check passed for user '%s' with role '%d'.", user.Username, user.Role)
 
-		// 8. Success
+		// 10. Success
 		log.Infof("[Auth Middleware] SUCCESS: All checks passed for user '%s'. Setting user in context and proceeding.", user.Username)
-		common.GinWithValue(c, conf.UserKey, user)
+		common.GinWithValue(c, conf.UserKey, user, conf.SessionKey, userClaims.SessionID)
 		c.Next()
 	}
 }
@@ -132,6 +158,9 @@ func Authn(c *gin.Context) {
 		c.Next()
 		return
 	}
+	if token == "" && authByClientCert(c) {
+		return
+	}
 	if token == "" {
 		guest, err := op.GetGuest()
 		if err != nil {
@@ -144,6 +173,10 @@ func Authn(c *gin.Context) {
 		c.Next()
 		return
 	}
+	if strings.HasPrefix(token, model.ApiTokenPrefix) {
+		authByApiToken(c, token)
+		return
+	}
 	userClaims, err := common.ParseToken(token)
 	if err != nil {
 		common.ErrorResp(c, err, 401)
@@ -162,16 +195,120 @@ func Authn(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	if _, err = op.CheckSession(userClaims.SessionID, user.ID); err != nil {
+		common.ErrorResp(c, err, 401)
+		c.Abort()
+		return
+	}
 	if user.Disabled {
 		common.ErrorStrResp(c, "Current user is disabled, replace please", 401)
 		c.Abort()
 		return
 	}
-	common.GinWithValue(c, conf.UserKey, user)
+	if !checkUserPolicies(c, user) {
+		return
+	}
+	common.GinWithValue(c, conf.UserKey, user, conf.SessionKey, userClaims.SessionID)
 	log.Debugf("use login token: %+v", user)
 	c.Next()
 }
 
+var (
+	// the routes needed to change an expired password
+	passwordChangeRoutes = []string{"/api/me", "/api/me/update", "/api/auth/logout"}
+	// the routes needed to enroll TOTP or WebAuthn
+	twoFactorEnrollRoutes = []string{"/api/me", "/api/auth/2fa/generate", "/api/auth/2fa/verify", "/api/auth/logout",
+		"/api/authn/webauthn_begin_registration", "/api/authn/webauthn_finish_registration", "/api/authn/getcredentials"}
+)
+
+// onRoute reports whether the request is on one of the routes
+func onRoute(c *gin.Context, routes []string) bool {
+	route := c.FullPath()
+	for _, r := range routes {
+		if strings.HasSuffix(route, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUserPolicies aborts the request of a user whose password has expired or who has not enrolled
+// the required 2FA, only changing the password or enrolling 2FA is allowed then.
+// It is shared by Auth and Authn for every way a user authenticates.
+func checkUserPolicies(c *gin.Context, user *model.User) bool {
+	if op.IsPasswordExpired(user) && !onRoute(c, passwordChangeRoutes) {
+		log.Debugf("[Auth Middleware] FAILED: Password of user '%s' has expired. Aborting.", user.Username)
+		common.ErrorResp(c, errs.PasswordExpired, http.StatusForbidden)
+		c.Abort()
+		return false
+	}
+	if op.Check2FAEnrolled(user) != nil && !onRoute(c, twoFactorEnrollRoutes) {
+		log.Debugf("[Auth Middleware] FAILED: User '%s' has not enrolled the required 2FA. Aborting.", user.Username)
+		common.ErrorResp(c, errs.TwoFactorRequired, http.StatusForbidden)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// authByClientCert authenticates the request with its verified TLS client certificate.
+// It returns false if there is no client certificate, otherwise the request is handled.
+func authByClientCert(c *gin.Context) bool {
+	cert := common.ClientCertificate(c.Request)
+	if cert == nil {
+		return false
+	}
+	user, err := op.GetUserByClientCertificate(cert)
+	if err != nil {
+		log.Warnf("[Auth Middleware] FAILED: client certificate %s rejected: %v", cert.Subject.CommonName, err)
+		common.ErrorResp(c, err, 401)
+		c.Abort()
+		return true
+	}
+	if user.Disabled {
+		common.ErrorStrResp(c, "Current user is disabled, replace please", 401)
+		c.Abort()
+		return true
+	}
+	if !checkUserPolicies(c, user) {
+		return true
+	}
+	log.Debugf("use client certificate: %+v", user)
+	common.GinWithValue(c, conf.UserKey, user)
+	c.Next()
+	return true
+}
+
+// authByApiToken authenticates the request with a personal API token,
+// the user in context is the owner limited to the scope of the token
+func authByApiToken(c *gin.Context, token string) {
+	user, apiToken, err := op.GetUserByApiToken(token)
+	if err != nil {
+		log.Warnf("[Auth Middleware] FAILED: api token rejected: %v", err)
+		common.ErrorResp(c, err, 401)
+		c.Abort()
+		return
+	}
+	if !checkUserPolicies(c, user) {
+		return
+	}
+	log.Debugf("use api token %s of user %s", apiToken.Name, user.Username)
+	common.GinWithValue(c, conf.UserKey, user)
+	common.GinWithValue(c, conf.ApiTokenKey, apiToken)
+	c.Next()
+}
+
+// AuthNotApiToken rejects requests authenticated by an API token,
+// used for credential management which a scoped token must not reach
+func AuthNotApiToken(c *gin.Context) {
+	if c.Request.Context().Value(conf.ApiTokenKey) != nil {
+		common.ErrorStrResp(c, "Not allowed with an API token", 403)
+		c.Abort()
+	} else {
+		c.Next()
+	}
+}
+
 func AuthNotGuest(c *gin.Context) {
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	if user.IsGuest() {
@@ -190,4 +327,15 @@ func AuthAdmin(c *gin.Context) {
 	} else {
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// AuthTenant 只允许属于某个租户的租户管理员访问
+func AuthTenant(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.Role != model.TENANT || user.TenantID == 0 {
+		common.ErrorStrResp(c, "You are not a tenant administrator", 403)
+		c.Abort()
+	} else {
+		c.Next()
+	}
+}
@@ -36,7 +36,7 @@ func FsUp(c *gin.Context) {
 			return
 		}
 	}
-	if !(common.CanAccess(user, meta, path, password) && (user.CanWrite() || common.CanWrite(meta, stdpath.Dir(path)))) {
+	if !(op.CanPath(user, meta, path, model.PathRead, password) && op.CanPath(user, meta, stdpath.Dir(path), model.PathWrite, "")) {
 		common.ErrorResp(c, errs.PermissionDenied, 403)
 		c.Abort()
 		return
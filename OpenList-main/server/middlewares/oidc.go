@@ -0,0 +1,17 @@
+package middlewares
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func OIDCProvider(c *gin.Context) {
+	if !setting.GetBool(conf.OIDCProviderEnabled) {
+		common.ErrorStrResp(c, "oidc provider is disabled", 404)
+		c.Abort()
+	} else {
+		c.Next()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/message"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -41,6 +42,7 @@ func Init(e *gin.Engine) {
 	}
 	WebDav(g.Group("/dav"))
 	S3(g.Group("/s3"))
+	SCIM(g.Group("/scim/v2"))
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	signCheck := middlewares.Down(sign.Verify)
@@ -65,21 +67,42 @@ func Init(e *gin.Engine) {
 	g.HEAD("/sad/:sid", middlewares.EmptyPathParse, middlewares.SharingIdParse, handles.SharingArchiveExtract)
 	g.HEAD("/sad/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, handles.SharingArchiveExtract)
 
-	api := g.Group("/api")
+	// OpenList as OIDC provider, the issuer is {site_url}/oidc
+	oidc := g.Group("/oidc", middlewares.OIDCProvider)
+	oidc.GET("/.well-known/openid-configuration", handles.OIDCDiscovery)
+	oidc.GET("/jwks", handles.OIDCJWKS)
+	oidc.GET("/authorize", handles.OIDCAuthorize)
+	oidc.POST("/token", handles.OIDCToken)
+	oidc.GET("/userinfo", handles.OIDCUserInfo)
+	oidc.POST("/userinfo", handles.OIDCUserInfo)
+
+	api := g.Group("/api", middlewares.Protocol(model.ProtocolWeb))
 	auth := api.Group("", middlewares.Auth(false))
 	webauthn := api.Group("/authn", middlewares.Authn)
 
-	api.POST("/auth/login", handles.Login)
-	api.POST("/auth/login/hash", handles.LoginHash)
-	api.POST("/auth/login/ldap", handles.LoginLdap)
-	api.POST("/auth/login/tenant", handles.TenantLogin) // 添加租户登录接口
+	api.POST("/auth/login", middlewares.AuditLogin, handles.Login)
+	api.POST("/auth/login/hash", middlewares.AuditLogin, handles.LoginHash)
+	api.POST("/auth/login/ldap", middlewares.AuditLogin, handles.LoginLdap)
+	api.POST("/auth/login/tenant", middlewares.AuditLogin, handles.TenantLogin) // 添加租户登录接口
+	api.POST("/auth/login/tenant/challenge", handles.TenantLoginChallenge)
 	auth.GET("/me", handles.CurrentUser)
-	auth.POST("/me/update", handles.UpdateCurrent)
+	auth.POST("/me/update", middlewares.AuthNotApiToken, handles.UpdateCurrent)
 	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
-	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
-	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
-	auth.POST("/auth/2fa/generate", handles.Generate2FA)
-	auth.POST("/auth/2fa/verify", handles.Verify2FA)
+	auth.POST("/me/sshkey/add", middlewares.AuthNotApiToken, handles.AddMyPublicKey)
+	auth.POST("/me/sshkey/delete", middlewares.AuthNotApiToken, handles.DeleteMyPublicKey)
+	auth.POST("/auth/2fa/generate", middlewares.AuthNotApiToken, handles.Generate2FA)
+	auth.POST("/auth/2fa/verify", middlewares.AuthNotApiToken, handles.Verify2FA)
+	auth.POST("/auth/2fa/recovery_codes", middlewares.AuthNotApiToken, handles.GenerateRecoveryCodes)
+	tokens := auth.Group("/me/tokens", middlewares.AuthNotGuest, middlewares.AuthNotApiToken)
+	tokens.GET("/list", handles.ListMyApiTokens)
+	tokens.POST("/create", handles.CreateMyApiToken)
+	tokens.POST("/revoke", handles.RevokeMyApiToken)
+
+	sessions := auth.Group("/me/sessions", middlewares.AuthNotGuest, middlewares.AuthNotApiToken)
+	sessions.GET("/list", handles.ListMySessions)
+	sessions.POST("/revoke", handles.RevokeMySession)
+	sessions.POST("/revoke_all", handles.RevokeMySessions)
+	auth.POST("/auth/oidc/authorize", middlewares.OIDCProvider, middlewares.AuthNotGuest, middlewares.AuthNotApiToken, handles.OIDCApprove)
 	auth.GET("/auth/logout", handles.LogOut)
 
 	// auth
@@ -101,22 +124,33 @@ func Init(e *gin.Engine) {
 	public.Any("/settings", handles.PublicSettings)
 	public.Any("/offline_download_tools", handles.OfflineDownloadTools)
 	public.Any("/archive_extensions", handles.ArchiveExtensions)
+	public.GET("/certificate/ca", handles.DownloadCACertificate)
+	public.GET("/certificate/crl", handles.CertificateCRL)
+	public.POST("/certificate/ocsp", handles.CertificateOCSP)
+	public.GET("/certificate/ocsp/*request", handles.CertificateOCSP)
 
 	_fs(auth.Group("/fs"))
 	fsAndShare(api.Group("/fs", middlewares.Auth(true)))
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
-	_sharing(auth.Group("/share", middlewares.AuthNotGuest))
+	_sharing(auth.Group("/share", middlewares.AuthNotGuest, middlewares.Audit))
 	
 	// 租户证书路由应该在auth路由组下，确保认证中间件被正确应用
-	tenant := auth.Group("/tenant")
+	tenant := auth.Group("/tenant", middlewares.AuthNotApiToken, middlewares.Audit)
 	{
 		tenant.POST("/certificate/request", handles.CreateTenantCertificateRequest)
+		tenant.POST("/certificate/renew", handles.RenewTenantCertificate)
 		tenant.GET("/certificate", handles.GetTenantCertificate)
 		tenant.GET("/certificate/requests", handles.GetTenantCertificateRequests)
 		tenant.GET("/certificate/download", handles.DownloadCertificate)
 	}
+	tenantUser := tenant.Group("/users", middlewares.AuthTenant)
+	tenantUser.GET("/list", handles.ListTenantUsers)
+	tenantUser.GET("/get", handles.GetTenantUser)
+	tenantUser.POST("/create", handles.CreateTenantUser)
+	tenantUser.POST("/update", handles.UpdateTenantUser)
+	tenantUser.POST("/delete", handles.DeleteTenantUser)
 
-	admin(auth.Group("/admin", middlewares.AuthAdmin))
+	admin(auth.Group("/admin", middlewares.AuthAdmin, middlewares.Audit))
 	if flags.Debug || flags.Dev {
 		debug(g.Group("/debug"))
 	}
@@ -143,6 +177,51 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/del_cache", handles.DelUserCache)
 	user.GET("/sshkey/list", handles.ListPublicKeys)
 	user.POST("/sshkey/delete", handles.DeletePublicKey)
+	user.GET("/sessions", handles.ListUserSessions)
+	user.POST("/logout", handles.ForceLogout)
+	user.POST("/unlock", handles.UnlockUser)
+
+	group := g.Group("/group")
+	group.GET("/list", handles.ListGroups)
+	group.GET("/get", handles.GetGroup)
+	group.POST("/create", handles.CreateGroup)
+	group.POST("/update", handles.UpdateGroup)
+	group.POST("/delete", handles.DeleteGroup)
+	group.GET("/members", handles.ListGroupMembers)
+	group.POST("/add_member", handles.AddGroupMember)
+	group.POST("/remove_member", handles.RemoveGroupMember)
+
+	oidcClient := g.Group("/oidc_client")
+	oidcClient.GET("/list", handles.ListOIDCProviderClients)
+	oidcClient.GET("/get", handles.GetOIDCProviderClient)
+	oidcClient.POST("/create", handles.CreateOIDCProviderClient)
+	oidcClient.POST("/update", handles.UpdateOIDCProviderClient)
+	oidcClient.POST("/reset_secret", handles.ResetOIDCProviderClientSecret)
+	oidcClient.POST("/delete", handles.DeleteOIDCProviderClient)
+
+	acl := g.Group("/acl")
+	acl.GET("/list", handles.ListPathACLs)
+	acl.GET("/get", handles.GetPathACL)
+	acl.POST("/create", handles.CreatePathACL)
+	acl.POST("/update", handles.UpdatePathACL)
+	acl.POST("/delete", handles.DeletePathACL)
+
+	ldapSync := g.Group("/ldap/sync")
+	ldapSync.GET("", handles.GetLdapSyncReport)
+	ldapSync.POST("", handles.SyncLdap)
+
+	audit := g.Group("/audit")
+	audit.GET("/list", handles.ListAuditEvents)
+	audit.GET("/export", handles.ExportAuditEvents)
+
+	g.GET("/cluster/nodes", handles.ListClusterNodes)
+
+	tenant := g.Group("/tenant")
+	tenant.GET("/list", handles.ListTenants)
+	tenant.GET("/get", handles.GetTenant)
+	tenant.POST("/create", handles.CreateTenant)
+	tenant.POST("/update", handles.UpdateTenant)
+	tenant.POST("/delete", handles.DeleteTenant)
 
 	storage := g.Group("/storage")
 	storage.GET("/list", handles.ListStorages)
@@ -153,6 +232,8 @@ func admin(g *gin.RouterGroup) {
 	storage.POST("/enable", handles.EnableStorage)
 	storage.POST("/disable", handles.DisableStorage)
 	storage.POST("/load_all", handles.LoadAllStorages)
+	storage.GET("/health", handles.StorageHealth)
+	storage.POST("/health/check", handles.CheckStorageHealth)
 
 	driver := g.Group("/driver")
 	driver.GET("/list", handles.ListDriverInfo)
@@ -184,6 +265,7 @@ func admin(g *gin.RouterGroup) {
 		certificate.PUT("/update/:id", handles.UpdateCertificate)
 		certificate.DELETE("/delete/:id", handles.DeleteCertificate)
 		certificate.POST("/revoke/:id", handles.RevokeCertificate)
+		certificate.GET("/:id/history", handles.CertificateHistory)
 		certificate.GET("/requests", handles.CertificateRequestList)
 		certificate.POST("/request/create", handles.CreateCertificateRequest)
 		certificate.POST("/request/approve/:id", handles.ApproveCertificateRequest)
@@ -6,7 +6,11 @@ import (
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
 	"github.com/OpenListTeam/OpenList/v4/server/s3"
 	"github.com/gin-gonic/gin"
 )
@@ -26,7 +30,7 @@ func S3(g *gin.RouterGroup) {
 	}
 	h, _ := s3.NewServer(context.Background())
 
-	g.Any("/*path", func(c *gin.Context) {
+	g.Any("/*path", middlewares.Protocol(model.ProtocolS3), S3Auth, func(c *gin.Context) {
 		adjustedPath := strings.TrimPrefix(c.Request.URL.Path, path.Join(conf.URL.Path, "/s3"))
 		c.Request.URL.Path = adjustedPath
 		gin.WrapH(h)(c)
@@ -35,5 +39,32 @@ func S3(g *gin.RouterGroup) {
 
 func S3Server(g *gin.RouterGroup) {
 	h, _ := s3.NewServer(context.Background())
-	g.Any("/*path", gin.WrapH(h))
+	g.Any("/*path", middlewares.Protocol(model.ProtocolS3), S3Auth, gin.WrapH(h))
+}
+
+// S3Auth sets the user the S3 requests act as, so that fs checks its path ACLs.
+// S3 has a single access key, acting as the user of the s3_user setting, or the admin.
+// A client certificate is checked on top of the V4 signature and its owner is used instead.
+func S3Auth(c *gin.Context) {
+	var user *model.User
+	var err error
+	if cert := common.ClientCertificate(c.Request); cert != nil {
+		user, err = op.GetUserByClientCertificate(cert)
+	} else if name := setting.GetStr(conf.S3User); name != "" {
+		user, err = op.GetUserByName(name)
+	} else {
+		user, err = op.GetAdmin()
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		c.Abort()
+		return
+	}
+	if user.Disabled {
+		common.ErrorStrResp(c, "Current user is disabled", 403)
+		c.Abort()
+		return
+	}
+	common.GinWithValue(c, conf.UserKey, user)
+	c.Next()
 }
@@ -86,7 +86,7 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 	response := gofakes3.NewObjectList()
 	path, remaining := prefixParser(prefix)
 
-	err = b.entryListR(bucketPath, path, remaining, prefix.HasDelimiter, response)
+	err = b.entryListR(ctx, bucketPath, path, remaining, prefix.HasDelimiter, response)
 	if err == gofakes3.ErrNoSuchKey {
 		// AWS just returns an empty list
 		response = gofakes3.NewObjectList()
@@ -108,6 +108,9 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err := canPath(ctx, fp, model.PathRead); err != nil {
+		return nil, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -151,6 +154,9 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err := canPath(ctx, fp, model.PathRead); err != nil {
+		return nil, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -250,6 +256,9 @@ func (b *s3Backend) PutObject(
 		reqPath = path.Dir(fp)
 	}
 	log.Debugf("reqPath: %s", reqPath)
+	if err = canPath(ctx, path.Dir(fp), model.PathWrite); err != nil {
+		return result, err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	ctx = context.WithValue(ctx, conf.MetaKey, fmeta)
 
@@ -342,6 +351,9 @@ func (b *s3Backend) deleteObject(ctx context.Context, bucketName, objectName str
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err := canPath(ctx, fp, model.PathDelete); err != nil {
+		return err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	// S3 does not report an error when attemping to delete a key that does not exist, so
 	// we need to skip IsNotExist errors.
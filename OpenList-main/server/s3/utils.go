@@ -42,8 +42,19 @@ func getBucketByName(name string) (Bucket, error) {
 	return Bucket{}, gofakes3.BucketNotFound(name)
 }
 
-func getDirEntries(path string) ([]model.Obj, error) {
-	ctx := context.Background()
+// canPath checks the action on the path for the user the request acts as
+func canPath(ctx context.Context, path string, action model.PathAction) error {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	if user == nil || !op.CanPathNoPassword(user, path, action) {
+		return errs.PermissionDenied
+	}
+	return nil
+}
+
+func getDirEntries(ctx context.Context, path string) ([]model.Obj, error) {
+	if err := canPath(ctx, path, model.PathList); err != nil {
+		return nil, err
+	}
 	meta, _ := op.GetNearestMeta(path)
 	fi, err := fs.Get(context.WithValue(ctx, conf.MetaKey, meta), path, &fs.GetArgs{})
 	if errs.IsNotFoundError(err) {
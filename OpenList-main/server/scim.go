@@ -0,0 +1,27 @@
+package server
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/server/scim"
+	"github.com/gin-gonic/gin"
+)
+
+func SCIM(g *gin.RouterGroup) {
+	g.Use(scim.Auth)
+	g.GET("/ServiceProviderConfig", scim.ServiceProviderConfig)
+
+	users := g.Group("/Users")
+	users.GET("", scim.ListUsers)
+	users.POST("", scim.CreateUser)
+	users.GET("/:id", scim.GetUser)
+	users.PUT("/:id", scim.ReplaceUser)
+	users.PATCH("/:id", scim.PatchUser)
+	users.DELETE("/:id", scim.DeleteUser)
+
+	groups := g.Group("/Groups")
+	groups.GET("", scim.ListGroups)
+	groups.POST("", scim.CreateGroup)
+	groups.GET("/:id", scim.GetGroup)
+	groups.PUT("/:id", scim.ReplaceGroup)
+	groups.PATCH("/:id", scim.PatchGroup)
+	groups.DELETE("/:id", scim.DeleteGroup)
+}
@@ -0,0 +1,142 @@
+package scim
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Filter matches a resource by its attributes, which are keyed by their lower case names
+type Filter func(attrs map[string]string) bool
+
+// ParseFilter parses the subset of the SCIM filter syntax directories send:
+// comparisons with eq, ne, co, sw, ew and pr joined by and and or, and binding tighter than or.
+// Values are compared case-insensitively, grouping with parentheses is not supported.
+func ParseFilter(s string) (Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return func(map[string]string) bool { return true }, nil
+	}
+	var or []Filter
+	var and []Filter
+	for i := 0; i < len(tokens); {
+		f, n, err := parseComparison(tokens[i:])
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, f)
+		i += n
+		if i == len(tokens) {
+			break
+		}
+		switch strings.ToLower(tokens[i]) {
+		case "and":
+		case "or":
+			or = append(or, all(and))
+			and = nil
+		default:
+			return nil, errors.Errorf("unexpected %q", tokens[i])
+		}
+		i++
+		if i == len(tokens) {
+			return nil, errors.New("unexpected end of filter")
+		}
+	}
+	or = append(or, all(and))
+	return func(attrs map[string]string) bool {
+		for _, f := range or {
+			if f(attrs) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func all(filters []Filter) Filter {
+	return func(attrs map[string]string) bool {
+		for _, f := range filters {
+			if !f(attrs) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// parseComparison parses a comparison at the start of tokens and returns the number of tokens used
+func parseComparison(tokens []string) (Filter, int, error) {
+	if len(tokens) < 2 {
+		return nil, 0, errors.New("incomplete comparison")
+	}
+	attr, op := strings.ToLower(tokens[0]), strings.ToLower(tokens[1])
+	if op == "pr" {
+		return func(attrs map[string]string) bool { return attrs[attr] != "" }, 2, nil
+	}
+	if len(tokens) < 3 {
+		return nil, 0, errors.New("incomplete comparison")
+	}
+	value := strings.ToLower(unquote(tokens[2]))
+	var match func(v string) bool
+	switch op {
+	case "eq":
+		match = func(v string) bool { return v == value }
+	case "ne":
+		match = func(v string) bool { return v != value }
+	case "co":
+		match = func(v string) bool { return strings.Contains(v, value) }
+	case "sw":
+		match = func(v string) bool { return strings.HasPrefix(v, value) }
+	case "ew":
+		match = func(v string) bool { return strings.HasSuffix(v, value) }
+	default:
+		return nil, 0, errors.Errorf("unsupported operator %q", tokens[1])
+	}
+	return func(attrs map[string]string) bool { return match(strings.ToLower(attrs[attr])) }, 3, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.ReplaceAll(s[1:len(s)-1], `\"`, `"`)
+	}
+	if s == "null" {
+		return ""
+	}
+	return s
+}
+
+// tokenize splits the filter by spaces outside of quoted values
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	quoted, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ' ' || r == '\t'):
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+			continue
+		case !quoted && (r == '(' || r == ')'):
+			return nil, errors.New("grouping is not supported")
+		}
+		cur.WriteRune(r)
+	}
+	if quoted {
+		return nil, errors.New("unterminated string")
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
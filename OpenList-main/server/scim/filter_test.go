@@ -0,0 +1,35 @@
+package scim
+
+import "testing"
+
+func TestParseFilter(t *testing.T) {
+	user := map[string]string{"username": "Alice", "externalid": "00u1", "active": "true", "displayname": ""}
+	var tests = []struct {
+		filter string
+		match  bool
+		isErr  bool
+	}{
+		{filter: "", match: true},
+		{filter: `userName eq "alice"`, match: true},
+		{filter: `userName eq "bob"`, match: false},
+		{filter: `userName sw "al" and active eq true`, match: true},
+		{filter: `userName eq "bob" or externalId eq "00u1"`, match: true},
+		{filter: `userName eq "bob" or externalId eq "00u1" and active eq false`, match: false},
+		{filter: `displayName pr`, match: false},
+		{filter: `userName ne "bob"`, match: true},
+		{filter: `displayName eq "a b"`, match: false},
+		{filter: `userName gt "a"`, isErr: true},
+		{filter: `userName eq "alice" and`, isErr: true},
+		{filter: `(userName eq "alice")`, isErr: true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if (err != nil) != tt.isErr {
+			t.Errorf("parse %q: expect error %v, got %v", tt.filter, tt.isErr, err)
+			continue
+		}
+		if err == nil && f(user) != tt.match {
+			t.Errorf("filter %q: expect match %v", tt.filter, tt.match)
+		}
+	}
+}
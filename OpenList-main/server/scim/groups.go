@@ -0,0 +1,342 @@
+package scim
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// memberFilterPath is the path of a remove operation of a single member, such as members[value eq "2"]
+var memberFilterPath = regexp.MustCompile(`(?i)^members\[value eq "?([^"\]]*)"?]$`)
+
+// groupMemberIDs returns the members of the group provisioned through SCIM,
+// the other members are neither shown to nor changed by the SCIM client
+func groupMemberIDs(groupID uint) ([]uint, []model.User, error) {
+	members, _, err := op.GetGroupMembers(groupID, 1, math.MaxInt32)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		ids   []uint
+		users []model.User
+	)
+	for i := range members {
+		if managed(&members[i]) {
+			ids = append(ids, members[i].ID)
+			users = append(users, members[i])
+		}
+	}
+	return ids, users, nil
+}
+
+func toGroup(c *gin.Context, g *model.Group) (Group, error) {
+	id := strconv.FormatUint(uint64(g.ID), 10)
+	r := Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		DisplayName: g.Name,
+		Members:     []Member{},
+		Meta:        &Meta{ResourceType: "Group", Location: location(c, "Groups", id)},
+	}
+	_, users, err := groupMemberIDs(g.ID)
+	if err != nil {
+		return r, err
+	}
+	for _, u := range users {
+		r.Members = append(r.Members, Member{Value: strconv.FormatUint(uint64(u.ID), 10), Display: u.Username})
+	}
+	return r, nil
+}
+
+func writeGroup(c *gin.Context, status int, g *model.Group) {
+	r, err := toGroup(c, g)
+	if err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	writeJSON(c, status, r)
+}
+
+func getGroup(c *gin.Context) (*model.Group, bool) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		writeError(c, 404, "", "group not found")
+		return nil, false
+	}
+	g, err := op.GetGroupById(id)
+	if err != nil {
+		writeError(c, 404, "", "group not found")
+		return nil, false
+	}
+	return g, true
+}
+
+func ListGroups(c *gin.Context) {
+	filter, err := ParseFilter(c.Query("filter"))
+	if err != nil {
+		writeError(c, 400, "invalidFilter", err.Error())
+		return
+	}
+	groups, _, err := op.GetGroups(1, math.MaxInt32)
+	if err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	var matched []*model.Group
+	for i := range groups {
+		if filter(map[string]string{"id": strconv.FormatUint(uint64(groups[i].ID), 10), "displayname": groups[i].Name}) {
+			matched = append(matched, &groups[i])
+		}
+	}
+	writeJSON(c, 200, page(c, matched, func(g *model.Group) any {
+		r, _ := toGroup(c, g)
+		return r
+	}))
+}
+
+func GetGroup(c *gin.Context) {
+	if g, ok := getGroup(c); ok {
+		writeGroup(c, 200, g)
+	}
+}
+
+func CreateGroup(c *gin.Context) {
+	var req Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+	if req.DisplayName == "" {
+		writeError(c, 400, "invalidValue", "displayName is required")
+		return
+	}
+	ids, err := memberIDs(req.Members)
+	if err != nil {
+		writeError(c, 400, "invalidValue", err.Error())
+		return
+	}
+	if nameTaken(req.DisplayName, 0) {
+		writeError(c, 409, "uniqueness", "displayName is already taken")
+		return
+	}
+	g := &model.Group{Name: req.DisplayName}
+	if err = op.CreateGroup(g); err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	if err = setMembers(g.ID, ids); err != nil {
+		writeError(c, 400, "invalidValue", err.Error())
+		return
+	}
+	writeGroup(c, 201, g)
+}
+
+func ReplaceGroup(c *gin.Context) {
+	g, ok := getGroup(c)
+	if !ok {
+		return
+	}
+	var req Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+	ids, err := memberIDs(req.Members)
+	if err != nil {
+		writeError(c, 400, "invalidValue", err.Error())
+		return
+	}
+	if err = renameGroup(g, req.DisplayName); err != nil {
+		writeError(c, 409, "uniqueness", err.Error())
+		return
+	}
+	if err = setMembers(g.ID, ids); err != nil {
+		writeError(c, 400, "invalidValue", err.Error())
+		return
+	}
+	writeGroup(c, 200, g)
+}
+
+func PatchGroup(c *gin.Context) {
+	g, ok := getGroup(c)
+	if !ok {
+		return
+	}
+	var req PatchOp
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+	for _, operation := range req.Operations {
+		if err := patchGroup(g, operation); err != nil {
+			writeError(c, 400, "invalidValue", err.Error())
+			return
+		}
+	}
+	writeGroup(c, 200, g)
+}
+
+func patchGroup(g *model.Group, operation Operation) error {
+	path := strings.ToLower(operation.Path)
+	opName := strings.ToLower(operation.Op)
+	if path == "" && opName != "remove" {
+		// the attributes are given as an object
+		var values struct {
+			DisplayName *string  `json:"displayName"`
+			Members     []Member `json:"members"`
+		}
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return err
+		}
+		if values.DisplayName != nil {
+			if err := renameGroup(g, *values.DisplayName); err != nil {
+				return err
+			}
+		}
+		if values.Members == nil {
+			return nil
+		}
+		path = "members"
+		operation.Value, _ = json.Marshal(values.Members)
+	}
+	if path == "displayname" {
+		var name string
+		if err := json.Unmarshal(operation.Value, &name); err != nil {
+			return err
+		}
+		return renameGroup(g, name)
+	}
+	if m := memberFilterPath.FindStringSubmatch(operation.Path); m != nil && opName == "remove" {
+		ids, err := memberIDs([]Member{{Value: m[1]}})
+		if err != nil {
+			return err
+		}
+		return op.RemoveGroupMember(g.ID, ids[0])
+	}
+	if path != "members" {
+		return errors.Errorf("unsupported path %q", operation.Path)
+	}
+	var members []Member
+	if len(operation.Value) > 0 {
+		if err := json.Unmarshal(operation.Value, &members); err != nil {
+			return err
+		}
+	}
+	ids, err := memberIDs(members)
+	if err != nil {
+		return err
+	}
+	switch opName {
+	case "add":
+		for _, id := range ids {
+			if err = op.AddGroupMember(g.ID, id); err != nil {
+				return err
+			}
+		}
+	case "replace":
+		return setMembers(g.ID, ids)
+	case "remove":
+		if members == nil {
+			return setMembers(g.ID, nil)
+		}
+		for _, id := range ids {
+			if err = op.RemoveGroupMember(g.ID, id); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("unsupported operation %q", operation.Op)
+	}
+	return nil
+}
+
+func DeleteGroup(c *gin.Context) {
+	g, ok := getGroup(c)
+	if !ok {
+		return
+	}
+	if err := op.DeleteGroupById(g.ID); err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	c.Status(204)
+}
+
+// memberIDs parses the members, only the users provisioned through SCIM can be members,
+// so that the guest, the admins and the tenant users can't be put into a group
+func memberIDs(members []Member) ([]uint, error) {
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, ok := parseID(m.Value)
+		if !ok {
+			return nil, errors.Errorf("invalid member %q", m.Value)
+		}
+		u, err := op.GetUserById(id)
+		if err != nil || !managed(u) {
+			return nil, errors.Errorf("invalid member %q", m.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// setMembers makes the users the exact SCIM provisioned members of the group
+func setMembers(groupID uint, ids []uint) error {
+	current, _, err := groupMemberIDs(groupID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !slices.Contains(current, id) {
+			if err = op.AddGroupMember(groupID, id); err != nil {
+				return err
+			}
+		}
+	}
+	for _, id := range current {
+		if !slices.Contains(ids, id) {
+			if err = op.RemoveGroupMember(groupID, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renameGroup renames the group, its permission, base path and metas are kept
+func renameGroup(g *model.Group, name string) error {
+	if name == "" || name == g.Name {
+		return nil
+	}
+	if nameTaken(name, g.ID) {
+		return errors.New("displayName is already taken")
+	}
+	g.Name = name
+	return op.UpdateGroup(g)
+}
+
+func nameTaken(name string, except uint) bool {
+	groups, _, err := op.GetGroups(1, math.MaxInt32)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(groups, func(g model.Group) bool {
+		return g.ID != except && strings.EqualFold(g.Name, name)
+	})
+}
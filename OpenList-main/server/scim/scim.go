@@ -0,0 +1,141 @@
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// SCIM 2.0 (RFC 7643, RFC 7644) provisioning of the users and groups by a central directory
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	contentType = "application/scim+json"
+	maxResults  = 200
+)
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    any      `json:"Resources"`
+}
+
+type PatchOp struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Auth checks the bearer token against the scim_token setting, SCIM is disabled while it is empty
+func Auth(c *gin.Context) {
+	token := setting.GetStr(conf.SCIMToken)
+	if token == "" {
+		writeError(c, 404, "", "SCIM is disabled")
+		c.Abort()
+		return
+	}
+	bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+		writeError(c, 401, "", "invalid SCIM token")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func ServiceProviderConfig(c *gin.Context) {
+	writeJSON(c, 200, gin.H{
+		"schemas":               []string{SchemaServiceProviderConfig},
+		"patch":                 gin.H{"supported": true},
+		"bulk":                  gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":                gin.H{"supported": true, "maxResults": maxResults},
+		"changePassword":        gin.H{"supported": true},
+		"sort":                  gin.H{"supported": false},
+		"etag":                  gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{"type": "oauthbearertoken", "name": "Bearer Token", "description": "the scim_token setting"}},
+	})
+}
+
+func writeJSON(c *gin.Context, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	c.Data(status, contentType, data)
+}
+
+func writeError(c *gin.Context, status int, scimType, detail string) {
+	resp := gin.H{
+		"schemas": []string{SchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		resp["scimType"] = scimType
+	}
+	data, _ := json.Marshal(resp)
+	c.Data(status, contentType, data)
+}
+
+func location(c *gin.Context, resource, id string) string {
+	return common.GetApiUrl(c) + "/scim/v2/" + resource + "/" + id
+}
+
+// page applies the 1-based startIndex and count parameters to the filtered items and converts the page
+func page[T any](c *gin.Context, items []T, convert func(T) any) ListResponse {
+	start, _ := strconv.Atoi(c.Query("startIndex"))
+	start = max(start, 1)
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(maxResults)))
+	if err != nil {
+		count = maxResults
+	}
+	count = min(max(count, 0), maxResults)
+	from := min(start-1, len(items))
+	to := min(from+count, len(items))
+	resources := make([]any, 0, to-from)
+	for _, item := range items[from:to] {
+		resources = append(resources, convert(item))
+	}
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(items),
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func parseID(id string) (uint, bool) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	return uint(n), err == nil
+}
@@ -0,0 +1,286 @@
+package scim
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type User struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id,omitempty"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	Active     *bool    `json:"active,omitempty"`
+	Password   string   `json:"password,omitempty"` // write only
+	Groups     []Member `json:"groups,omitempty"`   // read only, managed through the groups
+	Meta       *Meta    `json:"meta,omitempty"`
+}
+
+// managed reports whether the user can be provisioned through SCIM,
+// the admin, the guest and the users of tenants are left to OpenList,
+// deprovisioned users are gone for the directory
+func managed(u *model.User) bool {
+	return u.Role == model.GENERAL && u.TenantID == 0 && !u.Deprovisioned
+}
+
+func toUser(c *gin.Context, u *model.User) User {
+	id := strconv.FormatUint(uint64(u.ID), 10)
+	active := !u.Disabled
+	r := User{
+		Schemas:    []string{SchemaUser},
+		ID:         id,
+		ExternalID: u.ExternalID,
+		UserName:   u.Username,
+		Active:     &active,
+		Meta:       &Meta{ResourceType: "User", Location: location(c, "Users", id)},
+	}
+	for _, gid := range u.GroupIDs {
+		if g, err := op.GetGroupById(gid); err == nil {
+			r.Groups = append(r.Groups, Member{Value: strconv.FormatUint(uint64(gid), 10), Display: g.Name})
+		}
+	}
+	return r
+}
+
+func userAttrs(u *model.User) map[string]string {
+	return map[string]string{
+		"id":         strconv.FormatUint(uint64(u.ID), 10),
+		"username":   u.Username,
+		"externalid": u.ExternalID,
+		"active":     strconv.FormatBool(!u.Disabled),
+	}
+}
+
+func getUser(c *gin.Context) (*model.User, bool) {
+	id, ok := parseID(c.Param("id"))
+	if !ok {
+		writeError(c, 404, "", "user not found")
+		return nil, false
+	}
+	u, err := op.GetUserById(id)
+	if err != nil || !managed(u) {
+		writeError(c, 404, "", "user not found")
+		return nil, false
+	}
+	return u, true
+}
+
+func ListUsers(c *gin.Context) {
+	filter, err := ParseFilter(c.Query("filter"))
+	if err != nil {
+		writeError(c, 400, "invalidFilter", err.Error())
+		return
+	}
+	tenantID := uint(0)
+	users, _, err := op.GetUsers(1, math.MaxInt32, &tenantID)
+	if err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	var matched []uint
+	for i := range users {
+		if managed(&users[i]) && filter(userAttrs(&users[i])) {
+			matched = append(matched, users[i].ID)
+		}
+	}
+	writeJSON(c, 200, page(c, matched, func(id uint) any {
+		// the groups of the users are only loaded for the returned page
+		u, err := op.GetUserById(id)
+		if err != nil {
+			return User{Schemas: []string{SchemaUser}, ID: strconv.FormatUint(uint64(id), 10)}
+		}
+		return toUser(c, u)
+	}))
+}
+
+func GetUser(c *gin.Context) {
+	if u, ok := getUser(c); ok {
+		writeJSON(c, 200, toUser(c, u))
+	}
+}
+
+func CreateUser(c *gin.Context) {
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+	if req.UserName == "" {
+		writeError(c, 400, "invalidValue", "userName is required")
+		return
+	}
+	if _, err := op.GetUserByName(req.UserName); err == nil {
+		writeError(c, 409, "uniqueness", "userName is already taken")
+		return
+	}
+	// provisioned users start like the users registered by SSO, more permissions come from their groups
+	u := &model.User{
+		Username:   req.UserName,
+		ExternalID: req.ExternalID,
+		Disabled:   req.Active != nil && !*req.Active,
+		Permission: int32(setting.GetInt(conf.SSODefaultPermission, 0)),
+		BasePath:   setting.GetStr(conf.SSODefaultDir),
+	}
+	if req.Password == "" {
+		// users without a password log in with SSO or LDAP
+		u.SetPassword(random.String(32))
+	} else if err := op.SetUserPassword(u, req.Password); err != nil {
+		writeError(c, 400, "invalidValue", err.Error())
+		return
+	}
+	if err := op.CreateUser(u); err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	writeJSON(c, 201, toUser(c, u))
+}
+
+func ReplaceUser(c *gin.Context) {
+	u, ok := getUser(c)
+	if !ok {
+		return
+	}
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+	if req.UserName == "" {
+		writeError(c, 400, "invalidValue", "userName is required")
+		return
+	}
+	if req.Password != "" {
+		if err := op.SetUserPassword(u, req.Password); err != nil {
+			writeError(c, 400, "invalidValue", err.Error())
+			return
+		}
+	}
+	if err := setUserName(u, req.UserName); err != nil {
+		writeError(c, 409, "uniqueness", err.Error())
+		return
+	}
+	u.ExternalID = req.ExternalID
+	u.Disabled = req.Active != nil && !*req.Active
+	if err := op.UpdateUser(u); err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	writeJSON(c, 200, toUser(c, u))
+}
+
+func PatchUser(c *gin.Context) {
+	u, ok := getUser(c)
+	if !ok {
+		return
+	}
+	var req PatchOp
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+	for _, operation := range req.Operations {
+		var err error
+		switch strings.ToLower(operation.Op) {
+		case "add", "replace":
+			if operation.Path != "" {
+				err = patchUser(u, operation.Path, operation.Value)
+				break
+			}
+			var values map[string]json.RawMessage
+			if err = json.Unmarshal(operation.Value, &values); err != nil {
+				break
+			}
+			for attr, value := range values {
+				if err = patchUser(u, attr, value); err != nil {
+					break
+				}
+			}
+		case "remove":
+			err = patchUser(u, operation.Path, nil)
+		default:
+			err = errors.Errorf("unsupported operation %q", operation.Op)
+		}
+		if err != nil {
+			writeError(c, 400, "invalidValue", err.Error())
+			return
+		}
+	}
+	if err := op.UpdateUser(u); err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	writeJSON(c, 200, toUser(c, u))
+}
+
+// patchUser sets an attribute of the user, a nil value removes it.
+// The attributes OpenList does not store, such as name and emails, are ignored.
+func patchUser(u *model.User, attr string, value json.RawMessage) error {
+	var v any
+	if value != nil {
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+	}
+	str, _ := v.(string)
+	switch strings.ToLower(attr) {
+	case "active":
+		switch v := v.(type) {
+		case bool:
+			u.Disabled = !v
+		case string:
+			// some directories send booleans as strings
+			u.Disabled = !strings.EqualFold(v, "true")
+		default:
+			return errors.New("active must be a boolean")
+		}
+	case "username":
+		if str == "" {
+			return errors.New("userName is required")
+		}
+		return setUserName(u, str)
+	case "externalid":
+		u.ExternalID = str
+	case "password":
+		if str == "" {
+			return errors.New("password can not be removed")
+		}
+		return op.SetUserPassword(u, str)
+	}
+	return nil
+}
+
+func setUserName(u *model.User, name string) error {
+	if name == u.Username {
+		return nil
+	}
+	if _, err := op.GetUserByName(name); err == nil {
+		return errors.New("userName is already taken")
+	}
+	u.Username = name
+	return nil
+}
+
+// DeleteUser deprovisions the user by disabling it, so that its shares and settings are kept,
+// the user is answered as not found from then on until an admin enables it again
+func DeleteUser(c *gin.Context) {
+	u, ok := getUser(c)
+	if !ok {
+		return
+	}
+	u.Disabled, u.Deprovisioned = true, true
+	if err := op.UpdateUser(u); err != nil {
+		writeError(c, 500, "", err.Error())
+		return
+	}
+	c.Status(204)
+}
@@ -64,6 +64,7 @@ func (d *SftpDriver) GetFileSystem(sc *ssh.ServerConn) (sftpd.FileSystem, error)
 	ctx = context.WithValue(ctx, conf.UserKey, userObj)
 	ctx = context.WithValue(ctx, conf.MetaPassKey, "")
 	ctx = context.WithValue(ctx, conf.ClientIPKey, sc.RemoteAddr().String())
+	ctx = context.WithValue(ctx, conf.ProtocolKey, model.ProtocolSFTP)
 	ctx = context.WithValue(ctx, conf.ProxyHeaderKey, d.proxyHeader)
 	return &sftp.DriverAdapter{FtpDriver: ftp.NewAferoAdapter(ctx)}, nil
 }
@@ -94,7 +95,10 @@ func (d *SftpDriver) PasswordAuth(conn ssh.ConnMetadata, password []byte) (*ssh.
 		return nil, errors.New("user is not allowed to access via SFTP")
 	}
 	passHash := model.StaticHash(string(password))
-	if err = userObj.ValidatePwdStaticHash(passHash); err != nil {
+	if err = op.CheckProtocolUserPassword(userObj, passHash); err != nil {
+		return nil, err
+	}
+	if err = op.LoginSucceeded(userObj); err != nil {
 		return nil, err
 	}
 	return nil, nil
@@ -108,6 +112,15 @@ func (d *SftpDriver) PublicKeyAuth(conn ssh.ConnMetadata, key ssh.PublicKey) (*s
 	if userObj.Disabled || !userObj.CanFTPAccess() {
 		return nil, errors.New("user is not allowed to access via SFTP")
 	}
+	if err = op.CheckAccountLocked(userObj); err != nil {
+		return nil, err
+	}
+	if err = op.CheckCertificateLoginRequired(userObj); err != nil {
+		return nil, err
+	}
+	if err = op.Check2FAEnrolled(userObj); err != nil {
+		return nil, err
+	}
 	keys, _, err := op.GetSSHPublicKeyByUserId(userObj.ID, 1, -1)
 	if err != nil {
 		return nil, err
@@ -134,6 +147,10 @@ func (d *SftpDriver) AuthLogCallback(conn ssh.ConnMetadata, method string, err e
 	} else if method != "none" {
 		utils.Log.Infof("[SFTP] %s(%s) tries logging in via %s but with error: %s", conn.User(), ip, method, err)
 	}
+	// clients try none first to learn the methods, so only the sign-ins of guests without authorization count
+	if err == nil || method != "none" {
+		op.RecordLogin(model.ProtocolSFTP, ip, conn.User(), method, err)
+	}
 }
 
 func (d *SftpDriver) GetBanner(_ ssh.ConnMetadata) string {
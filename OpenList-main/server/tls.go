@@ -0,0 +1,32 @@
+package server
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/ca"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// TLSConfig returns the TLS config of the https listeners, requesting tenant
+// client certificates according to conf.Conf.Scheme.ClientAuth.
+// It returns nil if client certificates are not requested.
+func TLSConfig() *tls.Config {
+	var clientAuth tls.ClientAuthType
+	switch strings.ToLower(conf.Conf.Scheme.ClientAuth) {
+	case "", "none":
+		return nil
+	case "request":
+		clientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		utils.Log.Warnf("unknown client_auth %q, client certificates are not requested", conf.Conf.Scheme.ClientAuth)
+		return nil
+	}
+	return &tls.Config{
+		ClientAuth: clientAuth,
+		ClientCAs:  ca.CertPool(),
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
@@ -15,6 +16,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/server/webdav"
+	"github.com/OpenListTeam/go-cache"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
@@ -29,7 +31,7 @@ func WebDav(dav *gin.RouterGroup) {
 			log.Errorf("%s %s %+v", request.Method, request.URL.Path, err)
 		},
 	}
-	dav.Use(WebDAVAuth)
+	dav.Use(middlewares.Protocol(model.ProtocolDav), WebDAVAuth)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	dav.Any("/*path", uploadLimiter, downloadLimiter, ServeWebDAV)
@@ -48,6 +50,23 @@ func ServeWebDAV(c *gin.Context) {
 	handler.ServeHTTP(c.Writer, c.Request)
 }
 
+// davLogins username@ip -> struct{}, WebDAV clients send the credentials with every request,
+// so a successful sign-in is only recorded again once its entry has expired
+var davLogins = cache.NewMemCache[struct{}]()
+
+const davLoginTTL = 10 * time.Minute
+
+func recordDavLogin(ip, username, method string, err error) {
+	if err == nil {
+		key := username + "@" + ip
+		if _, ok := davLogins.Get(key); ok {
+			return
+		}
+		davLogins.Set(key, struct{}{}, cache.WithEx[struct{}](davLoginTTL))
+	}
+	op.RecordLogin(model.ProtocolDav, ip, username, method, err)
+}
+
 func WebDAVAuth(c *gin.Context) {
 	// check count of login
 	ip := c.ClientIP()
@@ -66,6 +85,22 @@ func WebDAVAuth(c *gin.Context) {
 	}
 	username, password, ok := c.Request.BasicAuth()
 	if !ok {
+		if cert := common.ClientCertificate(c.Request); cert != nil && c.GetHeader("Authorization") == "" {
+			user, err := op.GetUserByClientCertificate(cert)
+			if err == nil {
+				err = op.Check2FAEnrolled(user)
+			}
+			if err != nil {
+				recordDavLogin(ip, cert.Subject.CommonName, "certificate", err)
+				log.Debugf("[webdav auth] client certificate rejected: %+v", err)
+				c.Status(http.StatusUnauthorized)
+				c.Abort()
+				return
+			}
+			recordDavLogin(ip, user.Username, "certificate", nil)
+			webDAVAuthorize(c, user, guest)
+			return
+		}
 		bt := c.GetHeader("Authorization")
 		log.Debugf("[webdav auth] token: %s", bt)
 		if strings.HasPrefix(bt, "Bearer") {
@@ -79,6 +114,7 @@ func WebDAVAuth(c *gin.Context) {
 					c.Abort()
 					return
 				}
+				recordDavLogin(ip, admin.Username, "token", nil)
 				common.GinWithValue(c, conf.UserKey, admin)
 				c.Next()
 				return
@@ -95,12 +131,16 @@ func WebDAVAuth(c *gin.Context) {
 		return
 	}
 	user, err := op.GetUserByName(username)
-	if err != nil || user.ValidateRawPassword(password) != nil {
+	if err == nil {
+		err = op.CheckProtocolUserPassword(user, model.StaticHash(password))
+	}
+	if err != nil {
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, conf.UserKey, guest)
 			c.Next()
 			return
 		}
+		recordDavLogin(ip, username, "password", err)
 		model.LoginCache.Set(ip, count+1)
 		c.Status(http.StatusUnauthorized)
 		c.Abort()
@@ -108,6 +148,15 @@ func WebDAVAuth(c *gin.Context) {
 	}
 	// at least auth is successful till here
 	model.LoginCache.Del(ip)
+	recordDavLogin(ip, username, "password", nil)
+	if err = op.LoginSucceeded(user); err != nil {
+		log.Errorf("[webdav auth] failed reset failed logins: %+v", err)
+	}
+	webDAVAuthorize(c, user, guest)
+}
+
+// webDAVAuthorize checks the authenticated user's webdav permissions for the request method
+func webDAVAuthorize(c *gin.Context, user, guest *model.User) {
 	if user.Disabled || !user.CanWebdavRead() {
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, conf.UserKey, guest)
@@ -11,12 +11,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 )
@@ -109,6 +111,8 @@ var liveProps = map[xml.Name]struct {
 	findFn func(context.Context, LockSystem, string, model.Obj) (string, error)
 	// dir is true if the property applies to directories.
 	dir bool
+	// named is true if the property is only returned when it is requested by name.
+	named bool
 }{
 	{Space: "DAV:", Local: "resourcetype"}: {
 		findFn: findResourceType,
@@ -165,6 +169,18 @@ var liveProps = map[xml.Name]struct {
 		findFn: findChecksums,
 		dir:    false,
 	},
+
+	// quota properties are not returned by allprop, see RFC 4331 section 3.
+	{Space: "DAV:", Local: "quota-available-bytes"}: {
+		findFn: findQuotaAvailableBytes,
+		dir:    true,
+		named:  true,
+	},
+	{Space: "DAV:", Local: "quota-used-bytes"}: {
+		findFn: findQuotaUsedBytes,
+		dir:    true,
+		named:  true,
+	},
 }
 
 // TODO(nigeltao) merge props and allprop?
@@ -205,6 +221,12 @@ func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name)
 		// Otherwise, it must either be a live property or we don't know it.
 		if prop := liveProps[pn]; prop.findFn != nil && (prop.dir || !isDir) {
 			innerXML, err := prop.findFn(ctx, ls, fi.GetName(), fi)
+			if errors.Is(err, errPropNotFound) {
+				pstatNotFound.Props = append(pstatNotFound.Props, Property{
+					XMLName: pn,
+				})
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -268,6 +290,9 @@ func allprop(ctx context.Context, ls LockSystem, fi model.Obj, include []xml.Nam
 	if err != nil {
 		return nil, err
 	}
+	pnames = slices.DeleteFunc(pnames, func(pn xml.Name) bool {
+		return liveProps[pn].named
+	})
 	// Add names from include if they are not already covered in pnames.
 	nameset := make(map[xml.Name]bool)
 	for _, pn := range pnames {
@@ -493,3 +518,39 @@ func findChecksums(ctx context.Context, ls LockSystem, name string, fi model.Obj
 	}
 	return checksums, nil
 }
+
+// findQuota returns the quota of the current user which runs out of bytes first
+func findQuota(ctx context.Context) (*model.Quota, error) {
+	user := ctx.Value(conf.UserKey).(*model.User)
+	quotas, err := op.GetUserQuotas(user)
+	if err != nil {
+		return nil, err
+	}
+	var quota *model.Quota
+	for i := range quotas {
+		q := &quotas[i]
+		if q.LimitBytes > 0 && (quota == nil || q.AvailableBytes() < quota.AvailableBytes()) {
+			quota = q
+		}
+	}
+	if quota == nil {
+		return nil, errPropNotFound
+	}
+	return quota, nil
+}
+
+func findQuotaAvailableBytes(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
+	quota, err := findQuota(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(quota.AvailableBytes(), 10), nil
+}
+
+func findQuotaUsedBytes(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
+	quota, err := findQuota(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(quota.UsedBytes, 10), nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 )
@@ -228,6 +229,9 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (sta
 	if err != nil {
 		return http.StatusForbidden, err
 	}
+	if !op.CanPathNoPassword(user, reqPath, model.PathRead) {
+		return http.StatusForbidden, errs.PermissionDenied
+	}
 	fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
 	if err != nil {
 		return http.StatusNotFound, err
@@ -296,6 +300,9 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) (status i
 	if err != nil {
 		return 403, err
 	}
+	if !op.CanPathNoPassword(user, reqPath, model.PathDelete) {
+		return http.StatusForbidden, errs.PermissionDenied
+	}
 	// TODO: return MultiStatus where appropriate.
 
 	// "godoc os RemoveAll" says that "If the path does not exist, RemoveAll
@@ -341,6 +348,9 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 	if err != nil {
 		return http.StatusForbidden, err
 	}
+	if !op.CanPathNoPassword(user, path.Dir(reqPath), model.PathWrite) {
+		return http.StatusForbidden, errs.PermissionDenied
+	}
 	obj := model.Object{
 		Name:     path.Base(reqPath),
 		Size:     r.ContentLength,
@@ -393,6 +403,9 @@ func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request) (status in
 	if err != nil {
 		return 403, err
 	}
+	if !op.CanPathNoPassword(user, reqPath, model.PathWrite) {
+		return http.StatusForbidden, errs.PermissionDenied
+	}
 
 	if r.ContentLength > 0 {
 		return http.StatusUnsupportedMediaType, nil
@@ -461,6 +474,24 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request) (status
 	if err != nil {
 		return 403, err
 	}
+	// the permissions are checked by the middleware, only the path ACLs checked by fs.Copy,
+	// fs.Rename and fs.Move are checked here to fail before the locks are taken
+	var aclErr error
+	switch {
+	case r.Method == "COPY":
+		if aclErr = op.CheckPathACL(ctx, src, model.PathRead); aclErr == nil {
+			aclErr = op.CheckPathACL(ctx, path.Dir(dst), model.PathWrite)
+		}
+	case path.Dir(src) == path.Dir(dst):
+		aclErr = op.CheckPathACL(ctx, src, model.PathWrite)
+	default:
+		if aclErr = op.CheckPathACL(ctx, src, model.PathDelete); aclErr == nil {
+			aclErr = op.CheckPathACL(ctx, path.Dir(dst), model.PathWrite)
+		}
+	}
+	if aclErr != nil {
+		return http.StatusForbidden, aclErr
+	}
 
 	if r.Method == "COPY" {
 		// Section 7.5.1 says that a COPY only needs to lock the destination,
@@ -558,6 +589,9 @@ func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request) (retStatus
 		if err != nil {
 			return 403, err
 		}
+		if err = op.CheckPathACL(ctx, reqPath, model.PathWrite); err != nil {
+			return http.StatusForbidden, err
+		}
 		ld = LockDetails{
 			Root:      reqPath,
 			Duration:  duration,
@@ -641,6 +675,9 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 	if err != nil {
 		return 403, err
 	}
+	if !op.CanPathNoPassword(user, reqPath, model.PathList) {
+		return http.StatusForbidden, errs.PermissionDenied
+	}
 	fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
 	if err != nil {
 		if errs.IsNotFoundError(err) {
@@ -685,7 +722,7 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 		if err != nil {
 			return err
 		}
-		href := path.Join(h.Prefix, strings.TrimPrefix(reqPath, user.BasePath))
+		href := path.Join(h.Prefix, strings.TrimPrefix(reqPath, user.EffectiveBasePath()))
 		if href != "/" && info.IsDir() {
 			href += "/"
 		}
@@ -720,6 +757,9 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request) (statu
 	if err != nil {
 		return 403, err
 	}
+	if err = op.CheckPathACL(ctx, reqPath, model.PathWrite); err != nil {
+		return http.StatusForbidden, err
+	}
 	if _, err := fs.Get(ctx, reqPath, &fs.GetArgs{}); err != nil {
 		if errs.IsObjectNotFound(err) {
 			return http.StatusNotFound, err
@@ -833,6 +873,7 @@ var (
 	errNoFileSystem            = errors.New("webdav: no file system")
 	errNoLockSystem            = errors.New("webdav: no lock system")
 	errNotADirectory           = errors.New("webdav: not a directory")
+	errPropNotFound            = errors.New("webdav: property not found")
 	errPrefixMismatch          = errors.New("webdav: prefix mismatch")
 	errRecursionTooDeep        = errors.New("webdav: recursion too deep")
 	errUnsupportedLockInfo     = errors.New("webdav: unsupported lock info")